@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+func main() {
+	output := flag.String("o", "", "file to write the schema to (defaults to stdout)")
+	flag.Parse()
+
+	schema, err := json.MarshalIndent(models.ProtocolSchema(), "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode protocol schema: %v", err)
+	}
+	schema = append(schema, '\n')
+
+	if *output == "" {
+		os.Stdout.Write(schema)
+		return
+	}
+
+	if err := os.WriteFile(*output, schema, 0o644); err != nil {
+		log.Fatalf("Failed to write protocol schema: %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+package config
+
+const minAdminTokenLength = 16
+
+type AdminConfig struct {
+	// Token must be presented as a Bearer token to use the /admin API,
+	// which is disabled while it is empty.
+	Token string `yaml:"token"`
+}
+
+func (a AdminConfig) Enabled() bool {
+	return a.Token != ""
+}
+
+func (a *AdminConfig) applyEnv(env *envLoader) {
+	env.Secret("ADMIN_TOKEN", &a.Token)
+}
+
+func (a AdminConfig) validate(v *validator) {
+	v.check(!a.Enabled() || len(a.Token) >= minAdminTokenLength, "admin.token must be at least %d characters", minAdminTokenLength)
+}
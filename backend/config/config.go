@@ -0,0 +1,257 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AppConfig holds every setting of the backend. Values are resolved by Load
+// in increasing order of precedence: built-in defaults, the YAML file named
+// by -config or CONFIG_FILE, environment variables, and command-line flags.
+type AppConfig struct {
+	Env            string          `yaml:"env"`
+	IsProd         bool            `yaml:"-"`
+	IsDev          bool            `yaml:"-"`
+	Log            LogConfig       `yaml:"log"`
+	Server         ServerConfig    `yaml:"server"`
+	Database       DatabaseConfig  `yaml:"database"`
+	AllowedOrigins []string        `yaml:"allowedOrigins"`
+	Cookie         CookieConfig    `yaml:"-"`
+	Protocol       ProtocolConfig  `yaml:"protocol"`
+	WebSocket      WebSocketConfig `yaml:"websocket"`
+	RateLimit      RateLimitConfig `yaml:"rateLimit"`
+	Session        SessionConfig   `yaml:"session"`
+	Rooms          RoomConfig      `yaml:"rooms"`
+	OIDC           OIDCConfig      `yaml:"oidc"`
+	Metrics        MetricsConfig   `yaml:"metrics"`
+	Admin          AdminConfig     `yaml:"admin"`
+}
+
+const redacted = "[redacted]"
+
+var Cfg AppConfig
+
+// Load resolves the configuration from args and the environment, validates
+// it and makes it available as Cfg.
+func Load(args []string) error {
+	rest, err := LoadCommand("scrum-poker", args, nil)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected argument %q", rest[0])
+	}
+	return nil
+}
+
+// LoadCommand is Load for a subcommand called name: define, if not nil,
+// adds the command's own flags next to the configuration flags, and the
+// positional arguments that follow the flags are returned.
+func LoadCommand(name string, args []string, define func(fs *flag.FlagSet)) ([]string, error) {
+	cfg, rest, err := load(name, args, define)
+	if err != nil {
+		return nil, err
+	}
+	Cfg = cfg
+	return rest, nil
+}
+
+func load(name string, args []string, define func(fs *flag.FlagSet)) (AppConfig, []string, error) {
+	// Flags are parsed twice: once to find the configuration file, and
+	// again after the file and environment are applied so that they take
+	// precedence over both.
+	var scratch AppConfig
+	path := os.Getenv("CONFIG_FILE")
+	if err := newFlagSet(name, &scratch, &path, define).Parse(args); err != nil {
+		return AppConfig{}, nil, err
+	}
+
+	cfg := defaultConfig()
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return AppConfig{}, nil, err
+		}
+	}
+
+	env := &envLoader{}
+	cfg.applyEnv(env)
+	if err := errors.Join(env.errs...); err != nil {
+		return AppConfig{}, nil, err
+	}
+
+	fs := newFlagSet(name, &cfg, &path, define)
+	if err := fs.Parse(args); err != nil {
+		return AppConfig{}, nil, err
+	}
+
+	cfg.resolveDerived()
+	if err := cfg.Validate(); err != nil {
+		return AppConfig{}, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+func defaultConfig() AppConfig {
+	return AppConfig{
+		Log:       defaultLogConfig(),
+		Server:    defaultServerConfig(),
+		Database:  defaultDatabaseConfig(),
+		Protocol:  defaultProtocolConfig(),
+		WebSocket: defaultWebSocketConfig(),
+		RateLimit: defaultRateLimitConfig(),
+		Session:   defaultSessionConfig(),
+		Rooms:     defaultRoomConfig(),
+		OIDC:      defaultOIDCConfig(),
+		Metrics:   defaultMetricsConfig(),
+	}
+}
+
+func loadFile(path string, cfg *AppConfig) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %v", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return nil
+}
+
+func (c *AppConfig) applyEnv(env *envLoader) {
+	env.String("ENV", &c.Env)
+	env.List("ALLOWED_ORIGINS", &c.AllowedOrigins)
+	c.Log.applyEnv(env)
+	c.Server.applyEnv(env)
+	c.Database.applyEnv(env)
+	c.Protocol.applyEnv(env)
+	c.WebSocket.applyEnv(env)
+	c.RateLimit.applyEnv(env)
+	c.Session.applyEnv(env)
+	c.Rooms.applyEnv(env)
+	c.OIDC.applyEnv(env)
+	c.Metrics.applyEnv(env)
+	c.Admin.applyEnv(env)
+}
+
+// newFlagSet binds the command-line flags to cfg, using its current values
+// as defaults. Only settings that are commonly changed per run have flags.
+func newFlagSet(name string, cfg *AppConfig, configPath *string, define func(fs *flag.FlagSet)) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if define != nil {
+		define(fs)
+	}
+	fs.StringVar(configPath, "config", *configPath, "path to a YAML configuration file (CONFIG_FILE)")
+	fs.StringVar(&cfg.Env, "env", cfg.Env, "environment, dev or prod (ENV)")
+	fs.StringVar(&cfg.Log.Level, "log-level", cfg.Log.Level, "least severe log level written: debug, info, warn or error (LOG_LEVEL)")
+	fs.StringVar((*string)(&cfg.Log.Format), "log-format", string(cfg.Log.Format), "log output format, text or json (LOG_FORMAT)")
+	fs.StringVar(&cfg.Server.Port, "port", cfg.Server.Port, "HTTP port (BACKEND_PORT)")
+	fs.DurationVar(&cfg.Server.ShutdownTimeout, "shutdown-timeout", cfg.Server.ShutdownTimeout, "deadline for a graceful shutdown (SHUTDOWN_TIMEOUT)")
+	fs.StringVar(&cfg.Database.Host, "db-host", cfg.Database.Host, "database host (DB_HOST)")
+	fs.StringVar(&cfg.Database.Port, "db-port", cfg.Database.Port, "database port (DB_PORT)")
+	fs.StringVar(&cfg.Database.User, "db-user", cfg.Database.User, "database user (DB_USER)")
+	fs.StringVar(&cfg.Database.Name, "db-name", cfg.Database.Name, "database name (DB_NAME)")
+	fs.StringVar(&cfg.Database.SSLMode, "db-sslmode", cfg.Database.SSLMode, "database SSL mode (DB_SSLMODE)")
+	fs.Func("allowed-origins", "comma-separated CORS and WebSocket origins (ALLOWED_ORIGINS)", func(value string) error {
+		cfg.AllowedOrigins = splitList(value)
+		return nil
+	})
+	fs.DurationVar(&cfg.Session.MembershipTTL, "session-ttl", cfg.Session.MembershipTTL, "how long a room membership survives without activity (SESSION_TTL)")
+	fs.DurationVar(&cfg.Session.CleanupInterval, "cleanup-interval", cfg.Session.CleanupInterval, "interval between expired session sweeps (SESSION_CLEANUP_INTERVAL)")
+	return fs
+}
+
+// resolveDerived fills in the settings that follow from others.
+func (c *AppConfig) resolveDerived() {
+	c.Env = strings.ToLower(strings.TrimSpace(c.Env))
+	c.IsProd = c.Env == "prod"
+	c.IsDev = c.Env == "dev"
+	c.Cookie = CookieConfig{
+		Secure:   resolveSecure(c.IsProd),
+		SameSite: resolveSameSite(c.IsProd),
+	}
+	c.Log.Format = LogFormat(strings.ToLower(strings.TrimSpace(string(c.Log.Format))))
+	c.Protocol.ExtensionPolicy = ExtensionPolicy(strings.ToLower(strings.TrimSpace(string(c.Protocol.ExtensionPolicy))))
+	c.Session.resolveSigningKeys(c.IsDev)
+	c.OIDC.PostLoginURL = strings.TrimRight(c.OIDC.PostLoginURL, "/")
+}
+
+// Validate reports every invalid setting at once, so a broken deployment can
+// be fixed in one go.
+func (c AppConfig) Validate() error {
+	v := &validator{}
+
+	for _, origin := range c.AllowedOrigins {
+		parsed, err := url.Parse(origin)
+		v.check(origin == "*" || err == nil && parsed.Scheme != "" && parsed.Host != "", "allowedOrigins entry %q must be an origin such as https://example.com", origin)
+	}
+
+	c.Log.validate(v)
+	c.Server.validate(v)
+	c.Database.validate(v)
+	c.Protocol.validate(v)
+	c.WebSocket.validate(v)
+	c.RateLimit.validate(v)
+	c.Session.validate(v, c.IsDev)
+	c.Rooms.validate(v)
+	c.OIDC.validate(v)
+	c.Metrics.validate(v, c.IsProd)
+	c.Admin.validate(v)
+
+	if len(v.problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(v.problems, "\n  "))
+	}
+	return nil
+}
+
+// Redacted returns a copy of c with secrets masked, for display.
+func (c AppConfig) Redacted() AppConfig {
+	c.Database.Password = redact(c.Database.Password)
+	c.OIDC.ClientSecret = redact(c.OIDC.ClientSecret)
+	c.Metrics.Token = redact(c.Metrics.Token)
+	c.Admin.Token = redact(c.Admin.Token)
+
+	keys := make([]SigningKey, len(c.Session.SigningKeys))
+	for i, key := range c.Session.SigningKeys {
+		keys[i] = SigningKey{Id: key.Id, Secret: []byte(redacted)}
+	}
+	c.Session.SigningKeys = keys
+	return c
+}
+
+// Print writes the effective configuration as YAML with secrets redacted.
+func (c AppConfig) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redacted
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		v.problems = append(v.problems, fmt.Sprintf(format, args...))
+	}
+}
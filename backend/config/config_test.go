@@ -0,0 +1,165 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes a YAML configuration file for the test and returns
+// its path.
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+// useEnv sets the environment variables for the test, clearing those the
+// tests rely on so that the caller's environment cannot leak in.
+func useEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, key := range []string{"CONFIG_FILE", "BACKEND_PORT", "LOG_LEVEL", "LOG_FORMAT", "DB_HOST", "DB_NAME", "SESSION_TTL"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("ENV", "dev")
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+}
+
+func TestEachSourceOverridesTheOnesBefore(t *testing.T) {
+	path := writeConfigFile(t, `
+server:
+  port: "9000"
+log:
+  level: warn
+database:
+  name: from-file
+session:
+  membershipTTL: 30m
+`)
+	useEnv(t, map[string]string{
+		"LOG_LEVEL":   "debug",
+		"DB_NAME":     "from-env",
+		"SESSION_TTL": "40m",
+	})
+
+	cfg, _, err := load("test", []string{"-config", path, "-db-name", "from-flag"}, nil)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	if cfg.Database.Host != "postgres" {
+		t.Errorf("database.host = %q, want the default", cfg.Database.Host)
+	}
+	if cfg.Server.Port != "9000" {
+		t.Errorf("server.port = %q, want the file's 9000", cfg.Server.Port)
+	}
+	if cfg.Log.Level != "debug" || cfg.Session.MembershipTTL != 40*time.Minute {
+		t.Errorf("log.level = %q, session.membershipTTL = %v; want the environment's debug and 40m", cfg.Log.Level, cfg.Session.MembershipTTL)
+	}
+	if cfg.Database.Name != "from-flag" {
+		t.Errorf("database.name = %q, want the flag's from-flag", cfg.Database.Name)
+	}
+}
+
+func TestConfigFileFromEnvironment(t *testing.T) {
+	useEnv(t, map[string]string{"CONFIG_FILE": writeConfigFile(t, "server:\n  port: \"9000\"\n")})
+
+	cfg, _, err := load("test", nil, nil)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if cfg.Server.Port != "9000" {
+		t.Errorf("server.port = %q, want the port from CONFIG_FILE", cfg.Server.Port)
+	}
+}
+
+func TestCommandFlagsAndArguments(t *testing.T) {
+	useEnv(t, nil)
+
+	var output string
+	cfg, rest, err := load("export", []string{"-o", "room.json", "-port", "9000", "room-1"}, func(fs *flag.FlagSet) {
+		fs.StringVar(&output, "o", "", "output file")
+	})
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if output != "room.json" || cfg.Server.Port != "9000" {
+		t.Errorf("-o = %q, -port = %q; want both flags applied", output, cfg.Server.Port)
+	}
+	if !reflect.DeepEqual(rest, []string{"room-1"}) {
+		t.Errorf("arguments = %q, want [room-1]", rest)
+	}
+}
+
+func TestLoadRejectsMalformedSources(t *testing.T) {
+	useEnv(t, nil)
+
+	// A misspelt key in the file would otherwise be ignored silently.
+	misspelt := writeConfigFile(t, "server:\n  prot: \"9000\"\n")
+	if _, _, err := load("test", []string{"-config", misspelt}, nil); err == nil || !strings.Contains(err.Error(), "prot") {
+		t.Errorf("load with a misspelt file key = %v, want it named", err)
+	}
+
+	t.Setenv("SHUTDOWN_TIMEOUT", "soon")
+	if _, _, err := load("test", nil, nil); err == nil || !strings.Contains(err.Error(), "SHUTDOWN_TIMEOUT") {
+		t.Errorf("load with an unparsable environment value = %v, want it named", err)
+	}
+	t.Setenv("SHUTDOWN_TIMEOUT", "")
+
+	if _, _, err := load("test", []string{"-session-ttl", "soon"}, nil); err == nil {
+		t.Error("load accepted an unparsable flag")
+	}
+}
+
+func TestValidateListsEveryProblem(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Env = "dev"
+	cfg.resolveDerived()
+	cfg.Server.Port = "http"
+	cfg.Log.Level = "loud"
+	cfg.Database.SSLMode = "sometimes"
+	cfg.AllowedOrigins = []string{"example.com"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted an invalid configuration")
+	}
+	for _, problem := range []string{"server.port", "log.level", "database.sslMode", "allowedOrigins"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Validate() = %v, want %s reported", err, problem)
+		}
+	}
+}
+
+func TestRedactedHidesSecrets(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Database.Password = "db-password"
+	cfg.OIDC.ClientSecret = "client-secret"
+	cfg.Admin.Token = "admin-token"
+	cfg.Session.SigningKeys = []SigningKey{{Id: "k1", Secret: []byte("signing-secret")}}
+
+	var out strings.Builder
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("Print failed: %v", err)
+	}
+	for _, secret := range []string{"db-password", "client-secret", "admin-token", "signing-secret"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("printed configuration contains %q", secret)
+		}
+	}
+	if !strings.Contains(out.String(), "k1") {
+		t.Error("printed configuration leaves out the signing key ids")
+	}
+	if cfg.Database.Password != "db-password" {
+		t.Error("Print changed the configuration it printed")
+	}
+}
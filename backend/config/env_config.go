@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envLoader overrides settings with the environment variables that are set,
+// collecting values that cannot be parsed.
+type envLoader struct {
+	errs []error
+}
+
+func (e *envLoader) lookup(key string) (string, bool) {
+	value, exists := os.LookupEnv(key)
+	value = strings.TrimSpace(value)
+	return value, exists && value != ""
+}
+
+func (e *envLoader) String(key string, target *string) {
+	if value, ok := e.lookup(key); ok {
+		*target = value
+	}
+}
+
+// Secret is String without trimming, as secrets may contain any character.
+func (e *envLoader) Secret(key string, target *string) {
+	if value, exists := os.LookupEnv(key); exists && value != "" {
+		*target = value
+	}
+}
+
+func (e *envLoader) List(key string, target *[]string) {
+	if value, ok := e.lookup(key); ok {
+		*target = splitList(value)
+	}
+}
+
+func (e *envLoader) Bool(key string, target *bool) {
+	if value, ok := e.lookup(key); ok {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			e.invalid(key, value)
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (e *envLoader) Int(key string, target *int) {
+	if value, ok := e.lookup(key); ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			e.invalid(key, value)
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (e *envLoader) Int64(key string, target *int64) {
+	if value, ok := e.lookup(key); ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			e.invalid(key, value)
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (e *envLoader) Float(key string, target *float64) {
+	if value, ok := e.lookup(key); ok {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			e.invalid(key, value)
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (e *envLoader) Duration(key string, target *time.Duration) {
+	if value, ok := e.lookup(key); ok {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			e.invalid(key, value)
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (e *envLoader) invalid(key, value string) {
+	e.errs = append(e.errs, fmt.Errorf("invalid %s %q", key, value))
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"log/slog"
+)
+
+type LogFormat string
+
+const (
+	LogFormatText LogFormat = "text"
+	LogFormatJSON LogFormat = "json"
+)
+
+type LogConfig struct {
+	// Level is the least severe level written: debug, info, warn or error.
+	Level  string    `yaml:"level"`
+	Format LogFormat `yaml:"format"`
+}
+
+func defaultLogConfig() LogConfig {
+	return LogConfig{
+		Level:  "info",
+		Format: LogFormatText,
+	}
+}
+
+func (l *LogConfig) applyEnv(env *envLoader) {
+	env.String("LOG_LEVEL", &l.Level)
+	env.String("LOG_FORMAT", (*string)(&l.Format))
+}
+
+// SlogLevel returns Level as a slog.Level, or info if it cannot be parsed.
+func (l LogConfig) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.Level)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+func (l LogConfig) validate(v *validator) {
+	var level slog.Level
+	v.check(level.UnmarshalText([]byte(l.Level)) == nil, "log.level %q must be debug, info, warn or error", l.Level)
+	switch l.Format {
+	case LogFormatText, LogFormatJSON:
+	default:
+		v.check(false, "log.format %q must be text or json", l.Format)
+	}
+}
@@ -0,0 +1,23 @@
+package config
+
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Token must be presented as a Bearer token to read /metrics. It is
+	// required in production, where the endpoint would otherwise be public.
+	Token string `yaml:"token"`
+}
+
+func defaultMetricsConfig() MetricsConfig {
+	return MetricsConfig{
+		Enabled: true,
+	}
+}
+
+func (m *MetricsConfig) applyEnv(env *envLoader) {
+	env.Bool("METRICS_ENABLED", &m.Enabled)
+	env.Secret("METRICS_TOKEN", &m.Token)
+}
+
+func (m MetricsConfig) validate(v *validator, isProd bool) {
+	v.check(!isProd || !m.Enabled || m.Token != "", "metrics.token (METRICS_TOKEN) must be set in production unless metrics are disabled")
+}
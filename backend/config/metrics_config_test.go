@@ -0,0 +1,40 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMetricsNeedATokenInProduction(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Env = "prod"
+	cfg.Session.SigningKeys = []SigningKey{{Id: "k1", Secret: []byte(strings.Repeat("s", minSigningKeyLength))}}
+	cfg.resolveDerived()
+
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "METRICS_TOKEN") {
+		t.Errorf("Validate() without a metrics token = %v, want METRICS_TOKEN required", err)
+	}
+
+	cfg.Metrics.Token = "scraper-token"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with a metrics token = %v", err)
+	}
+
+	cfg.Metrics = MetricsConfig{Enabled: false}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with metrics disabled = %v", err)
+	}
+}
+
+func TestMetricsTokenIsOptionalElsewhere(t *testing.T) {
+	for _, env := range []string{"dev", "staging"} {
+		cfg := defaultConfig()
+		cfg.Env = env
+		cfg.Session.SigningKeys = []SigningKey{{Id: "k1", Secret: []byte(strings.Repeat("s", minSigningKeyLength))}}
+		cfg.resolveDerived()
+
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("ENV=%s: Validate() without a metrics token = %v", env, err)
+		}
+	}
+}
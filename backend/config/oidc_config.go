@@ -0,0 +1,71 @@
+package config
+
+import (
+	"net/url"
+)
+
+type OIDCConfig struct {
+	IssuerURL string `yaml:"issuerURL"`
+	// DiscoveryURL is where the provider metadata is fetched from when it
+	// is not reachable at IssuerURL, e.g. a stand-in issuer running in a
+	// neighbouring container. Tokens must still name IssuerURL.
+	DiscoveryURL string   `yaml:"discoveryURL"`
+	ClientID     string   `yaml:"clientID"`
+	ClientSecret string   `yaml:"clientSecret"`
+	RedirectURL  string   `yaml:"redirectURL"`
+	Scopes       []string `yaml:"scopes"`
+
+	// Claims of the ID token that are mapped onto the account.
+	SubjectClaim       string `yaml:"subjectClaim"`
+	NameClaim          string `yaml:"nameClaim"`
+	EmailClaim         string `yaml:"emailClaim"`
+	EmailVerifiedClaim string `yaml:"emailVerifiedClaim"`
+
+	// PostLoginURL is the frontend address users return to after signing
+	// in; the path they started from is appended to it.
+	PostLoginURL string `yaml:"postLoginURL"`
+}
+
+func (o OIDCConfig) Enabled() bool {
+	return o.IssuerURL != "" && o.ClientID != "" && o.RedirectURL != ""
+}
+
+func defaultOIDCConfig() OIDCConfig {
+	return OIDCConfig{
+		Scopes:             []string{"openid", "profile", "email"},
+		SubjectClaim:       "sub",
+		NameClaim:          "name",
+		EmailClaim:         "email",
+		EmailVerifiedClaim: "email_verified",
+	}
+}
+
+func (o *OIDCConfig) applyEnv(env *envLoader) {
+	env.String("OIDC_ISSUER_URL", &o.IssuerURL)
+	env.String("OIDC_DISCOVERY_URL", &o.DiscoveryURL)
+	env.String("OIDC_CLIENT_ID", &o.ClientID)
+	env.Secret("OIDC_CLIENT_SECRET", &o.ClientSecret)
+	env.String("OIDC_REDIRECT_URL", &o.RedirectURL)
+	env.List("OIDC_SCOPES", &o.Scopes)
+	env.String("OIDC_SUBJECT_CLAIM", &o.SubjectClaim)
+	env.String("OIDC_NAME_CLAIM", &o.NameClaim)
+	env.String("OIDC_EMAIL_CLAIM", &o.EmailClaim)
+	env.String("OIDC_EMAIL_VERIFIED_CLAIM", &o.EmailVerifiedClaim)
+	env.String("OIDC_POST_LOGIN_URL", &o.PostLoginURL)
+}
+
+// validate only applies once single sign-on is at least partly configured.
+func (o OIDCConfig) validate(v *validator) {
+	if o.IssuerURL == "" && o.ClientID == "" && o.RedirectURL == "" {
+		return
+	}
+	v.check(o.Enabled(), "oidc.issuerURL, clientID and redirectURL are all required for single sign-on")
+	for _, setting := range [][2]string{{"issuerURL", o.IssuerURL}, {"discoveryURL", o.DiscoveryURL}, {"redirectURL", o.RedirectURL}} {
+		if setting[1] == "" {
+			continue
+		}
+		parsed, err := url.Parse(setting[1])
+		v.check(err == nil && parsed.Scheme != "" && parsed.Host != "", "oidc.%s %q must be an absolute URL", setting[0], setting[1])
+	}
+	v.check(o.SubjectClaim != "" && o.EmailClaim != "", "oidc.subjectClaim and emailClaim are required")
+}
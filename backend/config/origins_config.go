@@ -0,0 +1,44 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// IsOriginAllowed reports whether origin matches ALLOWED_ORIGINS. Entries may
+// use a wildcard for subdomains, e.g. "https://*.example.com", and "*" allows
+// every origin.
+func (c AppConfig) IsOriginAllowed(origin string) bool {
+	parsed, err := url.Parse(strings.ToLower(origin))
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return false
+	}
+
+	for _, allowed := range c.AllowedOrigins {
+		if matchOrigin(strings.ToLower(allowed), parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchOrigin(allowed string, origin *url.URL) bool {
+	if allowed == "*" {
+		return true
+	}
+
+	pattern, err := url.Parse(allowed)
+	if err != nil || pattern.Scheme != origin.Scheme {
+		return false
+	}
+
+	if !strings.HasPrefix(pattern.Host, "*.") {
+		return pattern.Host == origin.Host
+	}
+
+	if pattern.Port() != origin.Port() {
+		return false
+	}
+	suffix := strings.TrimPrefix(pattern.Hostname(), "*")
+	return strings.HasSuffix(origin.Hostname(), suffix) && len(origin.Hostname()) > len(suffix)
+}
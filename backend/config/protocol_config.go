@@ -0,0 +1,49 @@
+package config
+
+type ExtensionPolicy string
+
+const (
+	ExtensionPolicyDrop    ExtensionPolicy = "drop"
+	ExtensionPolicyForward ExtensionPolicy = "forward"
+)
+
+type ProtocolConfig struct {
+	ExtensionPolicy  ExtensionPolicy `yaml:"extensionPolicy"`
+	ExtensionActions []string        `yaml:"extensionActions"`
+}
+
+// AllowsExtension reports whether a client extension action may be forwarded
+// to the other participants of a room.
+func (p ProtocolConfig) AllowsExtension(action string) bool {
+	if p.ExtensionPolicy != ExtensionPolicyForward {
+		return false
+	}
+	if len(p.ExtensionActions) == 0 {
+		return true
+	}
+	for _, allowed := range p.ExtensionActions {
+		if allowed == action {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultProtocolConfig() ProtocolConfig {
+	return ProtocolConfig{
+		ExtensionPolicy: ExtensionPolicyDrop,
+	}
+}
+
+func (p *ProtocolConfig) applyEnv(env *envLoader) {
+	env.String("WS_EXTENSION_POLICY", (*string)(&p.ExtensionPolicy))
+	env.List("WS_EXTENSION_ACTIONS", &p.ExtensionActions)
+}
+
+func (p ProtocolConfig) validate(v *validator) {
+	switch p.ExtensionPolicy {
+	case ExtensionPolicyDrop, ExtensionPolicyForward:
+	default:
+		v.check(false, "protocol.extensionPolicy %q must be drop or forward", p.ExtensionPolicy)
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"time"
+)
+
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Messages per second and burst size for WebSocket and SSE actions.
+	ConnectionRate  float64 `yaml:"connectionRate"`
+	ConnectionBurst int     `yaml:"connectionBurst"`
+	UserRate        float64 `yaml:"userRate"`
+	UserBurst       int     `yaml:"userBurst"`
+	AddressRate     float64 `yaml:"addressRate"`
+	AddressBurst    int     `yaml:"addressBurst"`
+
+	// Requests per second and burst size per source IP. Each kind of
+	// request has a budget of its own, so that a team sharing one address
+	// can reconnect without using up its joins and sign-ins: creating and
+	// joining rooms, signing in and issuing tokens, and opening WebSocket
+	// and SSE connections.
+	RequestRate  float64 `yaml:"requestRate"`
+	RequestBurst int     `yaml:"requestBurst"`
+	AuthRate     float64 `yaml:"authRate"`
+	AuthBurst    int     `yaml:"authBurst"`
+	UpgradeRate  float64 `yaml:"upgradeRate"`
+	UpgradeBurst int     `yaml:"upgradeBurst"`
+
+	// Connections are closed once they exceed MaxViolations throttled
+	// messages within ViolationWindow.
+	MaxViolations   int           `yaml:"maxViolations"`
+	ViolationWindow time.Duration `yaml:"violationWindow"`
+
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For / X-Real-IP,
+	// which is only safe behind a reverse proxy that sets them.
+	TrustProxyHeaders bool `yaml:"trustProxyHeaders"`
+}
+
+func defaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Enabled:         true,
+		ConnectionRate:  5,
+		ConnectionBurst: 20,
+		UserRate:        10,
+		UserBurst:       30,
+		AddressRate:     50,
+		AddressBurst:    100,
+		RequestRate:     5,
+		RequestBurst:    30,
+		AuthRate:        1,
+		AuthBurst:       10,
+		UpgradeRate:     10,
+		UpgradeBurst:    100,
+		MaxViolations:   20,
+		ViolationWindow: time.Minute,
+	}
+}
+
+func (r *RateLimitConfig) applyEnv(env *envLoader) {
+	env.Bool("RATE_LIMIT_ENABLED", &r.Enabled)
+	env.Float("RATE_LIMIT_WS_CONNECTION_RPS", &r.ConnectionRate)
+	env.Int("RATE_LIMIT_WS_CONNECTION_BURST", &r.ConnectionBurst)
+	env.Float("RATE_LIMIT_WS_USER_RPS", &r.UserRate)
+	env.Int("RATE_LIMIT_WS_USER_BURST", &r.UserBurst)
+	env.Float("RATE_LIMIT_WS_IP_RPS", &r.AddressRate)
+	env.Int("RATE_LIMIT_WS_IP_BURST", &r.AddressBurst)
+	env.Float("RATE_LIMIT_HTTP_IP_RPS", &r.RequestRate)
+	env.Int("RATE_LIMIT_HTTP_IP_BURST", &r.RequestBurst)
+	env.Float("RATE_LIMIT_HTTP_AUTH_RPS", &r.AuthRate)
+	env.Int("RATE_LIMIT_HTTP_AUTH_BURST", &r.AuthBurst)
+	env.Float("RATE_LIMIT_WS_UPGRADE_RPS", &r.UpgradeRate)
+	env.Int("RATE_LIMIT_WS_UPGRADE_BURST", &r.UpgradeBurst)
+	env.Int("RATE_LIMIT_MAX_VIOLATIONS", &r.MaxViolations)
+	env.Duration("RATE_LIMIT_VIOLATION_WINDOW", &r.ViolationWindow)
+	env.Bool("RATE_LIMIT_TRUST_PROXY", &r.TrustProxyHeaders)
+}
+
+func (r RateLimitConfig) validate(v *validator) {
+	if !r.Enabled {
+		return
+	}
+	v.check(r.ConnectionRate > 0 && r.ConnectionBurst > 0, "rateLimit.connectionRate and connectionBurst must be positive")
+	v.check(r.UserRate > 0 && r.UserBurst > 0, "rateLimit.userRate and userBurst must be positive")
+	v.check(r.AddressRate > 0 && r.AddressBurst > 0, "rateLimit.addressRate and addressBurst must be positive")
+	v.check(r.RequestRate > 0 && r.RequestBurst > 0, "rateLimit.requestRate and requestBurst must be positive")
+	v.check(r.AuthRate > 0 && r.AuthBurst > 0, "rateLimit.authRate and authBurst must be positive")
+	v.check(r.UpgradeRate > 0 && r.UpgradeBurst > 0, "rateLimit.upgradeRate and upgradeBurst must be positive")
+	v.check(r.MaxViolations > 0, "rateLimit.maxViolations must be positive")
+	v.check(r.ViolationWindow > 0, "rateLimit.violationWindow must be positive")
+}
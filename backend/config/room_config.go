@@ -0,0 +1,38 @@
+package config
+
+import (
+	"time"
+)
+
+// RoomConfig decides how long rooms live. A zero duration turns the
+// corresponding policy off.
+type RoomConfig struct {
+	// IdleTTL is how long a room may go without activity before it is
+	// archived. Persistent rooms use PersistentIdleTTL instead.
+	IdleTTL           time.Duration `yaml:"idleTTL"`
+	PersistentIdleTTL time.Duration `yaml:"persistentIdleTTL"`
+
+	// ArchiveRetention is how long archived rooms are kept before they are
+	// deleted for good.
+	ArchiveRetention time.Duration `yaml:"archiveRetention"`
+}
+
+func defaultRoomConfig() RoomConfig {
+	return RoomConfig{
+		IdleTTL:           24 * time.Hour,
+		PersistentIdleTTL: 30 * 24 * time.Hour,
+		ArchiveRetention:  90 * 24 * time.Hour,
+	}
+}
+
+func (r *RoomConfig) applyEnv(env *envLoader) {
+	env.Duration("ROOM_IDLE_TTL", &r.IdleTTL)
+	env.Duration("ROOM_PERSISTENT_IDLE_TTL", &r.PersistentIdleTTL)
+	env.Duration("ROOM_ARCHIVE_RETENTION", &r.ArchiveRetention)
+}
+
+func (r RoomConfig) validate(v *validator) {
+	v.check(r.IdleTTL >= 0, "rooms.idleTTL must not be negative")
+	v.check(r.PersistentIdleTTL >= 0, "rooms.persistentIdleTTL must not be negative")
+	v.check(r.ArchiveRetention >= 0, "rooms.archiveRetention must not be negative")
+}
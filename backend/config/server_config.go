@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type ServerConfig struct {
+	Port string `yaml:"port"`
+
+	// ShutdownTimeout bounds the whole shutdown: draining requests, closing
+	// real-time connections, stopping background jobs and the database.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+
+	// ReconnectDelay is the least time clients are told to wait before
+	// reconnecting after a restart. Each client gets up to twice as long so
+	// that reconnects are spread out.
+	ReconnectDelay time.Duration `yaml:"reconnectDelay"`
+
+	// ReadinessTimeout bounds the dependency checks of /readyz.
+	ReadinessTimeout time.Duration `yaml:"readinessTimeout"`
+}
+
+func defaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Port:             "8080",
+		ShutdownTimeout:  15 * time.Second,
+		ReconnectDelay:   2 * time.Second,
+		ReadinessTimeout: 2 * time.Second,
+	}
+}
+
+func (s *ServerConfig) applyEnv(env *envLoader) {
+	env.String("BACKEND_PORT", &s.Port)
+	env.Duration("SHUTDOWN_TIMEOUT", &s.ShutdownTimeout)
+	env.Duration("SHUTDOWN_RECONNECT_DELAY", &s.ReconnectDelay)
+	env.Duration("READINESS_TIMEOUT", &s.ReadinessTimeout)
+}
+
+func (s ServerConfig) validate(v *validator) {
+	port, err := strconv.Atoi(s.Port)
+	v.check(err == nil && port > 0 && port <= 65535, "server.port must be a port number")
+	v.check(s.ShutdownTimeout > 0, "server.shutdownTimeout must be positive")
+	v.check(s.ReconnectDelay > 0, "server.reconnectDelay must be positive")
+	v.check(s.ReadinessTimeout > 0, "server.readinessTimeout must be positive")
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslMode"`
+}
+
+func defaultDatabaseConfig() DatabaseConfig {
+	return DatabaseConfig{
+		Host:     "postgres",
+		Port:     "5432",
+		User:     "postgres",
+		Password: "postgres",
+		Name:     "scrumpoker",
+		SSLMode:  "disable",
+	}
+}
+
+func (d *DatabaseConfig) applyEnv(env *envLoader) {
+	env.String("DB_HOST", &d.Host)
+	env.String("DB_PORT", &d.Port)
+	env.String("DB_USER", &d.User)
+	env.Secret("DB_PASSWORD", &d.Password)
+	env.String("DB_NAME", &d.Name)
+	env.String("DB_SSLMODE", &d.SSLMode)
+}
+
+// ConnectionString returns the lib/pq connection string for the database.
+func (d DatabaseConfig) ConnectionString() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode)
+}
+
+func (d DatabaseConfig) validate(v *validator) {
+	v.check(d.Host != "", "database.host is required")
+	v.check(d.Port != "", "database.port is required")
+	v.check(d.User != "", "database.user is required")
+	v.check(d.Name != "", "database.name is required")
+
+	switch d.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		v.check(false, "database.sslMode %q is not a PostgreSQL SSL mode", d.SSLMode)
+	}
+}
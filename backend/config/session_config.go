@@ -0,0 +1,149 @@
+package config
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const minSigningKeyLength = 32
+
+type SigningKey struct {
+	Id     string
+	Secret []byte
+}
+
+// parseSigningKey reads a key written as id:secret.
+func parseSigningKey(value string) (SigningKey, error) {
+	id, secret, ok := strings.Cut(value, ":")
+	id = strings.TrimSpace(id)
+	if !ok || id == "" || strings.Contains(id, ".") || secret == "" {
+		return SigningKey{}, fmt.Errorf("malformed signing key for key %q, expected id:secret", id)
+	}
+	return SigningKey{Id: id, Secret: []byte(secret)}, nil
+}
+
+func (k *SigningKey) UnmarshalYAML(node *yaml.Node) error {
+	var value string
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	key, err := parseSigningKey(value)
+	if err != nil {
+		return err
+	}
+	*k = key
+	return nil
+}
+
+func (k SigningKey) MarshalYAML() (interface{}, error) {
+	return k.Id + ":" + string(k.Secret), nil
+}
+
+type SessionConfig struct {
+	// SigningKeys sign and verify session tokens. The first key signs new
+	// tokens; the remaining keys are only used for verification, so a key
+	// can be rotated out without logging everybody out at once.
+	SigningKeys []SigningKey `yaml:"signingKeys"`
+
+	// TokenTTL bounds how long an issued token is accepted. Tokens are
+	// reissued whenever the session is refreshed over HTTP.
+	TokenTTL time.Duration `yaml:"tokenTTL"`
+
+	// AccountTTL is how long a sign-in lasts without being refreshed.
+	AccountTTL time.Duration `yaml:"accountTTL"`
+
+	// MembershipTTL is how long a room membership lasts without activity.
+	MembershipTTL time.Duration `yaml:"membershipTTL"`
+
+	// CleanupInterval is how often expired memberships are swept.
+	CleanupInterval time.Duration `yaml:"cleanupInterval"`
+}
+
+// ActiveSigningKey returns the key used to sign new tokens.
+func (s SessionConfig) ActiveSigningKey() SigningKey {
+	return s.SigningKeys[0]
+}
+
+func (s SessionConfig) SigningKey(id string) (SigningKey, bool) {
+	for _, key := range s.SigningKeys {
+		if key.Id == id {
+			return key, true
+		}
+	}
+	return SigningKey{}, false
+}
+
+func defaultSessionConfig() SessionConfig {
+	return SessionConfig{
+		TokenTTL:        24 * time.Hour,
+		AccountTTL:      7 * 24 * time.Hour,
+		MembershipTTL:   20 * time.Minute,
+		CleanupInterval: time.Minute,
+	}
+}
+
+// applyEnv reads SESSION_SIGNING_KEYS, a comma separated list of id:secret
+// pairs with the signing key first, among the other session settings.
+func (s *SessionConfig) applyEnv(env *envLoader) {
+	var entries []string
+	env.List("SESSION_SIGNING_KEYS", &entries)
+	if len(entries) > 0 {
+		keys := make([]SigningKey, 0, len(entries))
+		for _, entry := range entries {
+			key, err := parseSigningKey(entry)
+			if err != nil {
+				env.errs = append(env.errs, fmt.Errorf("invalid SESSION_SIGNING_KEYS: %v", err))
+				continue
+			}
+			keys = append(keys, key)
+		}
+		s.SigningKeys = keys
+	}
+
+	env.Duration("SESSION_TOKEN_TTL", &s.TokenTTL)
+	env.Duration("SESSION_ACCOUNT_TTL", &s.AccountTTL)
+	env.Duration("SESSION_TTL", &s.MembershipTTL)
+	env.Duration("SESSION_CLEANUP_INTERVAL", &s.CleanupInterval)
+}
+
+// resolveSigningKeys generates a random key in development when none is
+// configured, which invalidates every session when the server restarts.
+// Anywhere else validate requires configured keys, since sessions would
+// neither survive a restart nor work across replicas.
+func (s *SessionConfig) resolveSigningKeys(isDev bool) {
+	if len(s.SigningKeys) > 0 || !isDev {
+		return
+	}
+
+	slog.Warn("SESSION_SIGNING_KEYS is not set, sessions will not survive a restart")
+	secret := make([]byte, minSigningKeyLength)
+	if _, err := rand.Read(secret); err != nil {
+		slog.Error("Failed to generate session signing key", "error", err)
+		os.Exit(1)
+	}
+	s.SigningKeys = []SigningKey{{Id: "ephemeral", Secret: secret}}
+}
+
+func (s SessionConfig) validate(v *validator, isDev bool) {
+	v.check(len(s.SigningKeys) > 0, "session.signingKeys (SESSION_SIGNING_KEYS) must be set outside development")
+	for _, key := range s.SigningKeys {
+		if len(key.Secret) >= minSigningKeyLength {
+			continue
+		}
+		if isDev {
+			slog.Warn("Session signing key is too short", "key_id", key.Id, "min_bytes", minSigningKeyLength)
+		} else {
+			v.check(false, "session signing key %q must be at least %d bytes", key.Id, minSigningKeyLength)
+		}
+	}
+	v.check(s.TokenTTL > 0, "session.tokenTTL must be positive")
+	v.check(s.AccountTTL > 0, "session.accountTTL must be positive")
+	v.check(s.MembershipTTL > 0, "session.membershipTTL must be positive")
+	v.check(s.CleanupInterval > 0, "session.cleanupInterval must be positive")
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSigningKeysAreRequiredOutsideDevelopment(t *testing.T) {
+	short := SigningKey{Id: "short", Secret: []byte("too-short")}
+	strong := SigningKey{Id: "strong", Secret: []byte(strings.Repeat("s", minSigningKeyLength))}
+
+	for _, env := range []string{"prod", ""} {
+		cfg := defaultConfig()
+		cfg.Env = env
+		cfg.Metrics.Token = "scraper-token"
+		cfg.resolveDerived()
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "SESSION_SIGNING_KEYS") {
+			t.Errorf("ENV=%q without keys: Validate() = %v, want SESSION_SIGNING_KEYS required", env, err)
+		}
+
+		cfg.Session.SigningKeys = []SigningKey{strong, short}
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `"short"`) {
+			t.Errorf("ENV=%q with a short key: Validate() = %v, want the short key rejected", env, err)
+		}
+
+		cfg.Session.SigningKeys = []SigningKey{strong}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("ENV=%q with a strong key: Validate() = %v", env, err)
+		}
+	}
+}
+
+func TestDevelopmentGeneratesAKey(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Env = "dev"
+	cfg.resolveDerived()
+
+	if len(cfg.Session.SigningKeys) != 1 || len(cfg.Session.ActiveSigningKey().Secret) < minSigningKeyLength {
+		t.Fatalf("SigningKeys = %d keys, want one generated key", len(cfg.Session.SigningKeys))
+	}
+	cfg.Session.SigningKeys = append(cfg.Session.SigningKeys, SigningKey{Id: "short", Secret: []byte("x")})
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() in development = %v, want short keys only warned about", err)
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"time"
+)
+
+type WebSocketConfig struct {
+	// WriteWait bounds how long a single write to a client may take.
+	WriteWait time.Duration `yaml:"writeWait"`
+
+	// PongWait is how long a connection may stay silent before it is
+	// considered dead. Pings are sent at nine tenths of it.
+	PongWait time.Duration `yaml:"pongWait"`
+
+	// MaxMessageSize is the largest client message accepted, in bytes, over
+	// WebSocket and POST /rooms/{roomId}/actions alike.
+	MaxMessageSize int64 `yaml:"maxMessageSize"`
+
+	// Recent events of each room are kept for EventBufferRetention after the
+	// room empties, up to EventBufferSize, so reconnecting clients can
+	// replay what they missed.
+	EventBufferSize      int           `yaml:"eventBufferSize"`
+	EventBufferRetention time.Duration `yaml:"eventBufferRetention"`
+}
+
+func defaultWebSocketConfig() WebSocketConfig {
+	return WebSocketConfig{
+		WriteWait:            10 * time.Second,
+		PongWait:             60 * time.Second,
+		MaxMessageSize:       2048,
+		EventBufferSize:      128,
+		EventBufferRetention: 10 * time.Minute,
+	}
+}
+
+func (w *WebSocketConfig) applyEnv(env *envLoader) {
+	env.Duration("WS_WRITE_WAIT", &w.WriteWait)
+	env.Duration("WS_PONG_WAIT", &w.PongWait)
+	env.Int64("WS_MAX_MESSAGE_SIZE", &w.MaxMessageSize)
+	env.Int("WS_EVENT_BUFFER_SIZE", &w.EventBufferSize)
+	env.Duration("WS_EVENT_BUFFER_RETENTION", &w.EventBufferRetention)
+}
+
+func (w WebSocketConfig) PingPeriod() time.Duration {
+	return (w.PongWait * 9) / 10
+}
+
+func (w WebSocketConfig) validate(v *validator) {
+	v.check(w.WriteWait > 0, "websocket.writeWait must be positive")
+	v.check(w.PongWait > 0, "websocket.pongWait must be positive")
+	v.check(w.MaxMessageSize > 0, "websocket.maxMessageSize must be positive")
+	v.check(w.EventBufferSize > 0, "websocket.eventBufferSize must be positive")
+	v.check(w.EventBufferRetention > 0, "websocket.eventBufferRetention must be positive")
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/scrum-poker/backend/config"
+)
+
+// runConfigCommand implements `config print`, which shows the effective
+// configuration, after the file, environment and flags are applied, with
+// secrets redacted.
+func runConfigCommand(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New("usage: scrum-poker config print [flags]")
+	}
+
+	rest, err := config.LoadCommand("scrum-poker config print", args[1:], nil)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected argument %q", rest[0])
+	}
+	return config.Cfg.Print(os.Stdout)
+}
@@ -0,0 +1,104 @@
+package csrf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/scrum-poker/backend/config"
+)
+
+// serve sends a request from a page at origin to the API at
+// https://poker.example.com through Middleware and returns the status.
+func serve(t *testing.T, method, origin string, header http.Header) int {
+	t.Helper()
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	r := httptest.NewRequest(method, "https://poker.example.com/rooms", nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func useProduction(t *testing.T) {
+	t.Helper()
+	previous := config.Cfg
+	t.Cleanup(func() { config.Cfg = previous })
+	config.Cfg.IsDev = false
+	config.Cfg.AllowedOrigins = []string{"https://app.example.com", "https://*.preview.example.com"}
+}
+
+func TestCrossSiteWritesAreRejected(t *testing.T) {
+	useProduction(t)
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		if code := serve(t, method, "https://evil.example.net", nil); code != http.StatusForbidden {
+			t.Errorf("cross-site %s got %d, want 403", method, code)
+		}
+	}
+	if code := serve(t, http.MethodPost, "null", nil); code != http.StatusForbidden {
+		t.Errorf("POST from an opaque origin got %d, want 403", code)
+	}
+
+	// Browsers that leave out Origin still say where the request came from.
+	header := http.Header{"Sec-Fetch-Site": {"cross-site"}}
+	if code := serve(t, http.MethodPost, "", header); code != http.StatusForbidden {
+		t.Errorf("POST marked cross-site got %d, want 403", code)
+	}
+}
+
+func TestSameSiteWritesPass(t *testing.T) {
+	useProduction(t)
+
+	origins := []string{
+		"https://poker.example.com",        // the API itself
+		"https://POKER.example.com",        // hosts are case-insensitive
+		"https://app.example.com",          // listed
+		"https://team.preview.example.com", // wildcard subdomain
+	}
+	for _, origin := range origins {
+		if code := serve(t, http.MethodPost, origin, nil); code != http.StatusNoContent {
+			t.Errorf("POST from %s got %d, want it to pass", origin, code)
+		}
+	}
+
+	if code := serve(t, http.MethodPost, "", http.Header{"Sec-Fetch-Site": {"same-site"}}); code != http.StatusNoContent {
+		t.Errorf("POST marked same-site got %d, want it to pass", code)
+	}
+	// Scripts and other clients that are not browsers send neither header.
+	if code := serve(t, http.MethodPost, "", nil); code != http.StatusNoContent {
+		t.Errorf("POST without browser headers got %d, want it to pass", code)
+	}
+}
+
+func TestRequestsABrowserCannotForgePass(t *testing.T) {
+	useProduction(t)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+		if code := serve(t, method, "https://evil.example.net", nil); code != http.StatusNoContent {
+			t.Errorf("cross-site %s got %d, want it to pass", method, code)
+		}
+	}
+
+	header := http.Header{"Authorization": {"Bearer token"}}
+	if code := serve(t, http.MethodPost, "https://evil.example.net", header); code != http.StatusNoContent {
+		t.Errorf("cross-site POST with an API token got %d, want it to pass", code)
+	}
+}
+
+func TestDevelopmentAcceptsEveryOrigin(t *testing.T) {
+	useProduction(t)
+	config.Cfg.IsDev = true
+
+	if code := serve(t, http.MethodPost, "https://evil.example.net", nil); code != http.StatusNoContent {
+		t.Errorf("cross-site POST in development got %d, want it to pass", code)
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/scrum-poker/backend/metrics"
+	"github.com/scrum-poker/backend/models"
+)
+
+var ErrEmailTaken = errors.New("email already registered")
+
+const selectAccount = "SELECT id, email, display_name, email_verified, password_hash, created_at, updated_at FROM accounts"
+
+func CreateAccount(account *models.Account) error {
+	defer metrics.TimeQuery("CreateAccount")()
+	_, err := DB.Exec(
+		`INSERT INTO accounts (id, email, display_name, email_verified, password_hash, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		account.Id, account.Email, account.DisplayName, account.EmailVerified, account.PasswordHash, account.CreatedAt, account.UpdatedAt,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrEmailTaken
+		}
+		return fmt.Errorf("failed to create account: %v", err)
+	}
+	return nil
+}
+
+func GetAccount(accountId string) (*models.Account, error) {
+	defer metrics.TimeQuery("GetAccount")()
+	return scanAccount(DB.QueryRow(selectAccount+" WHERE id = $1", accountId))
+}
+
+func GetAccountByEmail(email string) (*models.Account, error) {
+	defer metrics.TimeQuery("GetAccountByEmail")()
+	return scanAccount(DB.QueryRow(selectAccount+" WHERE email = $1", email))
+}
+
+func UpdateAccountPassword(account *models.Account) error {
+	defer metrics.TimeQuery("UpdateAccountPassword")()
+	_, err := DB.Exec(
+		"UPDATE accounts SET password_hash = $1, updated_at = $2 WHERE id = $3",
+		account.PasswordHash, account.UpdatedAt, account.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update account password: %v", err)
+	}
+	return nil
+}
+
+// GetAccountByIdentity returns the account linked to the identity provider
+// subject.
+func GetAccountByIdentity(issuer, subject string) (*models.Account, error) {
+	defer metrics.TimeQuery("GetAccountByIdentity")()
+	return scanAccount(DB.QueryRow(`
+		SELECT a.id, a.email, a.display_name, a.email_verified, a.password_hash, a.created_at, a.updated_at
+		FROM accounts a
+		JOIN account_identities ai ON ai.account_id = a.id
+		WHERE ai.issuer = $1 AND ai.subject = $2
+	`, issuer, subject))
+}
+
+func LinkAccountIdentity(issuer, subject, accountId string) error {
+	defer metrics.TimeQuery("LinkAccountIdentity")()
+	_, err := DB.Exec(
+		"INSERT INTO account_identities (issuer, subject, account_id) VALUES ($1, $2, $3) ON CONFLICT (issuer, subject) DO NOTHING",
+		issuer, subject, accountId,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to link account identity: %v", err)
+	}
+	return nil
+}
+
+// UpdateAccountProfile stores the display name and email verification state
+// reported by an identity provider.
+func UpdateAccountProfile(account *models.Account) error {
+	defer metrics.TimeQuery("UpdateAccountProfile")()
+	_, err := DB.Exec(
+		"UPDATE accounts SET display_name = $1, email_verified = $2, updated_at = $3 WHERE id = $4",
+		account.DisplayName, account.EmailVerified, account.UpdatedAt, account.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update account: %v", err)
+	}
+	return nil
+}
+
+func scanAccount(row *sql.Row) (*models.Account, error) {
+	var account models.Account
+	err := row.Scan(&account.Id, &account.Email, &account.DisplayName, &account.EmailVerified, &account.PasswordHash, &account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("account not found")
+		}
+		return nil, fmt.Errorf("failed to get account: %v", err)
+	}
+	return &account, nil
+}
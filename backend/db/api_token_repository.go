@@ -0,0 +1,134 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/scrum-poker/backend/metrics"
+	"github.com/scrum-poker/backend/models"
+)
+
+const selectAPIToken = "SELECT id, name, account_id, COALESCE(room_id, ''), session_id, scopes, token_hash, created_at, expires_at, last_used_at FROM api_tokens"
+
+// CreateAPIToken stores token together with the session it acts through.
+func CreateAPIToken(token *models.APIToken) error {
+	defer metrics.TimeQuery("CreateAPIToken")()
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to create API token: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"INSERT INTO sessions (id, created_at, expires_at, account_id) VALUES ($1, $2, $3, $4)",
+		token.SessionId, token.CreatedAt, token.ExpiresAt, token.AccountId,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create API token session: %v", err)
+	}
+
+	var roomId sql.NullString
+	if token.RoomId != "" {
+		roomId = sql.NullString{String: token.RoomId, Valid: true}
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO api_tokens (id, name, account_id, room_id, session_id, scopes, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		token.Id, token.Name, token.AccountId, roomId, token.SessionId, joinList(token.Scopes), token.TokenHash, token.CreatedAt, token.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create API token: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create API token: %v", err)
+	}
+	return nil
+}
+
+func GetAPITokenByHash(tokenHash string) (*models.APIToken, error) {
+	defer metrics.TimeQuery("GetAPITokenByHash")()
+	row := DB.QueryRow(selectAPIToken+" WHERE token_hash = $1", tokenHash)
+
+	token, err := scanAPIToken(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("API token not found")
+		}
+		return nil, fmt.Errorf("failed to get API token: %v", err)
+	}
+	return token, nil
+}
+
+func GetAccountAPITokens(accountId string) ([]*models.APIToken, error) {
+	defer metrics.TimeQuery("GetAccountAPITokens")()
+	rows, err := DB.Query(selectAPIToken+" WHERE account_id = $1 ORDER BY created_at", accountId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API tokens: %v", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]*models.APIToken, 0)
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %v", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func TouchAPIToken(tokenId string, usedAt time.Time) error {
+	defer metrics.TimeQuery("TouchAPIToken")()
+	_, err := DB.Exec("UPDATE api_tokens SET last_used_at = $1 WHERE id = $2", usedAt, tokenId)
+	if err != nil {
+		return fmt.Errorf("failed to update API token: %v", err)
+	}
+	return nil
+}
+
+// DeleteAPIToken revokes a token of accountId by deleting its session, which
+// removes the token and the token's room memberships with it. It reports
+// whether such a token existed.
+func DeleteAPIToken(tokenId, accountId string) (bool, error) {
+	defer metrics.TimeQuery("DeleteAPIToken")()
+	result, err := DB.Exec(
+		"DELETE FROM sessions WHERE id = (SELECT session_id FROM api_tokens WHERE id = $1 AND account_id = $2)",
+		tokenId, accountId,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete API token: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete API token: %v", err)
+	}
+	return affected > 0, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIToken(row rowScanner) (*models.APIToken, error) {
+	var token models.APIToken
+	var scopes string
+	var lastUsedAt sql.NullTime
+	err := row.Scan(&token.Id, &token.Name, &token.AccountId, &token.RoomId, &token.SessionId, &scopes,
+		&token.TokenHash, &token.CreatedAt, &token.ExpiresAt, &lastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = splitList(scopes)
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return &token, nil
+}
@@ -1,29 +1,29 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 
 	_ "github.com/lib/pq"
+	"github.com/scrum-poker/backend/config"
 )
 
 var DB *sql.DB
 
+// Connect opens the database and brings its schema up to date.
 func Connect() error {
-	host := getEnv("DB_HOST", "postgres")
-	port := getEnv("DB_PORT", "5432")
-	user := getEnv("DB_USER", "postgres")
-	password := getEnv("DB_PASSWORD", "postgres")
-	dbname := getEnv("DB_NAME", "scrumpoker")
-	sslmode := getEnv("DB_SSLMODE", "disable")
-
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		host, port, user, password, dbname, sslmode)
+	if err := Open(); err != nil {
+		return err
+	}
+	return Migrate()
+}
 
+// Open connects to the database without touching the schema.
+func Open() error {
 	var err error
-	DB, err = sql.Open("postgres", connStr)
+	DB, err = sql.Open("postgres", config.Cfg.Database.ConnectionString())
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %v", err)
 	}
@@ -33,18 +33,56 @@ func Connect() error {
 		return fmt.Errorf("failed to ping database: %v", err)
 	}
 
-	log.Println("Connected to database")
+	slog.Info("Connected to database")
+	return nil
+}
 
-	err = createTables()
-	if err != nil {
+// Migrate creates missing tables and columns and migrates data from older
+// layouts. It is safe to run repeatedly.
+func Migrate() error {
+	if err := createTables(); err != nil {
 		return fmt.Errorf("failed to create tables: %v", err)
 	}
-
 	return nil
 }
 
 func createTables() error {
 	_, err := DB.Exec(`
+		CREATE TABLE IF NOT EXISTS accounts (
+			id VARCHAR(36) PRIMARY KEY,
+			email VARCHAR(255) NOT NULL UNIQUE,
+			display_name VARCHAR(255) NOT NULL,
+			password_hash VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create accounts table: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		ALTER TABLE accounts ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add email_verified to accounts table: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS account_identities (
+			issuer VARCHAR(255) NOT NULL,
+			subject VARCHAR(255) NOT NULL,
+			account_id VARCHAR(36) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (issuer, subject),
+			FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create account_identities table: %v", err)
+	}
+
+	_, err = DB.Exec(`
 		CREATE TABLE IF NOT EXISTS rooms (
 			id VARCHAR(36) PRIMARY KEY,
 			name VARCHAR(255) NOT NULL,
@@ -56,6 +94,55 @@ func createTables() error {
 		return fmt.Errorf("failed to create rooms table: %v", err)
 	}
 
+	_, err = DB.Exec(`
+		ALTER TABLE rooms
+			ADD COLUMN IF NOT EXISTS owner_account_id VARCHAR(36) REFERENCES accounts(id) ON DELETE SET NULL,
+			ADD COLUMN IF NOT EXISTS allow_guests BOOLEAN NOT NULL DEFAULT TRUE,
+			ADD COLUMN IF NOT EXISTS allowed_email_domains TEXT NOT NULL DEFAULT ''
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add account columns to rooms table: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		ALTER TABLE rooms
+			ADD COLUMN IF NOT EXISTS persistent BOOLEAN NOT NULL DEFAULT FALSE,
+			ADD COLUMN IF NOT EXISTS last_active_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP,
+			ADD COLUMN IF NOT EXISTS archive TEXT
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add lifecycle columns to rooms table: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE INDEX IF NOT EXISTS rooms_last_active_at_idx ON rooms (last_active_at) WHERE archived_at IS NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create rooms activity index: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE INDEX IF NOT EXISTS rooms_archived_at_idx ON rooms (archived_at) WHERE archived_at IS NOT NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create rooms archive index: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS profiles (
+			id VARCHAR(36) PRIMARY KEY,
+			display_name VARCHAR(255) NOT NULL,
+			avatar_seed VARCHAR(64) NOT NULL,
+			preferred_role VARCHAR(20) NOT NULL DEFAULT 'voter',
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create profiles table: %v", err)
+	}
+
 	_, err = DB.Exec(`
 		CREATE TABLE IF NOT EXISTS users (
 			id VARCHAR(36) PRIMARY KEY,
@@ -67,6 +154,22 @@ func createTables() error {
 		return fmt.Errorf("failed to create users table: %v", err)
 	}
 
+	_, err = DB.Exec(`
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS profile_id VARCHAR(36) REFERENCES profiles(id) ON DELETE SET NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add profile_id to users table: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		ALTER TABLE users
+			ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'voter',
+			ADD COLUMN IF NOT EXISTS avatar_seed VARCHAR(64) NOT NULL DEFAULT ''
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add role and avatar_seed to users table: %v", err)
+	}
+
 	_, err = DB.Exec(`
 		CREATE TABLE IF NOT EXISTS room_participants (
 			room_id VARCHAR(36) NOT NULL,
@@ -97,32 +200,169 @@ func createTables() error {
 	_, err = DB.Exec(`
 		CREATE TABLE IF NOT EXISTS sessions (
 			id VARCHAR(36) PRIMARY KEY,
-			user_id VARCHAR(36) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			expires_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create sessions table: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		ALTER TABLE sessions ADD COLUMN IF NOT EXISTS account_id VARCHAR(36) REFERENCES accounts(id) ON DELETE SET NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to add account_id to sessions table: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS session_memberships (
+			session_id VARCHAR(36) NOT NULL,
 			room_id VARCHAR(36) NOT NULL,
+			user_id VARCHAR(36) NOT NULL,
 			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
 			expires_at TIMESTAMP NOT NULL,
-			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			PRIMARY KEY (session_id, room_id),
+			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+			FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create session_memberships table: %v", err)
+	}
+
+	if err := migrateSessionMemberships(); err != nil {
+		return err
+	}
+
+	_, err = DB.Exec(`
+		CREATE INDEX IF NOT EXISTS session_memberships_expires_at_idx ON session_memberships (expires_at)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create session_memberships expiry index: %v", err)
+	}
+
+	if err := uniqueUserMemberships(); err != nil {
+		return err
+	}
+
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS api_tokens (
+			id VARCHAR(36) PRIMARY KEY,
+			name VARCHAR(100) NOT NULL,
+			account_id VARCHAR(36) NOT NULL,
+			room_id VARCHAR(36),
+			session_id VARCHAR(36) NOT NULL UNIQUE,
+			scopes TEXT NOT NULL,
+			token_hash CHAR(64) NOT NULL UNIQUE,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			expires_at TIMESTAMP NOT NULL,
+			last_used_at TIMESTAMP,
+			FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE,
+			FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE,
+			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create api_tokens table: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS stories (
+			id VARCHAR(36) PRIMARY KEY,
+			room_id VARCHAR(36) NOT NULL,
+			title VARCHAR(200) NOT NULL,
+			position INTEGER NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			UNIQUE (room_id, position),
 			FOREIGN KEY (room_id) REFERENCES rooms(id) ON DELETE CASCADE
 		)
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to create sessions table: %v", err)
+		return fmt.Errorf("failed to create stories table: %v", err)
 	}
 
-	log.Println("Tables created successfully")
+	slog.Info("Tables created successfully")
 	return nil
 }
 
-func Close() {
-	if DB != nil {
-		DB.Close()
-		log.Println("Database connection closed")
+// migrateSessionMemberships moves the room and user of sessions created
+// before a session could span several rooms into session_memberships.
+func migrateSessionMemberships() error {
+	var legacy bool
+	err := DB.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'sessions' AND column_name = 'room_id'
+		)
+	`).Scan(&legacy)
+	if err != nil {
+		return fmt.Errorf("failed to inspect sessions table: %v", err)
+	}
+	if !legacy {
+		return nil
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to migrate sessions: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO session_memberships (session_id, room_id, user_id, created_at, expires_at)
+		SELECT id, room_id, user_id, created_at, expires_at FROM sessions
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to migrate sessions: %v", err)
+	}
+
+	if _, err := tx.Exec("ALTER TABLE sessions DROP COLUMN user_id, DROP COLUMN room_id"); err != nil {
+		return fmt.Errorf("failed to migrate sessions: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to migrate sessions: %v", err)
+	}
+	slog.Info("Migrated sessions to session memberships")
+	return nil
+}
+
+// uniqueUserMemberships makes sure a user holds at most one membership in a
+// room. Duplicates left by earlier versions are removed first, keeping the
+// membership that lasts longest.
+func uniqueUserMemberships() error {
+	_, err := DB.Exec(`
+		DELETE FROM session_memberships a USING session_memberships b
+		WHERE a.user_id = b.user_id AND a.room_id = b.room_id
+			AND (a.expires_at < b.expires_at OR (a.expires_at = b.expires_at AND a.session_id < b.session_id))
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to remove duplicate session memberships: %v", err)
+	}
+
+	_, err = DB.Exec(`
+		CREATE UNIQUE INDEX IF NOT EXISTS session_memberships_user_id_room_id_idx ON session_memberships (user_id, room_id)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create session_memberships user index: %v", err)
 	}
+	return nil
 }
 
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// Ping checks that the database is reachable.
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("not connected")
+	}
+	return DB.PingContext(ctx)
+}
+
+func Close() {
+	if DB != nil {
+		DB.Close()
+		slog.Info("Database connection closed")
 	}
-	return fallback
 }
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/scrum-poker/backend/models"
+)
+
+// useTestDB points DB at the database named by TEST_DATABASE_URL, migrated
+// and emptied, and skips the test when it is not set. Every table is
+// truncated, so the database must be one set aside for tests.
+func useTestDB(t *testing.T) {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	conn, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	previous := DB
+	DB = conn
+	t.Cleanup(func() {
+		conn.Close()
+		DB = previous
+	})
+
+	if err := Migrate(); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	if _, err := DB.Exec("TRUNCATE accounts, profiles, rooms, users, sessions CASCADE"); err != nil {
+		t.Fatalf("failed to empty test database: %v", err)
+	}
+}
+
+// createTestRoom stores a room with the given participants, the first of
+// which is its Scrum Master.
+func createTestRoom(t *testing.T, persistent bool, userIds ...string) *models.Room {
+	t.Helper()
+	room := models.NewRoom(uuid.New().String(), "Refinement", userIds[0])
+	room.Persistent = persistent
+	err := InTx(func(tx *Tx) error {
+		if err := tx.CreateRoom(room); err != nil {
+			return err
+		}
+		for _, userId := range userIds {
+			user := models.NewUser(userId, userId)
+			room.AddParticipant(user)
+			if err := tx.AddParticipantToRoom(room.Id, user); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to create room: %v", err)
+	}
+	return room
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/scrum-poker/backend/metrics"
+	"github.com/scrum-poker/backend/models"
+)
+
+func CreateProfile(profile *models.Profile) error {
+	defer metrics.TimeQuery("CreateProfile")()
+	_, err := DB.Exec(
+		`INSERT INTO profiles (id, display_name, avatar_seed, preferred_role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		profile.Id, profile.DisplayName, profile.AvatarSeed, profile.PreferredRole, profile.CreatedAt, profile.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create profile: %v", err)
+	}
+	return nil
+}
+
+func GetProfile(profileId string) (*models.Profile, error) {
+	defer metrics.TimeQuery("GetProfile")()
+	var profile models.Profile
+	err := DB.QueryRow(
+		"SELECT id, display_name, avatar_seed, preferred_role, created_at, updated_at FROM profiles WHERE id = $1",
+		profileId,
+	).Scan(&profile.Id, &profile.DisplayName, &profile.AvatarSeed, &profile.PreferredRole, &profile.CreatedAt, &profile.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("profile not found")
+		}
+		return nil, fmt.Errorf("failed to get profile: %v", err)
+	}
+
+	return &profile, nil
+}
+
+func UpdateProfile(profile *models.Profile) error {
+	defer metrics.TimeQuery("UpdateProfile")()
+	_, err := DB.Exec(
+		"UPDATE profiles SET display_name = $1, avatar_seed = $2, preferred_role = $3, updated_at = $4 WHERE id = $5",
+		profile.DisplayName, profile.AvatarSeed, profile.PreferredRole, profile.UpdatedAt, profile.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %v", err)
+	}
+	return nil
+}
+
+// DeleteProfile removes a profile. Users created from it remain, unlinked.
+func DeleteProfile(profileId string) error {
+	defer metrics.TimeQuery("DeleteProfile")()
+	_, err := DB.Exec("DELETE FROM profiles WHERE id = $1", profileId)
+	if err != nil {
+		return fmt.Errorf("failed to delete profile: %v", err)
+	}
+	return nil
+}
@@ -2,17 +2,34 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/scrum-poker/backend/metrics"
 	"github.com/scrum-poker/backend/models"
 )
 
+const roomColumns = "id, name, created_at, scrum_master, COALESCE(owner_account_id, ''), allow_guests, allowed_email_domains, persistent, last_active_at, archived_at"
+
 func CreateRoom(room *models.Room) error {
-	_, err := DB.Exec(
-		"INSERT INTO rooms (id, name, created_at, scrum_master) VALUES ($1, $2, $3, $4)",
-		room.Id, room.Name, room.CreatedAt, room.ScrumMaster,
+	defer metrics.TimeQuery("CreateRoom")()
+	return createRoom(DB, room)
+}
+
+func (tx *Tx) CreateRoom(room *models.Room) error {
+	defer metrics.TimeQuery("CreateRoom")()
+	return createRoom(tx.q, room)
+}
+
+func createRoom(q Querier, room *models.Room) error {
+	_, err := q.Exec(
+		`INSERT INTO rooms (id, name, created_at, scrum_master, owner_account_id, allow_guests, allowed_email_domains, persistent, last_active_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9)`,
+		room.Id, room.Name, room.CreatedAt, room.ScrumMaster, room.OwnerAccountId, room.AllowGuests, joinList(room.AllowedEmailDomains),
+		room.Persistent, room.LastActiveAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create room: %v", err)
@@ -22,7 +39,17 @@ func CreateRoom(room *models.Room) error {
 }
 
 func DeleteRoom(roomId string) error {
-	_, err := DB.Exec("DELETE FROM rooms WHERE id = $1", roomId)
+	defer metrics.TimeQuery("DeleteRoom")()
+	return deleteRoom(DB, roomId)
+}
+
+func (tx *Tx) DeleteRoom(roomId string) error {
+	defer metrics.TimeQuery("DeleteRoom")()
+	return deleteRoom(tx.q, roomId)
+}
+
+func deleteRoom(q Querier, roomId string) error {
+	_, err := q.Exec("DELETE FROM rooms WHERE id = $1", roomId)
 	if err != nil {
 		return fmt.Errorf("failed to delete room: %v", err)
 	}
@@ -30,12 +57,28 @@ func DeleteRoom(roomId string) error {
 }
 
 func GetRoom(roomId string) (*models.Room, error) {
+	defer metrics.TimeQuery("GetRoom")()
+	return getRoom(DB, roomId, false)
+}
+
+// GetRoomForUpdate loads a room and locks it until the unit of work ends,
+// so that concurrent commands on the room take turns.
+func (tx *Tx) GetRoomForUpdate(roomId string) (*models.Room, error) {
+	defer metrics.TimeQuery("GetRoomForUpdate")()
+	return getRoom(tx.q, roomId, true)
+}
+
+func getRoom(q Querier, roomId string, forUpdate bool) (*models.Room, error) {
 	var room models.Room
+	var domains string
+	var archivedAt sql.NullTime
 
-	err := DB.QueryRow(
-		"SELECT id, name, created_at, scrum_master FROM rooms WHERE id = $1",
-		roomId,
-	).Scan(&room.Id, &room.Name, &room.CreatedAt, &room.ScrumMaster)
+	query := "SELECT " + roomColumns + " FROM rooms WHERE id = $1"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	err := q.QueryRow(query, roomId).Scan(&room.Id, &room.Name, &room.CreatedAt, &room.ScrumMaster, &room.OwnerAccountId, &room.AllowGuests, &domains,
+		&room.Persistent, &room.LastActiveAt, &archivedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -44,12 +87,14 @@ func GetRoom(roomId string) (*models.Room, error) {
 		return nil, fmt.Errorf("failed to get room: %v", err)
 	}
 
+	room.AllowedEmailDomains = splitList(domains)
+	room.ArchivedAt = timeOrNil(archivedAt)
 	room.Participants = make(map[string]*models.User)
 	room.Votes = make(map[string]string)
 	room.VotesRevealed = false
 
-	rows, err := DB.Query(`
-		SELECT u.id, u.name, u.created_at
+	rows, err := q.Query(`
+		SELECT u.id, u.name, u.created_at, COALESCE(u.profile_id, ''), u.role, u.avatar_seed
 		FROM users u
 		JOIN room_participants rp ON u.id = rp.user_id
 		WHERE rp.room_id = $1
@@ -62,7 +107,7 @@ func GetRoom(roomId string) (*models.Room, error) {
 	for rows.Next() {
 		user := new(models.User)
 		var userCreatedAt time.Time
-		err := rows.Scan(&user.Id, &user.Name, &userCreatedAt)
+		err := rows.Scan(&user.Id, &user.Name, &userCreatedAt, &user.ProfileId, &user.Role, &user.AvatarSeed)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %v", err)
 		}
@@ -70,7 +115,7 @@ func GetRoom(roomId string) (*models.Room, error) {
 		room.Participants[user.Id] = user
 	}
 
-	voteRows, err := DB.Query("SELECT user_id, vote FROM votes WHERE room_id = $1", roomId)
+	voteRows, err := q.Query("SELECT user_id, vote FROM votes WHERE room_id = $1", roomId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get room votes: %v", err)
 	}
@@ -89,28 +134,34 @@ func GetRoom(roomId string) (*models.Room, error) {
 }
 
 func AddParticipantToRoom(roomId string, user *models.User) error {
-	tx, err := DB.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
-	}
-	defer tx.Rollback()
+	defer metrics.TimeQuery("AddParticipantToRoom")()
+	return InTx(func(tx *Tx) error {
+		return addParticipantToRoom(tx.q, roomId, user)
+	})
+}
+
+func (tx *Tx) AddParticipantToRoom(roomId string, user *models.User) error {
+	defer metrics.TimeQuery("AddParticipantToRoom")()
+	return addParticipantToRoom(tx.q, roomId, user)
+}
 
+func addParticipantToRoom(q Querier, roomId string, user *models.User) error {
 	var count int
-	err = tx.QueryRow("SELECT COUNT(*) FROM users WHERE id = $1", user.Id).Scan(&count)
+	err := q.QueryRow("SELECT COUNT(*) FROM users WHERE id = $1", user.Id).Scan(&count)
 	if err != nil {
 		return fmt.Errorf("failed to check if user exists: %v", err)
 	}
 
 	if count == 0 {
-		_, err = tx.Exec(
-			"INSERT INTO users (id, name, created_at) VALUES ($1, $2, $3)",
-			user.Id, user.Name, user.CreatedAt,
+		_, err = q.Exec(
+			"INSERT INTO users (id, name, created_at, profile_id, role, avatar_seed) VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6)",
+			user.Id, user.Name, user.CreatedAt, user.ProfileId, user.Role, user.AvatarSeed,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to create user: %v", err)
 		}
 	} else {
-		_, err = tx.Exec(
+		_, err = q.Exec(
 			"UPDATE users SET name = $1 WHERE id = $2",
 			user.Name, user.Id,
 		)
@@ -119,7 +170,7 @@ func AddParticipantToRoom(roomId string, user *models.User) error {
 		}
 	}
 
-	_, err = tx.Exec(
+	_, err = q.Exec(
 		`INSERT INTO room_participants (room_id, user_id) 
 		 VALUES ($1, $2) 
 		 ON CONFLICT (room_id, user_id) DO NOTHING`,
@@ -128,17 +179,35 @@ func AddParticipantToRoom(roomId string, user *models.User) error {
 	if err != nil {
 		return fmt.Errorf("failed to add participant to room: %v", err)
 	}
+	return touchRoom(q, roomId)
+}
 
-	err = tx.Commit()
+// touchRoom records activity in roomId, which keeps it from being archived
+// as idle.
+func touchRoom(q Querier, roomId string) error {
+	_, err := q.Exec("UPDATE rooms SET last_active_at = NOW() WHERE id = $1", roomId)
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+		return fmt.Errorf("failed to update room activity: %v", err)
 	}
-
 	return nil
 }
 
+// RemoveParticipantFromRoom removes userId from the room together with
+// their vote.
 func RemoveParticipantFromRoom(roomId, userId string) error {
-	_, err := DB.Exec(
+	defer metrics.TimeQuery("RemoveParticipantFromRoom")()
+	return InTx(func(tx *Tx) error {
+		return removeParticipantFromRoom(tx.q, roomId, userId)
+	})
+}
+
+func (tx *Tx) RemoveParticipantFromRoom(roomId, userId string) error {
+	defer metrics.TimeQuery("RemoveParticipantFromRoom")()
+	return removeParticipantFromRoom(tx.q, roomId, userId)
+}
+
+func removeParticipantFromRoom(q Querier, roomId, userId string) error {
+	_, err := q.Exec(
 		"DELETE FROM room_participants WHERE room_id = $1 AND user_id = $2",
 		roomId, userId,
 	)
@@ -146,7 +215,7 @@ func RemoveParticipantFromRoom(roomId, userId string) error {
 		return fmt.Errorf("failed to remove participant from room: %v", err)
 	}
 
-	_, err = DB.Exec(
+	_, err = q.Exec(
 		"DELETE FROM votes WHERE room_id = $1 AND user_id = $2",
 		roomId, userId,
 	)
@@ -157,8 +226,30 @@ func RemoveParticipantFromRoom(roomId, userId string) error {
 	return nil
 }
 
+func (tx *Tx) UpdateRoomSettings(roomId string, allowGuests bool, allowedEmailDomains []string, persistent bool) error {
+	defer metrics.TimeQuery("UpdateRoomSettings")()
+	_, err := tx.q.Exec(
+		"UPDATE rooms SET allow_guests = $1, allowed_email_domains = $2, persistent = $3 WHERE id = $4",
+		allowGuests, joinList(allowedEmailDomains), persistent, roomId,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update room: %v", err)
+	}
+	return nil
+}
+
 func UpdateScrumMaster(roomId, newScrumMasterID string) error {
-	_, err := DB.Exec(
+	defer metrics.TimeQuery("UpdateScrumMaster")()
+	return updateScrumMaster(DB, roomId, newScrumMasterID)
+}
+
+func (tx *Tx) UpdateScrumMaster(roomId, newScrumMasterID string) error {
+	defer metrics.TimeQuery("UpdateScrumMaster")()
+	return updateScrumMaster(tx.q, roomId, newScrumMasterID)
+}
+
+func updateScrumMaster(q Querier, roomId, newScrumMasterID string) error {
+	_, err := q.Exec(
 		"UPDATE rooms SET scrum_master = $1 WHERE id = $2",
 		newScrumMasterID, roomId,
 	)
@@ -168,8 +259,30 @@ func UpdateScrumMaster(roomId, newScrumMasterID string) error {
 	return nil
 }
 
+// GetAllRooms returns every room with its participants and votes. They are
+// loaded with one query each, whatever the number of rooms.
 func GetAllRooms() ([]*models.Room, error) {
-	rows, err := DB.Query("SELECT id, name, created_at, scrum_master FROM rooms")
+	defer metrics.TimeQuery("GetAllRooms")()
+	rooms, err := queryRooms()
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[string]*models.Room, len(rooms))
+	for _, room := range rooms {
+		byId[room.Id] = room
+	}
+	if err := loadParticipants(byId); err != nil {
+		return nil, err
+	}
+	if err := loadVotes(byId); err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+func queryRooms() ([]*models.Room, error) {
+	rows, err := DB.Query("SELECT " + roomColumns + " FROM rooms")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get rooms: %v", err)
 	}
@@ -177,70 +290,96 @@ func GetAllRooms() ([]*models.Room, error) {
 
 	var rooms []*models.Room
 	for rows.Next() {
-		var room models.Room
-		var createdAt time.Time
-		err := rows.Scan(&room.Id, &room.Name, &createdAt, &room.ScrumMaster)
+		room := new(models.Room)
+		var domains string
+		var archivedAt sql.NullTime
+		err := rows.Scan(&room.Id, &room.Name, &room.CreatedAt, &room.ScrumMaster, &room.OwnerAccountId, &room.AllowGuests, &domains,
+			&room.Persistent, &room.LastActiveAt, &archivedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan room: %v", err)
 		}
-		room.CreatedAt = createdAt
+		room.AllowedEmailDomains = splitList(domains)
+		room.ArchivedAt = timeOrNil(archivedAt)
 		room.Participants = make(map[string]*models.User)
 		room.Votes = make(map[string]string)
-		room.VotesRevealed = false
-
-		participantRows, err := DB.Query(`
-			SELECT u.id, u.name, u.created_at
-			FROM users u
-			JOIN room_participants rp ON u.id = rp.user_id
-			WHERE rp.room_id = $1
-		`, room.Id)
+		rooms = append(rooms, room)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get rooms: %v", err)
+	}
+	return rooms, nil
+}
+
+// loadParticipants adds the participants of every room to rooms, which is
+// keyed by room id.
+func loadParticipants(rooms map[string]*models.Room) error {
+	rows, err := DB.Query(`
+		SELECT rp.room_id, u.id, u.name, u.created_at, COALESCE(u.profile_id, ''), u.role, u.avatar_seed
+		FROM users u
+		JOIN room_participants rp ON u.id = rp.user_id
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to get room participants: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var roomId string
+		user := new(models.User)
+		err := rows.Scan(&roomId, &user.Id, &user.Name, &user.CreatedAt, &user.ProfileId, &user.Role, &user.AvatarSeed)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get room participants: %v", err)
+			return fmt.Errorf("failed to scan user: %v", err)
 		}
-		defer participantRows.Close()
-
-		for participantRows.Next() {
-			user := new(models.User)
-			var userCreatedAt time.Time
-			err := participantRows.Scan(&user.Id, &user.Name, &userCreatedAt)
-			if err != nil {
-				return nil, fmt.Errorf("failed to scan user: %v", err)
-			}
-			user.CreatedAt = userCreatedAt
+		// Rooms created since the rooms were listed are left out.
+		if room, ok := rooms[roomId]; ok {
 			room.Participants[user.Id] = user
 		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get room participants: %v", err)
+	}
+	return nil
+}
 
-		voteRows, err := DB.Query("SELECT user_id, vote FROM votes WHERE room_id = $1", room.Id)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get room votes: %v", err)
+// loadVotes adds the votes cast in every room to rooms, which is keyed by
+// room id.
+func loadVotes(rooms map[string]*models.Room) error {
+	rows, err := DB.Query("SELECT room_id, user_id, vote FROM votes")
+	if err != nil {
+		return fmt.Errorf("failed to get room votes: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var roomId, userId, vote string
+		if err := rows.Scan(&roomId, &userId, &vote); err != nil {
+			return fmt.Errorf("failed to scan vote: %v", err)
 		}
-		defer voteRows.Close()
-
-		for voteRows.Next() {
-			var userId, vote string
-			err := voteRows.Scan(&userId, &vote)
-			if err != nil {
-				return nil, fmt.Errorf("failed to scan vote: %v", err)
-			}
+		if room, ok := rooms[roomId]; ok {
 			room.Votes[userId] = vote
 		}
-
-		rooms = append(rooms, &room)
 	}
-
-	return rooms, nil
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get room votes: %v", err)
+	}
+	return nil
 }
 
 func GetRoomByUserId(userId string) (*models.Room, error) {
+	defer metrics.TimeQuery("GetRoomByUserId")()
 	var room models.Room
+	var domains string
+	var archivedAt sql.NullTime
 
 	err := DB.QueryRow(
-		`SELECT r.id, r.name, r.created_at, r.scrum_master
+		`SELECT r.id, r.name, r.created_at, r.scrum_master, COALESCE(r.owner_account_id, ''), r.allow_guests, r.allowed_email_domains,
+				r.persistent, r.last_active_at, r.archived_at
 				FROM rooms r 
 				JOIN room_participants rp ON r.id = rp.room_id 
 				WHERE rp.user_id = $1`,
 		userId,
-	).Scan(&room.Id, &room.Name, &room.CreatedAt, &room.ScrumMaster)
+	).Scan(&room.Id, &room.Name, &room.CreatedAt, &room.ScrumMaster, &room.OwnerAccountId, &room.AllowGuests, &domains,
+		&room.Persistent, &room.LastActiveAt, &archivedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -249,12 +388,14 @@ func GetRoomByUserId(userId string) (*models.Room, error) {
 		return nil, fmt.Errorf("failed to get room: %v", err)
 	}
 
+	room.AllowedEmailDomains = splitList(domains)
+	room.ArchivedAt = timeOrNil(archivedAt)
 	room.Participants = make(map[string]*models.User)
 	room.Votes = make(map[string]string)
 	room.VotesRevealed = false
 
 	rows, err := DB.Query(`
-		SELECT u.id, u.name, u.created_at
+		SELECT u.id, u.name, u.created_at, COALESCE(u.profile_id, ''), u.role, u.avatar_seed
 		FROM users u
 		JOIN room_participants rp ON u.id = rp.user_id
 		WHERE rp.room_id = $1
@@ -267,7 +408,7 @@ func GetRoomByUserId(userId string) (*models.Room, error) {
 	for rows.Next() {
 		user := new(models.User)
 		var userCreatedAt time.Time
-		err := rows.Scan(&user.Id, &user.Name, &userCreatedAt)
+		err := rows.Scan(&user.Id, &user.Name, &userCreatedAt, &user.ProfileId, &user.Role, &user.AvatarSeed)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %v", err)
 		}
@@ -292,3 +433,113 @@ func GetRoomByUserId(userId string) (*models.Room, error) {
 
 	return &room, nil
 }
+
+// ArchiveRoom archives room, which must have been loaded with
+// GetRoomForUpdate: its results are stored as they are now and its
+// participants are deleted along with their votes and memberships.
+func (tx *Tx) ArchiveRoom(room *models.Room) error {
+	defer metrics.TimeQuery("ArchiveRoom")()
+	now := time.Now()
+	room.ArchivedAt = &now
+	archive, err := json.Marshal(room.ToExport())
+	if err != nil {
+		return fmt.Errorf("failed to archive room: %v", err)
+	}
+
+	_, err = tx.q.Exec(
+		"UPDATE rooms SET archived_at = $1, archive = $2 WHERE id = $3",
+		now, string(archive), room.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to archive room: %v", err)
+	}
+
+	_, err = tx.q.Exec(
+		"DELETE FROM users WHERE id IN (SELECT user_id FROM room_participants WHERE room_id = $1)",
+		room.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete participants: %v", err)
+	}
+	return nil
+}
+
+// GetRoomArchive returns the results stored when roomId was archived.
+func GetRoomArchive(roomId string) (*models.RoomExport, error) {
+	defer metrics.TimeQuery("GetRoomArchive")()
+	var archive string
+	err := DB.QueryRow("SELECT archive FROM rooms WHERE id = $1 AND archive IS NOT NULL", roomId).Scan(&archive)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("room archive not found")
+		}
+		return nil, fmt.Errorf("failed to get room archive: %v", err)
+	}
+
+	var export models.RoomExport
+	if err := json.Unmarshal([]byte(archive), &export); err != nil {
+		return nil, fmt.Errorf("failed to read room archive: %v", err)
+	}
+	return &export, nil
+}
+
+// GetIdleRoomIds returns the rooms that are not archived and have been
+// inactive since before idleBefore, or persistentIdleBefore for persistent
+// rooms. A zero time leaves the corresponding rooms out.
+func GetIdleRoomIds(idleBefore, persistentIdleBefore time.Time) ([]string, error) {
+	defer metrics.TimeQuery("GetIdleRoomIds")()
+	rows, err := DB.Query(`
+		SELECT id FROM rooms
+		WHERE archived_at IS NULL
+			AND (NOT persistent AND last_active_at < $1 OR persistent AND last_active_at < $2)
+	`, nullTime(idleBefore), nullTime(persistentIdleBefore))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idle rooms: %v", err)
+	}
+	defer rows.Close()
+
+	var roomIds []string
+	for rows.Next() {
+		var roomId string
+		if err := rows.Scan(&roomId); err != nil {
+			return nil, fmt.Errorf("failed to scan room: %v", err)
+		}
+		roomIds = append(roomIds, roomId)
+	}
+	return roomIds, rows.Err()
+}
+
+// PurgeArchivedRooms deletes the rooms archived before archivedBefore.
+func PurgeArchivedRooms(archivedBefore time.Time) (int64, error) {
+	defer metrics.TimeQuery("PurgeArchivedRooms")()
+	result, err := DB.Exec("DELETE FROM rooms WHERE archived_at < $1", archivedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge archived rooms: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+func timeOrNil(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// nullTime stores the zero time as NULL, which no comparison matches.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// joinList stores a short list of values without commas, such as email
+// domains or token scopes, in a single TEXT column.
+func joinList(values []string) string {
+	return strings.Join(values, ",")
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
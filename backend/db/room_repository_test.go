@@ -0,0 +1,55 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetAllRoomsKeepsParticipantsAndVotesWithTheirRoom(t *testing.T) {
+	useTestDB(t)
+	team := createTestRoom(t, false, "alice", "bob")
+	other := createTestRoom(t, true, "carol")
+	empty := createTestRoom(t, false, "dave")
+	if err := InTx(func(tx *Tx) error { return tx.RemoveParticipantFromRoom(empty.Id, "dave") }); err != nil {
+		t.Fatalf("failed to empty room: %v", err)
+	}
+	for _, vote := range []struct{ roomId, userId, vote string }{
+		{team.Id, "alice", "3"},
+		{team.Id, "bob", "5"},
+		{other.Id, "carol", "8"},
+	} {
+		if err := AddVote(vote.roomId, vote.userId, vote.vote); err != nil {
+			t.Fatalf("AddVote failed: %v", err)
+		}
+	}
+
+	rooms, err := GetAllRooms()
+	if err != nil {
+		t.Fatalf("GetAllRooms failed: %v", err)
+	}
+	if len(rooms) != 3 {
+		t.Fatalf("GetAllRooms returned %d rooms, want 3", len(rooms))
+	}
+
+	want := map[string]map[string]string{
+		team.Id:  {"alice": "3", "bob": "5"},
+		other.Id: {"carol": "8"},
+		empty.Id: {},
+	}
+	for _, room := range rooms {
+		if !reflect.DeepEqual(room.Votes, want[room.Id]) {
+			t.Errorf("room %s has votes %v, want %v", room.Id, room.Votes, want[room.Id])
+		}
+		if len(room.Participants) != len(want[room.Id]) {
+			t.Errorf("room %s has %d participants, want %d", room.Id, len(room.Participants), len(want[room.Id]))
+		}
+		for userId := range want[room.Id] {
+			if user := room.Participants[userId]; user == nil || user.Name != userId {
+				t.Errorf("room %s is missing participant %s", room.Id, userId)
+			}
+		}
+		if room.Persistent != (room.Id == other.Id) {
+			t.Errorf("room %s persistent = %v", room.Id, room.Persistent)
+		}
+	}
+}
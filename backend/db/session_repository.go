@@ -4,92 +4,397 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/lib/pq"
+	"github.com/scrum-poker/backend/metrics"
 	"github.com/scrum-poker/backend/models"
+	"time"
 )
 
+const selectMembership = "SELECT session_id, user_id, room_id, created_at, expires_at FROM session_memberships"
+
+// CreateSession stores session's membership in its room, creating the session
+// itself if this is its first room.
 func CreateSession(session *models.Session) error {
-	_, err := DB.Exec(
-		"INSERT INTO sessions (id, user_id, room_id, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)",
-		session.Id, session.UserId, session.RoomId, session.CreatedAt, session.ExpiresAt,
+	defer metrics.TimeQuery("CreateSession")()
+	return InTx(func(tx *Tx) error {
+		return createSession(tx.q, session)
+	})
+}
+
+func (tx *Tx) CreateSession(session *models.Session) error {
+	defer metrics.TimeQuery("CreateSession")()
+	return createSession(tx.q, session)
+}
+
+func createSession(q Querier, session *models.Session) error {
+	_, err := q.Exec(
+		`INSERT INTO sessions (id, created_at, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET expires_at = GREATEST(sessions.expires_at, EXCLUDED.expires_at)`,
+		session.Id, session.CreatedAt, session.ExpiresAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create session: %v", err)
 	}
+
+	_, err = q.Exec(
+		`INSERT INTO session_memberships (session_id, room_id, user_id, created_at, expires_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (session_id, room_id) DO UPDATE SET user_id = EXCLUDED.user_id, expires_at = EXCLUDED.expires_at`,
+		session.Id, session.RoomId, session.UserId, session.CreatedAt, session.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create session membership: %v", err)
+	}
 	return nil
 }
 
-func GetSession(sessionID string) (*models.Session, error) {
-	var session models.Session
-	err := DB.QueryRow(
-		"SELECT id, user_id, room_id, created_at, expires_at FROM sessions WHERE id = $1",
-		sessionID,
-	).Scan(&session.Id, &session.UserId, &session.RoomId, &session.CreatedAt, &session.ExpiresAt)
+func SessionExists(sessionID string) (bool, error) {
+	defer metrics.TimeQuery("SessionExists")()
+	return sessionExists(DB, sessionID)
+}
+
+func (tx *Tx) SessionExists(sessionID string) (bool, error) {
+	defer metrics.TimeQuery("SessionExists")()
+	return sessionExists(tx.q, sessionID)
+}
 
+func sessionExists(q Querier, sessionID string) (bool, error) {
+	var exists bool
+	err := q.QueryRow("SELECT EXISTS (SELECT 1 FROM sessions WHERE id = $1)", sessionID).Scan(&exists)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("session not found")
-		}
-		return nil, fmt.Errorf("failed to get session: %v", err)
+		return false, fmt.Errorf("failed to get session: %v", err)
 	}
+	return exists, nil
+}
 
-	return &session, nil
+// GetSession returns the membership of session sessionID in roomId.
+func GetSession(sessionID, roomId string) (*models.Session, error) {
+	defer metrics.TimeQuery("GetSession")()
+	return scanSession(DB.QueryRow(selectMembership+" WHERE session_id = $1 AND room_id = $2", sessionID, roomId))
+}
+
+func GetSessionMemberships(sessionID string) ([]*models.Session, error) {
+	defer metrics.TimeQuery("GetSessionMemberships")()
+	return querySessions(selectMembership+" WHERE session_id = $1 ORDER BY created_at", sessionID)
 }
 
+// UpdateSession moves the expiry of session's membership, extending the
+// session itself if the membership now outlasts it.
 func UpdateSession(session *models.Session) error {
+	defer metrics.TimeQuery("UpdateSession")()
+	return InTx(func(tx *Tx) error {
+		_, err := tx.q.Exec(
+			"UPDATE session_memberships SET expires_at = $1 WHERE session_id = $2 AND room_id = $3",
+			session.ExpiresAt, session.Id, session.RoomId,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update session: %v", err)
+		}
+
+		_, err = tx.q.Exec(
+			"UPDATE sessions SET expires_at = GREATEST(expires_at, $1) WHERE id = $2",
+			session.ExpiresAt, session.Id,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update session: %v", err)
+		}
+		return nil
+	})
+}
+
+// DeleteSession removes a session together with all of its memberships.
+func DeleteSession(sessionID string) error {
+	defer metrics.TimeQuery("DeleteSession")()
+	_, err := DB.Exec("DELETE FROM sessions WHERE id = $1", sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %v", err)
+	}
+	return nil
+}
+
+// DeleteSessionMembership removes a session from a room, and the session
+// itself when that was its last room and no account is signed in to it.
+func DeleteSessionMembership(sessionID, roomId string) error {
+	defer metrics.TimeQuery("DeleteSessionMembership")()
+	return InTx(func(tx *Tx) error {
+		_, err := tx.q.Exec("DELETE FROM session_memberships WHERE session_id = $1 AND room_id = $2", sessionID, roomId)
+		if err != nil {
+			return fmt.Errorf("failed to delete session membership: %v", err)
+		}
+
+		_, err = tx.q.Exec(`
+			DELETE FROM sessions
+			WHERE id = $1 AND account_id IS NULL
+				AND NOT EXISTS (SELECT 1 FROM session_memberships WHERE session_id = $1)
+		`, sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to delete session: %v", err)
+		}
+		return nil
+	})
+}
+
+// DeleteEmptySessions removes sessions that no longer belong to any room,
+// unless an account is signed in to them and they have not yet expired.
+func DeleteEmptySessions() (int64, error) {
+	defer metrics.TimeQuery("DeleteEmptySessions")()
+	result, err := DB.Exec(`
+		DELETE FROM sessions
+		WHERE (account_id IS NULL OR expires_at < NOW())
+			AND NOT EXISTS (SELECT 1 FROM session_memberships WHERE session_memberships.session_id = sessions.id)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete empty sessions: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// SignInSession signs accountId in to session sessionID, creating the
+// session if it does not exist yet.
+func SignInSession(sessionID, accountId string, createdAt, expiresAt time.Time) error {
+	defer metrics.TimeQuery("SignInSession")()
 	_, err := DB.Exec(
-		"UPDATE sessions SET expires_at = $1 WHERE id = $2",
-		session.ExpiresAt, session.Id,
+		`INSERT INTO sessions (id, created_at, expires_at, account_id) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET account_id = EXCLUDED.account_id,
+			expires_at = GREATEST(sessions.expires_at, EXCLUDED.expires_at)`,
+		sessionID, createdAt, expiresAt, accountId,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update session: %v", err)
+		return fmt.Errorf("failed to sign in session: %v", err)
 	}
 	return nil
 }
 
-func DeleteSession(sessionID string) error {
-	_, err := DB.Exec("DELETE FROM sessions WHERE id = $1", sessionID)
+func SignOutSession(sessionID string) error {
+	defer metrics.TimeQuery("SignOutSession")()
+	_, err := DB.Exec("UPDATE sessions SET account_id = NULL WHERE id = $1", sessionID)
 	if err != nil {
-		return fmt.Errorf("failed to delete session: %v", err)
+		return fmt.Errorf("failed to sign out session: %v", err)
+	}
+	return nil
+}
+
+// SignOutAccountSessions signs accountId out of every session but
+// exceptSessionID. Sessions of API tokens are kept; tokens are revoked
+// explicitly.
+func SignOutAccountSessions(accountId, exceptSessionID string) error {
+	defer metrics.TimeQuery("SignOutAccountSessions")()
+	_, err := DB.Exec(`
+		UPDATE sessions SET account_id = NULL
+		WHERE account_id = $1 AND id <> $2
+			AND NOT EXISTS (SELECT 1 FROM api_tokens WHERE api_tokens.session_id = sessions.id)
+	`, accountId, exceptSessionID)
+	if err != nil {
+		return fmt.Errorf("failed to sign out sessions: %v", err)
 	}
 	return nil
 }
 
+// GetSessionAccount returns the account signed in to session sessionID, or
+// an empty string, along with the session's expiry.
+func GetSessionAccount(sessionID string) (string, time.Time, error) {
+	defer metrics.TimeQuery("GetSessionAccount")()
+	var accountId string
+	var expiresAt time.Time
+	err := DB.QueryRow(
+		"SELECT COALESCE(account_id, ''), expires_at FROM sessions WHERE id = $1",
+		sessionID,
+	).Scan(&accountId, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", time.Time{}, fmt.Errorf("session not found")
+		}
+		return "", time.Time{}, fmt.Errorf("failed to get session: %v", err)
+	}
+	return accountId, expiresAt, nil
+}
+
 func GetSessionsByRoomID(roomId string) ([]*models.Session, error) {
-	rows, err := DB.Query(
-		"SELECT id, user_id, room_id, created_at, expires_at FROM sessions WHERE room_id = $1",
-		roomId,
+	defer metrics.TimeQuery("GetSessionsByRoomID")()
+	return querySessions(selectMembership+" WHERE room_id = $1", roomId)
+}
+
+// GetExpiredMemberships returns the memberships that expired before cutoff,
+// ordered by room.
+func GetExpiredMemberships(cutoff time.Time) ([]*models.Session, error) {
+	defer metrics.TimeQuery("GetExpiredMemberships")()
+	return querySessions(selectMembership+" WHERE expires_at < $1 ORDER BY room_id, user_id", cutoff)
+}
+
+// RefreshMemberships extends memberships, and the sessions they belong to,
+// until expiresAt, and marks their rooms active.
+func RefreshMemberships(memberships []*models.Session, expiresAt time.Time) error {
+	defer metrics.TimeQuery("RefreshMemberships")()
+	sessionIds := make([]string, len(memberships))
+	roomIds := make([]string, len(memberships))
+	for i, membership := range memberships {
+		sessionIds[i] = membership.Id
+		roomIds[i] = membership.RoomId
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to refresh sessions: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE session_memberships SET expires_at = $1
+		WHERE (session_id, room_id) IN (SELECT * FROM unnest($2::varchar[], $3::varchar[]))
+	`, expiresAt, pq.Array(sessionIds), pq.Array(roomIds))
+	if err != nil {
+		return fmt.Errorf("failed to refresh sessions: %v", err)
+	}
+
+	_, err = tx.Exec(
+		"UPDATE sessions SET expires_at = GREATEST(expires_at, $1) WHERE id = ANY($2)",
+		expiresAt, pq.Array(sessionIds),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get sessions for room: %v", err)
+		return fmt.Errorf("failed to refresh sessions: %v", err)
 	}
-	defer rows.Close()
 
-	var sessions []*models.Session
-	for rows.Next() {
-		session := new(models.Session)
-		err := rows.Scan(&session.Id, &session.UserId, &session.RoomId, &session.CreatedAt, &session.ExpiresAt)
+	// Someone connected counts as activity in the room.
+	_, err = tx.Exec("UPDATE rooms SET last_active_at = NOW() WHERE id = ANY($1)", pq.Array(roomIds))
+	if err != nil {
+		return fmt.Errorf("failed to update room activity: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to refresh sessions: %v", err)
+	}
+	return nil
+}
+
+// RemoveExpiredParticipants removes, in one transaction, the users among
+// userIds whose memberships in roomId all expired before cutoff, together
+// with their votes and memberships. A room left empty is archived unless it
+// is persistent, with its results as they were before the last participants
+// were removed; when the Scrum Master was removed, a random remaining
+// participant takes over.
+// Users that were refreshed in the meantime are kept.
+func RemoveExpiredParticipants(roomId string, userIds []string, cutoff time.Time) (*models.RoomCleanup, error) {
+	defer metrics.TimeQuery("RemoveExpiredParticipants")()
+	cleanup := &models.RoomCleanup{}
+	err := InTx(func(tx *Tx) error {
+		var persistent bool
+		var participants int
+		err := tx.q.QueryRow(
+			"SELECT scrum_master, persistent, (SELECT COUNT(*) FROM room_participants WHERE room_id = $1) FROM rooms WHERE id = $1 FOR UPDATE",
+			roomId,
+		).Scan(&cleanup.PreviousScrumMaster, &persistent, &participants)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan session: %v", err)
+			if errors.Is(err, sql.ErrNoRows) {
+				// The room is gone, and its memberships with it.
+				return nil
+			}
+			return fmt.Errorf("failed to get room: %v", err)
 		}
-		sessions = append(sessions, session)
+		cleanup.ScrumMaster = cleanup.PreviousScrumMaster
+
+		// The results are taken before anyone is removed, in case the room
+		// ends up empty.
+		var room *models.Room
+		if !persistent && len(userIds) >= participants {
+			room, err = getRoom(tx.q, roomId, false)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Participants, votes and memberships go with the user.
+		rows, err := tx.q.Query(`
+			DELETE FROM users
+			WHERE id = ANY($2)
+				AND EXISTS (SELECT 1 FROM session_memberships m WHERE m.user_id = users.id AND m.room_id = $1)
+				AND NOT EXISTS (SELECT 1 FROM session_memberships m WHERE m.user_id = users.id AND m.expires_at >= $3)
+			RETURNING id
+		`, roomId, pq.Array(userIds), cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to remove expired participants: %v", err)
+		}
+		for rows.Next() {
+			var userId string
+			if err := rows.Scan(&userId); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan user: %v", err)
+			}
+			cleanup.RemovedUserIds = append(cleanup.RemovedUserIds, userId)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to remove expired participants: %v", err)
+		}
+		if len(cleanup.RemovedUserIds) == 0 {
+			return nil
+		}
+
+		if room != nil && len(cleanup.RemovedUserIds) == participants {
+			cleanup.Archived = true
+			return tx.ArchiveRoom(room)
+		}
+
+		if containsString(cleanup.RemovedUserIds, cleanup.PreviousScrumMaster) {
+			err = tx.q.QueryRow(`
+				UPDATE rooms SET scrum_master = COALESCE((SELECT user_id FROM room_participants WHERE room_id = $1 ORDER BY random() LIMIT 1), scrum_master)
+				WHERE id = $1
+				RETURNING scrum_master
+			`, roomId).Scan(&cleanup.ScrumMaster)
+			if err != nil {
+				return fmt.Errorf("failed to update Scrum Master: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return cleanup, nil
+}
 
-	return sessions, nil
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
-func GetSessionByUserID(userId string) (*models.Session, error) {
-	var session models.Session
-	err := DB.QueryRow(
-		"SELECT id, user_id, room_id, created_at, expires_at FROM sessions WHERE user_id = $1",
-		userId,
-	).Scan(&session.Id, &session.UserId, &session.RoomId, &session.CreatedAt, &session.ExpiresAt)
+// GetSessionByUserID returns the membership userId holds in roomId, of which
+// there is at most one.
+func GetSessionByUserID(userId, roomId string) (*models.Session, error) {
+	defer metrics.TimeQuery("GetSessionByUserID")()
+	return scanSession(DB.QueryRow(selectMembership+" WHERE user_id = $1 AND room_id = $2", userId, roomId))
+}
 
+func scanSession(row *sql.Row) (*models.Session, error) {
+	var session models.Session
+	err := row.Scan(&session.Id, &session.UserId, &session.RoomId, &session.CreatedAt, &session.ExpiresAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("session not found")
 		}
 		return nil, fmt.Errorf("failed to get session: %v", err)
 	}
-
 	return &session, nil
 }
+
+func querySessions(query string, args ...interface{}) ([]*models.Session, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %v", err)
+	}
+	defer rows.Close()
+
+	var sessions []*models.Session
+	for rows.Next() {
+		session := new(models.Session)
+		err := rows.Scan(&session.Id, &session.UserId, &session.RoomId, &session.CreatedAt, &session.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
@@ -0,0 +1,348 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+func TestSessionHoldsMembershipsInSeveralRooms(t *testing.T) {
+	useTestDB(t)
+	team := createTestRoom(t, false, "alice")
+	other := createTestRoom(t, false, "alice-other")
+
+	for _, membership := range []*models.Session{
+		models.NewSession("browser", "alice", team.Id, time.Hour),
+		models.NewSession("browser", "alice-other", other.Id, time.Hour),
+	} {
+		if err := CreateSession(membership); err != nil {
+			t.Fatalf("CreateSession(%s) failed: %v", membership.RoomId, err)
+		}
+	}
+
+	memberships, err := GetSessionMemberships("browser")
+	if err != nil {
+		t.Fatalf("GetSessionMemberships failed: %v", err)
+	}
+	if len(memberships) != 2 {
+		t.Fatalf("session has %d memberships, want 2", len(memberships))
+	}
+
+	membership, err := GetSession("browser", other.Id)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if membership.UserId != "alice-other" {
+		t.Errorf("membership in the other room is alice's %q, want alice-other", membership.UserId)
+	}
+
+	if _, err := GetSessionByUserID("alice", other.Id); err == nil {
+		t.Error("GetSessionByUserID found alice in a room she has not joined")
+	}
+}
+
+func TestJoiningTwiceFromOneSessionKeepsOneMembership(t *testing.T) {
+	useTestDB(t)
+	room := createTestRoom(t, false, "alice")
+
+	first := models.NewSession("browser", "alice", room.Id, time.Minute)
+	if err := CreateSession(first); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	again := models.NewSession("browser", "alice", room.Id, time.Hour)
+	if err := CreateSession(again); err != nil {
+		t.Fatalf("CreateSession for the same room again failed: %v", err)
+	}
+
+	memberships, err := GetSessionsByRoomID(room.Id)
+	if err != nil {
+		t.Fatalf("GetSessionsByRoomID failed: %v", err)
+	}
+	if len(memberships) != 1 {
+		t.Fatalf("room has %d memberships, want 1", len(memberships))
+	}
+	if !memberships[0].ExpiresAt.After(first.ExpiresAt) {
+		t.Errorf("membership expires at %v, want it extended past %v", memberships[0].ExpiresAt, first.ExpiresAt)
+	}
+}
+
+func TestSecondSessionCannotJoinAsTheSameUser(t *testing.T) {
+	useTestDB(t)
+	room := createTestRoom(t, false, "alice")
+
+	if err := CreateSession(models.NewSession("laptop", "alice", room.Id, time.Hour)); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if err := CreateSession(models.NewSession("phone", "alice", room.Id, time.Hour)); err == nil {
+		t.Fatal("a second session joined the room as the same user")
+	}
+
+	membership, err := GetSessionByUserID("alice", room.Id)
+	if err != nil {
+		t.Fatalf("GetSessionByUserID failed: %v", err)
+	}
+	if membership.Id != "laptop" {
+		t.Errorf("alice's membership belongs to session %q, want laptop", membership.Id)
+	}
+	if exists, err := SessionExists("phone"); err != nil || exists {
+		t.Errorf("SessionExists(phone) = %v, %v; want the rejected session rolled back", exists, err)
+	}
+}
+
+func TestMigrationRemovesDuplicateMemberships(t *testing.T) {
+	useTestDB(t)
+	room := createTestRoom(t, false, "alice")
+
+	// Memberships as an earlier version could leave them, before the index.
+	if _, err := DB.Exec("DROP INDEX session_memberships_user_id_room_id_idx"); err != nil {
+		t.Fatalf("failed to drop index: %v", err)
+	}
+	now := time.Now()
+	for session, expiresAt := range map[string]time.Time{
+		"laptop": now.Add(time.Hour),
+		"phone":  now.Add(2 * time.Hour),
+		"tablet": now.Add(time.Minute),
+	} {
+		_, err := DB.Exec("INSERT INTO sessions (id, expires_at) VALUES ($1, $2)", session, expiresAt)
+		if err != nil {
+			t.Fatalf("failed to insert session: %v", err)
+		}
+		_, err = DB.Exec(
+			"INSERT INTO session_memberships (session_id, room_id, user_id, expires_at) VALUES ($1, $2, $3, $4)",
+			session, room.Id, "alice", expiresAt,
+		)
+		if err != nil {
+			t.Fatalf("failed to insert membership: %v", err)
+		}
+	}
+
+	if err := Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	memberships, err := GetSessionsByRoomID(room.Id)
+	if err != nil {
+		t.Fatalf("GetSessionsByRoomID failed: %v", err)
+	}
+	if len(memberships) != 1 || memberships[0].Id != "phone" {
+		t.Fatalf("memberships after migration = %+v, want only the longest-lasting one of phone", memberships)
+	}
+	if err := CreateSession(models.NewSession("laptop", "alice", room.Id, time.Hour)); err == nil {
+		t.Error("the unique index was not recreated")
+	}
+}
+
+// createTestMembership stores a membership of userId in roomId, in a session
+// of its own, expiring at expiresAt.
+func createTestMembership(t *testing.T, roomId, userId string, expiresAt time.Time) *models.Session {
+	t.Helper()
+	membership := &models.Session{
+		Id:        "session-" + userId,
+		UserId:    userId,
+		RoomId:    roomId,
+		CreatedAt: expiresAt.Add(-time.Hour),
+		ExpiresAt: expiresAt,
+	}
+	if err := CreateSession(membership); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	return membership
+}
+
+func TestGetExpiredMembershipsGroupsThemByRoom(t *testing.T) {
+	useTestDB(t)
+	now := time.Now()
+	first := createTestRoom(t, false, "alice", "bob")
+	second := createTestRoom(t, false, "carol", "dave")
+	createTestMembership(t, first.Id, "bob", now.Add(-time.Minute))
+	createTestMembership(t, second.Id, "carol", now.Add(-time.Hour))
+	createTestMembership(t, first.Id, "alice", now.Add(-time.Hour))
+	createTestMembership(t, second.Id, "dave", now.Add(time.Hour))
+
+	expired, err := GetExpiredMemberships(now)
+	if err != nil {
+		t.Fatalf("GetExpiredMemberships failed: %v", err)
+	}
+
+	var got []string
+	for _, membership := range expired {
+		got = append(got, membership.RoomId+"/"+membership.UserId)
+	}
+	want := []string{first.Id + "/alice", first.Id + "/bob", second.Id + "/carol"}
+	if first.Id > second.Id {
+		want = []string{second.Id + "/carol", first.Id + "/alice", first.Id + "/bob"}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expired memberships = %v, want %v", got, want)
+	}
+}
+
+func TestRefreshMembershipsExtendsTheBatchOnly(t *testing.T) {
+	useTestDB(t)
+	now := time.Now()
+	first := createTestRoom(t, false, "alice", "bob")
+	second := createTestRoom(t, false, "carol")
+	alice := createTestMembership(t, first.Id, "alice", now.Add(-time.Minute))
+	createTestMembership(t, first.Id, "bob", now.Add(-time.Minute))
+	carol := createTestMembership(t, second.Id, "carol", now.Add(-time.Minute))
+	if _, err := DB.Exec("UPDATE rooms SET last_active_at = $1", now.Add(-time.Hour)); err != nil {
+		t.Fatalf("failed to age rooms: %v", err)
+	}
+
+	until := now.Add(20 * time.Minute)
+	if err := RefreshMemberships([]*models.Session{alice, carol}, until); err != nil {
+		t.Fatalf("RefreshMemberships failed: %v", err)
+	}
+
+	expired, err := GetExpiredMemberships(now)
+	if err != nil {
+		t.Fatalf("GetExpiredMemberships failed: %v", err)
+	}
+	if len(expired) != 1 || expired[0].UserId != "bob" {
+		t.Errorf("expired memberships after refresh = %+v, want only bob's", expired)
+	}
+
+	for _, membership := range []*models.Session{alice, carol} {
+		_, expiresAt, err := GetSessionAccount(membership.Id)
+		if err != nil || expiresAt.Before(until.Add(-time.Second)) {
+			t.Errorf("session %s expires at %v, %v; want it extended with its membership", membership.Id, expiresAt, err)
+		}
+
+		room, err := GetRoom(membership.RoomId)
+		if err != nil {
+			t.Fatalf("GetRoom failed: %v", err)
+		}
+		if room.LastActiveAt.Before(now.Add(-time.Minute)) {
+			t.Errorf("room %s last active at %v, want it marked active", room.Id, room.LastActiveAt)
+		}
+	}
+}
+
+func TestRemoveExpiredParticipantsKeepsThoseRefreshedMeanwhile(t *testing.T) {
+	useTestDB(t)
+	now := time.Now()
+	room := createTestRoom(t, false, "alice", "bob", "carol")
+	createTestMembership(t, room.Id, "alice", now.Add(-time.Hour))
+	// bob was refreshed after the sweep listed him as expired.
+	createTestMembership(t, room.Id, "bob", now.Add(time.Hour))
+	createTestMembership(t, room.Id, "carol", now.Add(time.Hour))
+	if err := AddVote(room.Id, "alice", "5"); err != nil {
+		t.Fatalf("AddVote failed: %v", err)
+	}
+
+	cleanup, err := RemoveExpiredParticipants(room.Id, []string{"alice", "bob"}, now)
+	if err != nil {
+		t.Fatalf("RemoveExpiredParticipants failed: %v", err)
+	}
+	if !reflect.DeepEqual(cleanup.RemovedUserIds, []string{"alice"}) {
+		t.Errorf("removed %v, want only alice", cleanup.RemovedUserIds)
+	}
+	if cleanup.PreviousScrumMaster != "alice" || cleanup.ScrumMaster == "alice" || cleanup.ScrumMaster == "" {
+		t.Errorf("Scrum Master went from %q to %q, want a remaining participant to take over", cleanup.PreviousScrumMaster, cleanup.ScrumMaster)
+	}
+
+	stored, err := GetRoom(room.Id)
+	if err != nil {
+		t.Fatalf("GetRoom failed: %v", err)
+	}
+	if _, ok := stored.Participants["alice"]; ok || len(stored.Participants) != 2 {
+		t.Errorf("participants = %v, want bob and carol", stored.Participants)
+	}
+	if _, ok := stored.Votes["alice"]; ok {
+		t.Error("alice's vote was kept")
+	}
+	if stored.ScrumMaster != cleanup.ScrumMaster {
+		t.Errorf("stored Scrum Master = %q, reported %q", stored.ScrumMaster, cleanup.ScrumMaster)
+	}
+	if _, err := GetSessionByUserID("alice", room.Id); err == nil {
+		t.Error("alice's membership was kept")
+	}
+}
+
+func TestRemoveExpiredParticipantsOfAGoneRoom(t *testing.T) {
+	useTestDB(t)
+
+	cleanup, err := RemoveExpiredParticipants("no-such-room", []string{"alice"}, time.Now())
+	if err != nil {
+		t.Fatalf("RemoveExpiredParticipants failed: %v", err)
+	}
+	if len(cleanup.RemovedUserIds) != 0 || cleanup.Archived {
+		t.Errorf("cleanup of a missing room = %+v, want nothing done", cleanup)
+	}
+}
+
+func TestRemovingTheLastParticipantsArchivesTheRoom(t *testing.T) {
+	useTestDB(t)
+	now := time.Now()
+	room := createTestRoom(t, false, "alice", "bob")
+	createTestMembership(t, room.Id, "alice", now.Add(-time.Hour))
+	createTestMembership(t, room.Id, "bob", now.Add(-time.Hour))
+	if err := AddVote(room.Id, "alice", "5"); err != nil {
+		t.Fatalf("AddVote failed: %v", err)
+	}
+
+	cleanup, err := RemoveExpiredParticipants(room.Id, []string{"alice", "bob"}, now)
+	if err != nil {
+		t.Fatalf("RemoveExpiredParticipants failed: %v", err)
+	}
+	if !cleanup.Archived || len(cleanup.RemovedUserIds) != 2 {
+		t.Fatalf("cleanup = %+v, want both removed and the room archived", cleanup)
+	}
+
+	stored, err := GetRoom(room.Id)
+	if err != nil {
+		t.Fatalf("GetRoom failed: %v", err)
+	}
+	if !stored.IsArchived() || len(stored.Participants) != 0 {
+		t.Errorf("room archived at %v with %d participants, want it archived and empty", stored.ArchivedAt, len(stored.Participants))
+	}
+
+	// The results are those from before the participants were removed.
+	archive, err := GetRoomArchive(room.Id)
+	if err != nil {
+		t.Fatalf("GetRoomArchive failed: %v", err)
+	}
+	votes := map[string]string{}
+	for _, participant := range archive.Participants {
+		votes[participant.Name] = participant.Vote
+	}
+	if want := map[string]string{"alice": "5", "bob": ""}; !reflect.DeepEqual(votes, want) {
+		t.Errorf("archived votes = %v, want %v", votes, want)
+	}
+
+	// The room is only deleted once its retention has passed.
+	if purged, err := PurgeArchivedRooms(now.Add(-time.Minute)); err != nil || purged != 0 {
+		t.Errorf("PurgeArchivedRooms before the cutoff = %d, %v; want nothing purged", purged, err)
+	}
+	if purged, err := PurgeArchivedRooms(time.Now().Add(time.Minute)); err != nil || purged != 1 {
+		t.Errorf("PurgeArchivedRooms after the cutoff = %d, %v; want the room purged", purged, err)
+	}
+	if _, err := GetRoom(room.Id); err == nil {
+		t.Error("the purged room can still be loaded")
+	}
+}
+
+func TestRemovingTheLastParticipantsKeepsPersistentRooms(t *testing.T) {
+	useTestDB(t)
+	now := time.Now()
+	room := createTestRoom(t, true, "alice")
+	createTestMembership(t, room.Id, "alice", now.Add(-time.Hour))
+
+	cleanup, err := RemoveExpiredParticipants(room.Id, []string{"alice"}, now)
+	if err != nil {
+		t.Fatalf("RemoveExpiredParticipants failed: %v", err)
+	}
+	if cleanup.Archived || len(cleanup.RemovedUserIds) != 1 {
+		t.Fatalf("cleanup = %+v, want alice removed and the room kept", cleanup)
+	}
+
+	stored, err := GetRoom(room.Id)
+	if err != nil {
+		t.Fatalf("GetRoom failed: %v", err)
+	}
+	if stored.IsArchived() || len(stored.Participants) != 0 {
+		t.Errorf("persistent room archived at %v with %d participants, want it open and empty", stored.ArchivedAt, len(stored.Participants))
+	}
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/scrum-poker/backend/metrics"
+	"github.com/scrum-poker/backend/models"
+)
+
+// GetStories returns the room's stories in order.
+func GetStories(roomId string) ([]*models.Story, error) {
+	defer metrics.TimeQuery("GetStories")()
+	rows, err := DB.Query(
+		`SELECT id, room_id, title, position, created_at FROM stories
+		 WHERE room_id = $1 ORDER BY position`,
+		roomId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stories: %v", err)
+	}
+	defer rows.Close()
+
+	stories := []*models.Story{}
+	for rows.Next() {
+		var story models.Story
+		if err := rows.Scan(&story.Id, &story.RoomId, &story.Title, &story.Position, &story.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan story: %v", err)
+		}
+		stories = append(stories, &story)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get stories: %v", err)
+	}
+	return stories, nil
+}
+
+// CountStories returns how many stories the room has. Call it after
+// GetRoomForUpdate, so that the count holds until the unit of work ends.
+func (tx *Tx) CountStories(roomId string) (int, error) {
+	defer metrics.TimeQuery("CountStories")()
+	var count int
+	err := tx.q.QueryRow("SELECT COUNT(*) FROM stories WHERE room_id = $1", roomId).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count stories: %v", err)
+	}
+	return count, nil
+}
+
+// AddStories appends stories to the end of the room's list and sets their
+// positions.
+func (tx *Tx) AddStories(roomId string, stories []*models.Story) error {
+	defer metrics.TimeQuery("AddStories")()
+	var next int
+	err := tx.q.QueryRow("SELECT COALESCE(MAX(position) + 1, 0) FROM stories WHERE room_id = $1", roomId).Scan(&next)
+	if err != nil {
+		return fmt.Errorf("failed to add stories: %v", err)
+	}
+
+	for i, story := range stories {
+		story.Position = next + i
+		_, err := tx.q.Exec(
+			`INSERT INTO stories (id, room_id, title, position, created_at)
+			 VALUES ($1, $2, $3, $4, $5)`,
+			story.Id, roomId, story.Title, story.Position, story.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add story: %v", err)
+		}
+	}
+	return touchRoom(tx.q, roomId)
+}
+
+// DeleteStory removes a story from the room and reports whether it existed.
+func DeleteStory(roomId, storyId string) (bool, error) {
+	defer metrics.TimeQuery("DeleteStory")()
+	result, err := DB.Exec("DELETE FROM stories WHERE room_id = $1 AND id = $2", roomId, storyId)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete story: %v", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete story: %v", err)
+	}
+	return deleted > 0, nil
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Querier runs statements. Both *sql.DB and *sql.Tx implement it, so the
+// same repository code serves single statements and units of work.
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Tx is a unit of work: the repository operations called on it take effect
+// together or not at all.
+type Tx struct {
+	q Querier
+}
+
+// InTx runs fn as a unit of work, committing it when fn returns nil and
+// rolling it back otherwise. fn's error is returned as is.
+func InTx(fn func(tx *Tx) error) error {
+	sqlTx, err := DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer sqlTx.Rollback()
+
+	if err := fn(&Tx{q: sqlTx}); err != nil {
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
@@ -6,11 +6,22 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/scrum-poker/backend/metrics"
 	"github.com/scrum-poker/backend/models"
 )
 
 func DeleteUser(userId string) error {
-	_, err := DB.Exec("DELETE FROM users WHERE id = $1", userId)
+	defer metrics.TimeQuery("DeleteUser")()
+	return deleteUser(DB, userId)
+}
+
+func (tx *Tx) DeleteUser(userId string) error {
+	defer metrics.TimeQuery("DeleteUser")()
+	return deleteUser(tx.q, userId)
+}
+
+func deleteUser(q Querier, userId string) error {
+	_, err := q.Exec("DELETE FROM users WHERE id = $1", userId)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %v", err)
 	}
@@ -18,12 +29,13 @@ func DeleteUser(userId string) error {
 }
 
 func GetUser(userId string) (*models.User, error) {
+	defer metrics.TimeQuery("GetUser")()
 	var user models.User
 	var createdAt time.Time
 	err := DB.QueryRow(
-		"SELECT id, name, created_at FROM users WHERE id = $1",
+		"SELECT id, name, created_at, COALESCE(profile_id, ''), role, avatar_seed FROM users WHERE id = $1",
 		userId,
-	).Scan(&user.Id, &user.Name, &createdAt)
+	).Scan(&user.Id, &user.Name, &createdAt, &user.ProfileId, &user.Role, &user.AvatarSeed)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -37,6 +49,7 @@ func GetUser(userId string) (*models.User, error) {
 }
 
 func UpdateUserName(userId, name string) error {
+	defer metrics.TimeQuery("UpdateUserName")()
 	_, err := DB.Exec(
 		"UPDATE users SET name = $1 WHERE id = $2",
 		name, userId,
@@ -1,8 +1,13 @@
 package db
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/scrum-poker/backend/metrics"
+)
 
 func AddVote(roomId, userId, vote string) error {
+	defer metrics.TimeQuery("AddVote")()
 	_, err := DB.Exec(
 		`INSERT INTO votes (room_id, user_id, vote) 
 		 VALUES ($1, $2, $3) 
@@ -13,19 +18,21 @@ func AddVote(roomId, userId, vote string) error {
 		return fmt.Errorf("failed to add vote: %v", err)
 	}
 
-	return nil
+	return touchRoom(DB, roomId)
 }
 
 func ResetVotes(roomId string) error {
+	defer metrics.TimeQuery("ResetVotes")()
 	_, err := DB.Exec("DELETE FROM votes WHERE room_id = $1", roomId)
 	if err != nil {
 		return fmt.Errorf("failed to reset votes: %v", err)
 	}
 
-	return nil
+	return touchRoom(DB, roomId)
 }
 
 func DeleteVote(roomId, userId string) error {
+	defer metrics.TimeQuery("DeleteVote")()
 	_, err := DB.Exec("DELETE FROM votes WHERE room_id = $1 AND user_id = $2", roomId, userId)
 	if err != nil {
 		return fmt.Errorf("failed to delete vote: %v", err)
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/logic/room_logic"
+)
+
+const exportUsage = "usage: scrum-poker export room [flags] <roomId>"
+
+// runExportCommand implements `export room`, which writes a room with its
+// participants and their votes as JSON.
+func runExportCommand(args []string) error {
+	roomId, output, err := parseExportArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Open(); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	export, err := room_logic.ExportRoom(roomId)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		return writeJSON(os.Stdout, export)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(f, export); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseExportArgs loads the configuration for `export room` and returns the
+// room to export and the file to write, if not standard output.
+func parseExportArgs(args []string) (string, string, error) {
+	if len(args) == 0 || args[0] != "room" {
+		return "", "", errors.New(exportUsage)
+	}
+
+	var output string
+	rest, err := loadCommand("export room", args[1:], func(fs *flag.FlagSet) {
+		fs.StringVar(&output, "o", "", "write to this file instead of standard output")
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if len(rest) != 1 {
+		return "", "", errors.New(exportUsage)
+	}
+	return rest[0], output, nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportArguments(t *testing.T) {
+	useCommandEnv(t)
+
+	roomId, output, err := parseExportArgs([]string{"room", "room-1"})
+	if err != nil || roomId != "room-1" || output != "" {
+		t.Errorf("export room room-1 = %q, %q, %v; want room-1 to standard output", roomId, output, err)
+	}
+	roomId, output, err = parseExportArgs([]string{"room", "-o", "room.json", "room-1"})
+	if err != nil || roomId != "room-1" || output != "room.json" {
+		t.Errorf("export room -o room.json room-1 = %q, %q, %v", roomId, output, err)
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"rooms", "room-1"},
+		{"room"},
+		{"room", "room-1", "room-2"},
+		{"room", "room-1", "-o", "room.json"},
+	} {
+		if _, _, err := parseExportArgs(args); err == nil {
+			t.Errorf("parseExportArgs(%q) was accepted", args)
+		}
+	}
+}
+
+func TestWriteJSONIsIndented(t *testing.T) {
+	var out strings.Builder
+	if err := writeJSON(&out, map[string]string{"id": "room-1"}); err != nil {
+		t.Fatalf("writeJSON failed: %v", err)
+	}
+	if want := "{\n  \"id\": \"room-1\"\n}\n"; out.String() != want {
+		t.Errorf("writeJSON wrote %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,129 @@
+package account_handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/scrum-poker/backend/logging"
+	"github.com/scrum-poker/backend/logic/account_logic"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/utils"
+)
+
+type RegisterRequest struct {
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	DisplayName string `json:"displayName"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+func RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var req RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
+		return
+	}
+
+	account, err := account_logic.Register(req.Email, req.Password, req.DisplayName)
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	if err := signIn(w, r, account.Id); err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+	utils.PrepareJSONResponse(w, http.StatusCreated, account)
+}
+
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
+		return
+	}
+
+	account, err := account_logic.Authenticate(req.Email, req.Password)
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	if err := signIn(w, r, account.Id); err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+	utils.PrepareJSONResponse(w, http.StatusOK, account)
+}
+
+// LogoutHandler signs the account out of the current session. Room
+// memberships held by the session are kept.
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if sessionId := session.IdFromRequest(r); sessionId != "" {
+		if err := session.GlobalManager.SignOut(sessionId); err != nil {
+			utils.PrepareErrorResponse(w, models.DatabaseError{Operation: "SignOut", Message: "Failed to sign out"})
+			return
+		}
+		if err := session.SetCookie(w, sessionId); err != nil {
+			logging.FromRequest(r).Error("Failed to reissue session token", "error", err)
+			session.ClearCookie(w)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAccountHandler returns the signed-in account and extends the sign-in.
+func GetAccountHandler(w http.ResponseWriter, r *http.Request) {
+	account, err := account_logic.GetAccount(session.AccountIdFromRequest(r))
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	if err := signIn(w, r, account.Id); err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+	utils.PrepareJSONResponse(w, http.StatusOK, account)
+}
+
+func ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
+		return
+	}
+
+	err := account_logic.ChangePassword(session.AccountIdFromRequest(r), session.IdFromRequest(r), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// signIn attaches accountId to the request's session, or to a new session
+// when there is none, and reissues the session cookie.
+func signIn(w http.ResponseWriter, r *http.Request, accountId string) error {
+	sessionId, err := session.GlobalManager.SignIn(session.IdFromRequest(r), accountId)
+	if err != nil {
+		return models.DatabaseError{Operation: "SignIn", Message: "Failed to sign in"}
+	}
+	if err := session.SetCookie(w, sessionId); err != nil {
+		return models.DatabaseError{Operation: "SetCookie", Message: "Failed to issue session token"}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package admin_handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/logging"
+	"github.com/scrum-poker/backend/logic/admin_logic"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/utils"
+	"github.com/scrum-poker/backend/websocket"
+)
+
+type CloseRoomRequest struct {
+	Reason string `json:"reason"`
+}
+
+type NoticeRequest struct {
+	Message string `json:"message"`
+}
+
+// RequireAdmin lets requests through that carry ADMIN_TOKEN as a Bearer
+// token. The admin API is hidden entirely while no token is configured.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !config.Cfg.Admin.Enabled() {
+			http.NotFound(w, r)
+			return
+		}
+		if !utils.HasBearerToken(r, config.Cfg.Admin.Token) {
+			logging.FromRequest(r).Warn("Rejected admin request", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			utils.PrepareErrorResponse(w, models.UnauthorizedError{Message: "A valid admin token is required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func ListRoomsHandler(w http.ResponseWriter, _ *http.Request) {
+	rooms, err := admin_logic.ListRooms(websocket.GlobalHub)
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	utils.PrepareJSONResponse(w, http.StatusOK, rooms)
+}
+
+func InspectRoomHandler(w http.ResponseWriter, r *http.Request) {
+	room, err := admin_logic.InspectRoom(websocket.GlobalHub, mux.Vars(r)["roomId"])
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	utils.PrepareJSONResponse(w, http.StatusOK, room)
+}
+
+// CloseRoomHandler disconnects everyone in the room and deletes it. The
+// request body, with an optional reason shown to participants, may be
+// omitted.
+func CloseRoomHandler(w http.ResponseWriter, r *http.Request) {
+	var req CloseRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
+		return
+	}
+
+	if err := admin_logic.CloseRoom(websocket.GlobalHub, mux.Vars(r)["roomId"], req.Reason); err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	logging.FromRequest(r).Info("Admin closed room", "reason", req.Reason)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func EvictUserHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := admin_logic.EvictUser(websocket.GlobalHub, vars["roomId"], vars["userId"]); err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	logging.FromRequest(r).Info("Admin evicted user", "user_id", vars["userId"])
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func BroadcastNoticeHandler(w http.ResponseWriter, r *http.Request) {
+	var req NoticeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
+		return
+	}
+
+	result, err := admin_logic.BroadcastNotice(websocket.GlobalHub, req.Message)
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	logging.FromRequest(r).Info("Admin broadcast notice", "rooms", result.Rooms)
+	utils.PrepareJSONResponse(w, http.StatusOK, result)
+}
+
+// RunSessionCleanupHandler runs a session cleanup sweep and answers with its
+// report once it has finished.
+func RunSessionCleanupHandler(w http.ResponseWriter, r *http.Request) {
+	report := admin_logic.RunSessionCleanup()
+
+	logging.FromRequest(r).Info("Admin ran session cleanup", "removed", report.Removed, "rooms_archived", report.RoomsArchived)
+	utils.PrepareJSONResponse(w, http.StatusOK, report)
+}
@@ -0,0 +1,101 @@
+package auth_handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/logging"
+	"github.com/scrum-poker/backend/logic/account_logic"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/sso"
+	"github.com/scrum-poker/backend/utils"
+	"golang.org/x/oauth2"
+)
+
+// OIDCLoginHandler starts an authorization code flow with PKCE and redirects
+// the browser to the identity provider.
+func OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if !config.Cfg.OIDC.Enabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	state := session.LoginState{
+		State:        oauth2.GenerateVerifier(),
+		Nonce:        oauth2.GenerateVerifier(),
+		CodeVerifier: oauth2.GenerateVerifier(),
+		ReturnTo:     returnPath(r.URL.Query().Get("returnTo")),
+	}
+
+	authURL, err := sso.AuthCodeURL(r.Context(), state.State, state.Nonce, state.CodeVerifier)
+	if err != nil {
+		logging.FromRequest(r).Error("Failed to start OIDC login", "error", err)
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	if err := session.SetLoginStateCookie(w, state); err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallbackHandler completes the flow: it checks the state, redeems the
+// code, signs the mapped account in and sends the browser back to where it
+// started.
+func OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if !config.Cfg.OIDC.Enabled() {
+		http.NotFound(w, r)
+		return
+	}
+
+	state, err := session.LoginStateFromRequest(r)
+	session.ClearLoginStateCookie(w)
+	if err != nil || r.URL.Query().Get("state") != state.State {
+		utils.PrepareErrorResponse(w, models.UnauthorizedError{Message: "Login state is missing or invalid"})
+		return
+	}
+
+	if providerErr := r.URL.Query().Get("error"); providerErr != "" {
+		logging.FromRequest(r).Warn("OIDC login was rejected by the provider", "provider_error", providerErr)
+		utils.PrepareErrorResponse(w, models.UnauthorizedError{Message: "Sign-in was cancelled or rejected"})
+		return
+	}
+
+	identity, err := sso.Exchange(r.Context(), r.URL.Query().Get("code"), state.CodeVerifier, state.Nonce)
+	if err != nil {
+		logging.FromRequest(r).Warn("Failed to complete OIDC login", "error", err)
+		utils.PrepareErrorResponse(w, models.UnauthorizedError{Message: "Sign-in could not be verified"})
+		return
+	}
+
+	account, err := account_logic.SignInWithIdentity(identity)
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	sessionId, err := session.GlobalManager.SignIn(session.IdFromRequest(r), account.Id)
+	if err != nil {
+		utils.PrepareErrorResponse(w, models.DatabaseError{Operation: "SignIn", Message: "Failed to sign in"})
+		return
+	}
+	if err := session.SetCookie(w, sessionId); err != nil {
+		utils.PrepareErrorResponse(w, models.DatabaseError{Operation: "SetCookie", Message: "Failed to issue session token"})
+		return
+	}
+
+	http.Redirect(w, r, config.Cfg.OIDC.PostLoginURL+state.ReturnTo, http.StatusFound)
+}
+
+// returnPath only accepts paths on the frontend itself, so the login cannot
+// be used to redirect to another site.
+func returnPath(value string) string {
+	if !strings.HasPrefix(value, "/") || strings.HasPrefix(value, "//") || strings.Contains(value, "\\") {
+		return "/"
+	}
+	return value
+}
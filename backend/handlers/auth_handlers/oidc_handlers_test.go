@@ -0,0 +1,97 @@
+package auth_handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/session"
+)
+
+func useOIDCConfig(t *testing.T) {
+	t.Helper()
+	previous := config.Cfg
+	config.Cfg.OIDC = config.OIDCConfig{
+		IssuerURL:   "https://issuer.example.com",
+		ClientID:    "scrum-poker",
+		RedirectURL: "https://poker.example.com/auth/oidc/callback",
+	}
+	config.Cfg.Session.SigningKeys = []config.SigningKey{{Id: "test", Secret: []byte("0123456789abcdef0123456789abcdef")}}
+	t.Cleanup(func() { config.Cfg = previous })
+}
+
+// loginStateCookie returns the cookie OIDCLoginHandler would have set for
+// state.
+func loginStateCookie(t *testing.T, state session.LoginState) *http.Cookie {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	if err := session.SetLoginStateCookie(recorder, state); err != nil {
+		t.Fatal(err)
+	}
+	return recorder.Result().Cookies()[0]
+}
+
+func TestOIDCCallbackRejectsInvalidState(t *testing.T) {
+	useOIDCConfig(t)
+	state := session.LoginState{State: "expected-state", Nonce: "nonce", CodeVerifier: "verifier", ReturnTo: "/"}
+
+	tampered := loginStateCookie(t, state)
+	tampered.Value += "x"
+
+	tests := []struct {
+		name   string
+		query  string
+		cookie *http.Cookie
+	}{
+		{name: "missing cookie", query: "?state=expected-state&code=code"},
+		{name: "state does not match", query: "?state=other-state&code=code", cookie: loginStateCookie(t, state)},
+		{name: "missing state", query: "?code=code", cookie: loginStateCookie(t, state)},
+		{name: "tampered cookie", query: "?state=expected-state&code=code", cookie: tampered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback"+tt.query, nil)
+			if tt.cookie != nil {
+				r.AddCookie(tt.cookie)
+			}
+			w := httptest.NewRecorder()
+
+			OIDCCallbackHandler(w, r)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+			}
+			cleared := false
+			for _, cookie := range w.Result().Cookies() {
+				if cookie.Name == session.LoginStateCookieName && cookie.MaxAge < 0 {
+					cleared = true
+				}
+			}
+			if !cleared {
+				t.Error("the login state cookie was not cleared")
+			}
+		})
+	}
+}
+
+func TestReturnPath(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "/room/abc", want: "/room/abc"},
+		{value: "", want: "/"},
+		{value: "https://evil.example.com", want: "/"},
+		{value: "//evil.example.com", want: "/"},
+		{value: "/\\evil.example.com", want: "/"},
+		{value: "room/abc", want: "/"},
+	}
+
+	for _, tt := range tests {
+		if got := returnPath(tt.value); got != tt.want {
+			t.Errorf("returnPath(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package event_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/logging"
+	"github.com/scrum-poker/backend/logic/message_logic"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/ratelimit"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/utils"
+	"github.com/scrum-poker/backend/validation"
+	"github.com/scrum-poker/backend/websocket"
+)
+
+func ActionsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomId := vars["roomId"]
+
+	currSession, err := session.FromRequest(r, roomId)
+	if err != nil {
+		session.WriteError(w, err)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, config.Cfg.WebSocket.MaxMessageSize))
+	if err != nil {
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
+		return
+	}
+
+	msg, err := models.DecodeClientMessage(body)
+	if err != nil {
+		if errors.Is(err, models.ErrServerOnlyAction) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: err.Error()})
+		return
+	}
+
+	if token := session.APITokenFromRequest(r); token != nil && !token.HasScope(models.ActionScope(msg, currSession.UserId)) {
+		utils.PrepareErrorResponse(w, models.ForbiddenError{Message: "API token lacks the facilitate scope"})
+		return
+	}
+
+	if ok, retryAfter := ratelimit.AllowAction(nil, currSession.UserId, ratelimit.ClientIP(r)); !ok {
+		ratelimit.WriteThrottled(w, retryAfter)
+		return
+	}
+
+	if msg.Action == models.ActionTypeHello {
+		http.Error(w, "Protocol negotiation is only available over WebSocket", http.StatusBadRequest)
+		return
+	}
+
+	if payload, ok := msg.Payload.(*models.RenamePayload); ok {
+		if _, err := validation.UserName("payload.name", payload.Name); err != nil {
+			utils.PrepareErrorResponse(w, err)
+			return
+		}
+	}
+
+	logger := logging.FromRequest(r).With("user_id", currSession.UserId)
+	message_logic.ProcessMessage(logger, websocket.GlobalHub.Broadcast, roomId, currSession.UserId, msg)
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,34 @@
+package event_handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/websocket"
+)
+
+func EventsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomId := vars["roomId"]
+
+	currSession, err := session.FromRequest(r, roomId)
+	if err != nil {
+		session.WriteError(w, err)
+		return
+	}
+
+	room, err := db.GetRoom(roomId)
+	if err != nil {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	if _, ok := room.Participants[currSession.UserId]; !ok {
+		http.Error(w, "User not in room", http.StatusForbidden)
+		return
+	}
+
+	websocket.ServeSSE(websocket.GlobalHub, w, r, roomId, currSession.UserId)
+}
@@ -1,26 +1,52 @@
 package handlers
 
 import (
-	"fmt"
+	"github.com/scrum-poker/backend/handlers/account_handlers"
+	"github.com/scrum-poker/backend/handlers/admin_handlers"
+	"github.com/scrum-poker/backend/handlers/auth_handlers"
+	"github.com/scrum-poker/backend/handlers/event_handlers"
+	"github.com/scrum-poker/backend/handlers/health_handlers"
+	"github.com/scrum-poker/backend/handlers/profile_handlers"
 	"github.com/scrum-poker/backend/handlers/room_handlers"
 	"github.com/scrum-poker/backend/handlers/session_handlers"
+	"github.com/scrum-poker/backend/handlers/token_handlers"
 	"github.com/scrum-poker/backend/handlers/websocket_handlers"
+	"github.com/scrum-poker/backend/logging"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/utils"
 	"net/http"
 )
 
-func HealthCheckHandler(w http.ResponseWriter, _ *http.Request) {
+func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, err := w.Write([]byte("OK"))
 	if err != nil {
-		fmt.Println(err)
+		logging.FromRequest(r).Warn("Error writing health check response", "error", err)
 		return
 	}
 }
 
+func ProtocolSchemaHandler(w http.ResponseWriter, _ *http.Request) {
+	utils.PrepareJSONResponse(w, http.StatusOK, models.ProtocolSchema())
+}
+
+var (
+	LivezHandler  = health_handlers.LivezHandler
+	ReadyzHandler = health_handlers.ReadyzHandler
+)
+
 var (
 	CreateRoomHandler = room_handlers.CreateRoomHandler
 	GetRoomHandler    = room_handlers.GetRoomHandler
 	JoinRoomHandler   = room_handlers.JoinRoomHandler
+	UpdateRoomHandler = room_handlers.UpdateRoomHandler
+	ExportRoomHandler = room_handlers.ExportRoomHandler
+)
+
+var (
+	GetStoriesHandler  = room_handlers.GetStoriesHandler
+	AddStoriesHandler  = room_handlers.AddStoriesHandler
+	DeleteStoryHandler = room_handlers.DeleteStoryHandler
 )
 
 var (
@@ -28,7 +54,46 @@ var (
 )
 
 var (
-	CreateSessionHandler = session_handlers.CreateSessionHandler
+	EventsHandler  = event_handlers.EventsHandler
+	ActionsHandler = event_handlers.ActionsHandler
+)
+
+var (
 	GetSessionHandler    = session_handlers.GetSessionHandler
 	DeleteSessionHandler = session_handlers.DeleteSessionHandler
 )
+
+var (
+	GetProfileHandler    = profile_handlers.GetProfileHandler
+	SaveProfileHandler   = profile_handlers.SaveProfileHandler
+	DeleteProfileHandler = profile_handlers.DeleteProfileHandler
+)
+
+var (
+	RegisterHandler       = account_handlers.RegisterHandler
+	LoginHandler          = account_handlers.LoginHandler
+	LogoutHandler         = account_handlers.LogoutHandler
+	GetAccountHandler     = account_handlers.GetAccountHandler
+	ChangePasswordHandler = account_handlers.ChangePasswordHandler
+)
+
+var (
+	OIDCLoginHandler    = auth_handlers.OIDCLoginHandler
+	OIDCCallbackHandler = auth_handlers.OIDCCallbackHandler
+)
+
+var (
+	IssueTokenHandler  = token_handlers.IssueTokenHandler
+	ListTokensHandler  = token_handlers.ListTokensHandler
+	RevokeTokenHandler = token_handlers.RevokeTokenHandler
+)
+
+var (
+	RequireAdmin               = admin_handlers.RequireAdmin
+	AdminListRoomsHandler      = admin_handlers.ListRoomsHandler
+	AdminInspectRoomHandler    = admin_handlers.InspectRoomHandler
+	AdminCloseRoomHandler      = admin_handlers.CloseRoomHandler
+	AdminEvictUserHandler      = admin_handlers.EvictUserHandler
+	AdminNoticeHandler         = admin_handlers.BroadcastNoticeHandler
+	AdminSessionCleanupHandler = admin_handlers.RunSessionCleanupHandler
+)
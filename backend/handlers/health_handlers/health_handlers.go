@@ -0,0 +1,100 @@
+package health_handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/logging"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/utils"
+	"github.com/scrum-poker/backend/websocket"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+type HealthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type check func(ctx context.Context) error
+
+// readinessChecks are the dependencies an instance needs to serve rooms.
+var readinessChecks = map[string]check{
+	"database": db.Ping,
+	"hub": func(ctx context.Context) error {
+		return websocket.GlobalHub.Check(ctx)
+	},
+	"sessionCleanup": func(context.Context) error {
+		return session.GlobalManager.CheckCleanup()
+	},
+}
+
+// LivezHandler reports that the process is up and serving HTTP. It checks
+// no dependencies, so an orchestrator does not restart an instance only
+// because the database is unavailable.
+func LivezHandler(w http.ResponseWriter, _ *http.Request) {
+	utils.PrepareJSONResponse(w, http.StatusOK, HealthResponse{Status: StatusOK})
+}
+
+// ReadyzHandler runs every readiness check concurrently within
+// READINESS_TIMEOUT and answers 503 with the failing ones when any fails, so
+// that traffic is routed elsewhere.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), config.Cfg.Server.ReadinessTimeout)
+	defer cancel()
+
+	response := HealthResponse{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(readinessChecks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, run := range readinessChecks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := runCheck(ctx, run)
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[name] = result
+			if result.Status != StatusOK {
+				response.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if response.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+		logging.FromRequest(r).Warn("Readiness check failed", "checks", response.Checks)
+	}
+	utils.PrepareJSONResponse(w, status, response)
+}
+
+func runCheck(ctx context.Context, run check) CheckResult {
+	start := time.Now()
+	err := run(ctx)
+	result := CheckResult{Status: StatusOK, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
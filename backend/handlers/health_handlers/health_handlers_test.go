@@ -0,0 +1,152 @@
+package health_handlers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/websocket"
+)
+
+// database stands in for Postgres: it accepts connections, refuses them, or
+// never answers.
+type database string
+
+const (
+	databaseUp      database = "up"
+	databaseDown    database = "down"
+	databaseHanging database = "hanging"
+)
+
+func (d database) Connect(ctx context.Context) (driver.Conn, error) {
+	switch d {
+	case databaseDown:
+		return nil, errors.New("connection refused")
+	case databaseHanging:
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return conn{}, nil
+}
+
+func (d database) Driver() driver.Driver { return nil }
+
+type conn struct{}
+
+func (conn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (conn) Close() error                        { return nil }
+func (conn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+// useDependencies starts a hub and session cleanup like the server does and
+// points the database at d.
+func useDependencies(t *testing.T, d database) {
+	t.Helper()
+	previousCfg, previousDB := config.Cfg, db.DB
+	previousHub, previousManager := websocket.GlobalHub, session.GlobalManager
+	config.Cfg.Server.ReadinessTimeout = 100 * time.Millisecond
+	config.Cfg.Session.CleanupInterval = time.Hour
+
+	db.DB = sql.OpenDB(d)
+	websocket.Init()
+	manager := session.GlobalManager
+	t.Cleanup(func() {
+		manager.StopCleanupProcess()
+		db.DB.Close()
+		config.Cfg, db.DB = previousCfg, previousDB
+		websocket.GlobalHub, session.GlobalManager = previousHub, previousManager
+	})
+}
+
+func getHealth(t *testing.T, handler http.HandlerFunc) (int, HealthResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var response HealthResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return w.Code, response
+}
+
+// expectFailing checks that readiness fails on exactly the checks named.
+func expectFailing(t *testing.T, failing ...string) {
+	t.Helper()
+	code, response := getHealth(t, ReadyzHandler)
+
+	wantCode, wantStatus := http.StatusOK, StatusOK
+	if len(failing) > 0 {
+		wantCode, wantStatus = http.StatusServiceUnavailable, StatusFail
+	}
+	if code != wantCode || response.Status != wantStatus {
+		t.Errorf("/readyz = %d %s, want %d %s", code, response.Status, wantCode, wantStatus)
+	}
+
+	for name := range readinessChecks {
+		result, reported := response.Checks[name]
+		if !reported {
+			t.Errorf("check %s was not reported", name)
+			continue
+		}
+		fails := false
+		for _, f := range failing {
+			fails = fails || f == name
+		}
+		if fails && (result.Status != StatusFail || result.Error == "") {
+			t.Errorf("check %s = %+v, want it failed with a reason", name, result)
+		}
+		if !fails && result.Status != StatusOK {
+			t.Errorf("check %s = %+v, want ok", name, result)
+		}
+	}
+}
+
+func TestReadyWhenDependenciesAreUp(t *testing.T) {
+	useDependencies(t, databaseUp)
+	expectFailing(t)
+}
+
+func TestNotReadyWithoutTheDatabase(t *testing.T) {
+	useDependencies(t, databaseDown)
+	expectFailing(t, "database")
+
+	// Liveness does not depend on the database.
+	if code, response := getHealth(t, LivezHandler); code != http.StatusOK || response.Status != StatusOK {
+		t.Errorf("/livez = %d %s, want 200 ok", code, response.Status)
+	}
+}
+
+func TestReadinessGivesUpOnAHangingDatabase(t *testing.T) {
+	useDependencies(t, databaseHanging)
+
+	start := time.Now()
+	expectFailing(t, "database")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("/readyz took %v, want it bounded by the readiness timeout", elapsed)
+	}
+}
+
+func TestNotReadyWhileTheHubShutsDown(t *testing.T) {
+	useDependencies(t, databaseUp)
+	websocket.GlobalHub.Shutdown()
+	expectFailing(t, "hub")
+}
+
+func TestNotReadyWithoutSessionCleanup(t *testing.T) {
+	useDependencies(t, databaseUp)
+
+	stopped := session.NewManager(nil, nil, nil)
+	stopped.StartCleanupProcess()
+	stopped.StopCleanupProcess()
+	session.GlobalManager = stopped
+	expectFailing(t, "sessionCleanup")
+}
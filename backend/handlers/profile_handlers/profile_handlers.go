@@ -0,0 +1,58 @@
+package profile_handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/scrum-poker/backend/logic/profile_logic"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/utils"
+)
+
+type SaveProfileRequest struct {
+	DisplayName   string `json:"displayName"`
+	AvatarSeed    string `json:"avatarSeed"`
+	PreferredRole string `json:"preferredRole"`
+}
+
+func GetProfileHandler(w http.ResponseWriter, r *http.Request) {
+	profile, err := profile_logic.GetProfile(session.ProfileIdFromRequest(r))
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	utils.PrepareJSONResponse(w, http.StatusOK, profile)
+}
+
+// SaveProfileHandler creates the device's profile on first use and updates it
+// afterwards, binding it to the device through the profile cookie.
+func SaveProfileHandler(w http.ResponseWriter, r *http.Request) {
+	var req SaveProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
+		return
+	}
+
+	profile, err := profile_logic.SaveProfile(session.ProfileIdFromRequest(r), req.DisplayName, req.AvatarSeed, req.PreferredRole)
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	session.SetProfileCookie(w, profile.Id)
+	utils.PrepareJSONResponse(w, http.StatusOK, profile)
+}
+
+func DeleteProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if profileId := session.ProfileIdFromRequest(r); profileId != "" {
+		if err := profile_logic.DeleteProfile(profileId); err != nil {
+			utils.PrepareErrorResponse(w, err)
+			return
+		}
+	}
+
+	session.ClearProfileCookie(w)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,47 +3,66 @@ package room_handlers
 import (
 	"encoding/json"
 	"github.com/scrum-poker/backend/logic/room_logic"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
 	"github.com/scrum-poker/backend/utils"
 	"net/http"
 	"time"
 )
 
 type CreateRoomRequest struct {
-	Name     string `json:"name"`
-	UserName string `json:"userName"`
+	Name                string   `json:"name"`
+	UserName            string   `json:"userName"`
+	AllowGuests         *bool    `json:"allowGuests"`
+	AllowedEmailDomains []string `json:"allowedEmailDomains"`
+	Persistent          bool     `json:"persistent"`
 }
 
 type RoomResponse struct {
-	Id            string                 `json:"id"`
-	Name          string                 `json:"name"`
-	CreatedAt     time.Time              `json:"createdAt"`
-	ScrumMaster   string                 `json:"scrumMaster"`
-	Participants  map[string]interface{} `json:"participants"`
-	Votes         map[string]string      `json:"votes"`
-	VotesRevealed bool                   `json:"votesRevealed"`
+	Id                  string                 `json:"id"`
+	Name                string                 `json:"name"`
+	CreatedAt           time.Time              `json:"createdAt"`
+	ScrumMaster         string                 `json:"scrumMaster"`
+	Participants        map[string]interface{} `json:"participants"`
+	Votes               map[string]string      `json:"votes"`
+	VotesRevealed       bool                   `json:"votesRevealed"`
+	AllowGuests         bool                   `json:"allowGuests"`
+	AllowedEmailDomains []string               `json:"allowedEmailDomains"`
+	Persistent          bool                   `json:"persistent"`
 }
 
 func CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
 	var req CreateRoomRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
 		return
 	}
 
-	room, user, err := room_logic.CreateRoom(req.Name, req.UserName)
+	access := room_logic.RoomSettings{
+		AllowGuests:         req.AllowGuests == nil || *req.AllowGuests,
+		AllowedEmailDomains: req.AllowedEmailDomains,
+		Persistent:          req.Persistent,
+	}
+	room, membership, err := room_logic.CreateRoom(req.Name, req.UserName, session.IdentityFromRequest(r), access, session.IdFromRequest(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		utils.PrepareErrorResponse(w, err)
 		return
 	}
 
+	issueCookie(w, r, membership)
+	user := room.Participants[membership.UserId]
+
 	resp := RoomResponse{
-		Id:            room.Id,
-		Name:          room.Name,
-		CreatedAt:     room.CreatedAt,
-		ScrumMaster:   room.ScrumMaster,
-		Participants:  map[string]interface{}{user.Id: user.ToJSON()},
-		Votes:         make(map[string]string),
-		VotesRevealed: false,
+		Id:                  room.Id,
+		Name:                room.Name,
+		CreatedAt:           room.CreatedAt,
+		ScrumMaster:         room.ScrumMaster,
+		Participants:        map[string]interface{}{user.Id: user.ToJSON()},
+		Votes:               make(map[string]string),
+		VotesRevealed:       false,
+		AllowGuests:         room.AllowGuests,
+		AllowedEmailDomains: room.AllowedEmailDomains,
+		Persistent:          room.Persistent,
 	}
 	utils.PrepareJSONResponse(w, http.StatusCreated, resp)
 }
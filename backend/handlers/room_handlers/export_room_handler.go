@@ -0,0 +1,22 @@
+package room_handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/scrum-poker/backend/logic/room_logic"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/utils"
+)
+
+// ExportRoomHandler returns the room's results, archived or not, to the
+// account that owns it.
+func ExportRoomHandler(w http.ResponseWriter, r *http.Request) {
+	export, err := room_logic.ExportOwnedRoom(mux.Vars(r)["roomId"], session.AccountIdFromRequest(r))
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	utils.PrepareJSONResponse(w, http.StatusOK, export)
+}
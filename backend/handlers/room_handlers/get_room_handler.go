@@ -3,19 +3,30 @@ package room_handlers
 import (
 	"github.com/gorilla/mux"
 	"github.com/scrum-poker/backend/logic/room_logic"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
 	"github.com/scrum-poker/backend/utils"
 	"net/http"
 )
 
+// GetRoomHandler returns the room. Restricted rooms are shown only to their
+// members, so that their participants and votes stay private.
 func GetRoomHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	roomId := vars["roomId"]
 
 	room, err := room_logic.GetRoom(roomId)
 	if err != nil {
-		http.Error(w, "Room not found", http.StatusNotFound)
+		utils.PrepareErrorResponse(w, models.NotFoundError{Resource: "Room", Message: "Room not found"})
 		return
 	}
 
+	if room.IsRestricted() {
+		if _, err := session.FromRequest(r, roomId); err != nil {
+			session.WriteError(w, err)
+			return
+		}
+	}
+
 	utils.PrepareJSONResponse(w, http.StatusOK, room.ToJSON())
 }
@@ -2,12 +2,13 @@ package room_handlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/scrum-poker/backend/config"
 	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/logging"
 	"github.com/scrum-poker/backend/logic/room_logic"
 	"github.com/scrum-poker/backend/models"
 	"github.com/scrum-poker/backend/session"
@@ -26,83 +27,54 @@ type JoinRoomResponse struct {
 func JoinRoomHandler(w http.ResponseWriter, r *http.Request) {
 	var req JoinRoomRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
 		return
 	}
 
 	vars := mux.Vars(r)
 	roomId := vars["roomId"]
 
-	currSession := getSession(r)
+	currSession := getSession(r, roomId)
 
-	userId, err := room_logic.JoinRoom(roomId, req.UserName, currSession, websocket.GlobalHub.Broadcast)
+	membership, err := room_logic.JoinRoom(roomId, req.UserName, session.IdentityFromRequest(r), session.IdFromRequest(r), currSession, websocket.GlobalHub.Broadcast)
 	if err != nil {
-		switch e := err.(type) {
-		case room_logic.ValidationError:
-			http.Error(w, e.Error(), http.StatusBadRequest)
-		case room_logic.NotFoundError:
-			http.Error(w, e.Error(), http.StatusNotFound)
-		case room_logic.DatabaseError:
-			http.Error(w, e.Error(), http.StatusInternalServerError)
-		default:
-			http.Error(w, e.Error(), http.StatusInternalServerError)
-		}
+		utils.PrepareErrorResponse(w, err)
 		return
 	}
 
-	if currSession == nil {
-		sessionID, _ := session.GlobalManager.CreateSession(userId, roomId)
-
-		http.SetCookie(w, &http.Cookie{
-			Name:     "sessionId",
-			Value:    sessionID,
-			Path:     "/",
-			HttpOnly: true,
-			Secure:   config.Cfg.Cookie.Secure,
-			SameSite: config.Cfg.Cookie.SameSite,
-		})
-	}
+	issueCookie(w, r, membership)
 
 	utils.PrepareJSONResponse(w, http.StatusOK, JoinRoomResponse{
-		UserId: userId,
+		UserId: membership.UserId,
 	})
 }
 
-func getSession(r *http.Request) *models.Session {
-	cookie, err := r.Cookie("sessionId")
-	if err != nil {
-		fmt.Print("Session cookie not found.")
-		return nil
-	}
-	sessionId := cookie.Value
-	currSession, err := db.GetSession(sessionId)
+// getSession returns the caller's session for the room, refreshed, or nil
+// when the request does not carry a usable one.
+func getSession(r *http.Request, roomId string) *models.Session {
+	currSession, err := session.FromRequest(r, roomId)
 	if err != nil {
-		fmt.Print("Session not found in database.")
-		return nil
-	}
-
-	if currSession.IsExpired() {
-		fmt.Print("Session is expired.")
-		err := db.DeleteSession(sessionId)
-		if err != nil {
-			return nil
+		if !errors.Is(err, session.ErrNoSession) {
+			logging.FromRequest(r).Info("Ignoring session cookie on join", "error", err)
 		}
 		return nil
 	}
 
-	vars := mux.Vars(r)
-	roomId := vars["roomId"]
-
-	if roomId != currSession.RoomId {
-		fmt.Print("Room Id does not match session.")
-		return nil
-	}
-
-	currSession.Refresh(session.TTL)
-
+	currSession.Refresh(config.Cfg.Session.MembershipTTL)
 	if err := db.UpdateSession(currSession); err != nil {
-		fmt.Print("Failed to update session.")
+		logging.FromRequest(r).Error("Failed to update session", "user_id", currSession.UserId, "error", err)
 		return nil
 	}
 	return currSession
 }
+
+// issueCookie stores currSession in the session cookie. API tokens carry
+// their session themselves and get no cookie.
+func issueCookie(w http.ResponseWriter, r *http.Request, currSession *models.Session) {
+	if session.APITokenFromRequest(r) != nil {
+		return
+	}
+	if err := session.SetCookie(w, currSession.Id); err != nil {
+		logging.FromRequest(r).Error("Failed to issue session token", "user_id", currSession.UserId, "error", err)
+	}
+}
@@ -0,0 +1,86 @@
+package room_handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/scrum-poker/backend/logic/room_logic"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/utils"
+)
+
+type AddStoriesRequest struct {
+	Stories []StoryRequest `json:"stories"`
+}
+
+type StoryRequest struct {
+	Title string `json:"title"`
+}
+
+type StoriesResponse struct {
+	Stories []*models.Story `json:"stories"`
+}
+
+// GetStoriesHandler lists the room's stories to its members.
+func GetStoriesHandler(w http.ResponseWriter, r *http.Request) {
+	roomId := mux.Vars(r)["roomId"]
+	if _, err := session.FromRequest(r, roomId); err != nil {
+		session.WriteError(w, err)
+		return
+	}
+
+	stories, err := room_logic.GetStories(roomId)
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	utils.PrepareJSONResponse(w, http.StatusOK, StoriesResponse{Stories: stories})
+}
+
+// AddStoriesHandler appends stories to the room's list.
+func AddStoriesHandler(w http.ResponseWriter, r *http.Request) {
+	var req AddStoriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
+		return
+	}
+
+	roomId := mux.Vars(r)["roomId"]
+	if _, err := session.FromRequest(r, roomId); err != nil {
+		session.WriteError(w, err)
+		return
+	}
+
+	titles := make([]string, 0, len(req.Stories))
+	for _, story := range req.Stories {
+		titles = append(titles, story.Title)
+	}
+	stories, err := room_logic.AddStories(roomId, titles)
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	utils.PrepareJSONResponse(w, http.StatusCreated, StoriesResponse{Stories: stories})
+}
+
+// DeleteStoryHandler removes a story; only the Scrum Master may do so.
+func DeleteStoryHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roomId := vars["roomId"]
+	currSession, err := session.FromRequest(r, roomId)
+	if err != nil {
+		session.WriteError(w, err)
+		return
+	}
+
+	if err := room_logic.DeleteStory(roomId, currSession.UserId, vars["storyId"]); err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
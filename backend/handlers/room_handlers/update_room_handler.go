@@ -0,0 +1,50 @@
+package room_handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/scrum-poker/backend/logic/room_logic"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/utils"
+	"github.com/scrum-poker/backend/websocket"
+)
+
+type UpdateRoomRequest struct {
+	AllowGuests         *bool    `json:"allowGuests"`
+	AllowedEmailDomains []string `json:"allowedEmailDomains"`
+	Persistent          *bool    `json:"persistent"`
+	// Archived, when true, archives the room after any other change.
+	Archived *bool `json:"archived"`
+}
+
+const archivedReason = "The room was archived"
+
+func UpdateRoomHandler(w http.ResponseWriter, r *http.Request) {
+	var req UpdateRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
+		return
+	}
+
+	roomId := mux.Vars(r)["roomId"]
+	accountId := session.AccountIdFromRequest(r)
+	if req.AllowGuests != nil || req.AllowedEmailDomains != nil || req.Persistent != nil {
+		if err := room_logic.UpdateRoomSettings(roomId, accountId, req.AllowGuests, req.AllowedEmailDomains, req.Persistent); err != nil {
+			utils.PrepareErrorResponse(w, err)
+			return
+		}
+	}
+
+	if req.Archived != nil && *req.Archived {
+		if err := room_logic.ArchiveRoom(roomId, accountId); err != nil {
+			utils.PrepareErrorResponse(w, err)
+			return
+		}
+		websocket.GlobalHub.CloseRoom(roomId, archivedReason)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
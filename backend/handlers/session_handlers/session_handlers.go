@@ -1,89 +1,26 @@
 package session_handlers
 
 import (
+	"errors"
 	"net/http"
 
-	"github.com/gorilla/mux"
 	"github.com/scrum-poker/backend/config"
 	"github.com/scrum-poker/backend/db"
 	"github.com/scrum-poker/backend/session"
 	"github.com/scrum-poker/backend/utils"
 )
 
-func CreateSessionHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userId := vars["userId"]
-	roomId := vars["roomId"]
-
-	if userId == "" || roomId == "" {
-		http.Error(w, "User Id and Room Id are required", http.StatusBadRequest)
-		return
-	}
-
-	_, err := db.GetUser(userId)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
-	}
-
-	room, err := db.GetRoom(roomId)
-	if err != nil {
-		http.Error(w, "Room not found", http.StatusNotFound)
-		return
-	}
-
-	if _, ok := room.Participants[userId]; !ok {
-		http.Error(w, "User not in room", http.StatusForbidden)
-		return
-	}
-
-	sessionID, err := session.GlobalManager.CreateSession(userId, roomId)
-	if err != nil {
-		http.Error(w, "Failed to create session", http.StatusInternalServerError)
-		return
-	}
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "sessionId",
-		Value:    sessionID,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   config.Cfg.Cookie.Secure,
-		SameSite: config.Cfg.Cookie.SameSite,
-	})
-
-	utils.PrepareJSONResponse(w, http.StatusCreated, map[string]string{
-		"sessionId": sessionID,
-	})
-}
-
 func GetSessionHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("sessionId")
+	existingSession, err := session.FromRequest(r, r.URL.Query().Get("roomId"))
 	if err != nil {
-		http.Error(w, "Cookie not found", http.StatusNotFound)
-		return
-	}
-
-	sessionId := cookie.Value
-	if sessionId == "" {
-		http.Error(w, "Session id is required", http.StatusBadRequest)
-		return
-	}
-
-	existingSession, err := db.GetSession(sessionId)
-	if err != nil {
-		http.Error(w, "Session not found in database", http.StatusNotFound)
-		return
-	}
-
-	roomId := r.URL.Query().Get("roomId")
-	if existingSession.RoomId != roomId {
-		http.Error(w, "Room id does not match session", http.StatusForbidden)
-		return
-	}
-
-	if existingSession.IsExpired() {
-		http.Error(w, "Session expired", http.StatusUnauthorized)
+		switch {
+		case errors.Is(err, session.ErrNoSession):
+			http.Error(w, "Cookie not found", http.StatusNotFound)
+		case errors.Is(err, session.ErrSessionUnknown):
+			http.Error(w, "Session not found in database", http.StatusNotFound)
+		default:
+			session.WriteError(w, err)
+		}
 		return
 	}
 
@@ -99,11 +36,15 @@ func GetSessionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existingSession.Refresh(session.TTL)
+	existingSession.Refresh(config.Cfg.Session.MembershipTTL)
 	if err := db.UpdateSession(existingSession); err != nil {
 		http.Error(w, "Failed to update session", http.StatusInternalServerError)
 		return
 	}
+	if err := session.SetCookie(w, existingSession.Id); err != nil {
+		http.Error(w, "Failed to issue session token", http.StatusInternalServerError)
+		return
+	}
 
 	response := map[string]interface{}{
 		"session": existingSession.ToJSON(),
@@ -113,33 +54,40 @@ func GetSessionHandler(w http.ResponseWriter, r *http.Request) {
 	utils.PrepareJSONResponse(w, http.StatusOK, response)
 }
 
+// DeleteSessionHandler ends the session's membership in the room given by
+// the roomId query parameter, or the whole session when it is omitted. A
+// token that no longer verifies has nothing left to revoke, so the cookie is
+// simply cleared.
 func DeleteSessionHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("sessionId")
-	if err != nil {
+	claims, err := session.ClaimsFromRequest(r)
+	if errors.Is(err, session.ErrNoSession) {
 		http.Error(w, "Session not found", http.StatusNotFound)
 		return
 	}
+	if err != nil {
+		session.ClearCookie(w)
+		utils.PrepareJSONResponse(w, http.StatusOK, []byte("OK"))
+		return
+	}
 
-	sessionID := cookie.Value
-	if sessionID == "" {
-		http.Error(w, "Session Id is required", http.StatusBadRequest)
+	roomId := r.URL.Query().Get("roomId")
+	if roomId == "" {
+		if err := session.GlobalManager.DeleteSession(claims.SessionId); err != nil {
+			http.Error(w, "Failed to delete session", http.StatusInternalServerError)
+			return
+		}
+		session.ClearCookie(w)
+		utils.PrepareJSONResponse(w, http.StatusOK, []byte("OK"))
 		return
 	}
 
-	if err := session.GlobalManager.DeleteSession(sessionID); err != nil {
+	if err := session.GlobalManager.LeaveRoom(claims.SessionId, roomId); err != nil {
 		http.Error(w, "Failed to delete session", http.StatusInternalServerError)
 		return
 	}
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "sessionId",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		MaxAge:   -1,
-		Secure:   config.Cfg.Cookie.Secure,
-		SameSite: config.Cfg.Cookie.SameSite,
-	})
-
+	if err := session.SetCookie(w, claims.SessionId); err != nil {
+		http.Error(w, "Failed to issue session token", http.StatusInternalServerError)
+		return
+	}
 	utils.PrepareJSONResponse(w, http.StatusOK, []byte("OK"))
 }
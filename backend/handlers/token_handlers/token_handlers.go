@@ -0,0 +1,65 @@
+package token_handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/scrum-poker/backend/logic/token_logic"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/utils"
+)
+
+type IssueTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	RoomId        string   `json:"roomId"`
+	ExpiresInDays int      `json:"expiresInDays"`
+}
+
+type IssueTokenResponse struct {
+	*models.APIToken
+	Token string `json:"token"`
+}
+
+// IssueTokenHandler creates an API token for the signed-in account. The
+// token value is only returned in this response.
+func IssueTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req IssueTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.PrepareErrorResponse(w, models.ValidationError{Message: "Invalid request body"})
+		return
+	}
+
+	token, secret, err := token_logic.IssueToken(session.AccountIdFromRequest(r), req.Name, req.Scopes, req.RoomId, req.ExpiresInDays)
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	utils.PrepareJSONResponse(w, http.StatusCreated, IssueTokenResponse{
+		APIToken: token,
+		Token:    secret,
+	})
+}
+
+func ListTokensHandler(w http.ResponseWriter, r *http.Request) {
+	tokens, err := token_logic.ListTokens(session.AccountIdFromRequest(r))
+	if err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	utils.PrepareJSONResponse(w, http.StatusOK, tokens)
+}
+
+func RevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	tokenId := mux.Vars(r)["tokenId"]
+	if err := token_logic.RevokeToken(session.AccountIdFromRequest(r), tokenId); err != nil {
+		utils.PrepareErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
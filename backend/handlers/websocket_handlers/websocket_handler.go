@@ -1,32 +1,23 @@
 package websocket_handlers
 
 import (
+	"net/http"
+
 	"github.com/gorilla/mux"
-	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/session"
 	"github.com/scrum-poker/backend/websocket"
-	"net/http"
 )
 
+// WebSocketHandler connects the caller to the room as the participant their
+// session holds the membership of.
 func WebSocketHandler(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	roomId := vars["roomId"]
+	roomId := mux.Vars(r)["roomId"]
 
-	userId := r.URL.Query().Get("userId")
-	if userId == "" {
-		http.Error(w, "User Id is required", http.StatusBadRequest)
-		return
-	}
-
-	room, err := db.GetRoom(roomId)
+	currSession, err := session.FromRequest(r, roomId)
 	if err != nil {
-		http.Error(w, "Room not found", http.StatusNotFound)
-		return
-	}
-
-	if _, ok := room.Participants[userId]; !ok {
-		http.Error(w, "User not in room", http.StatusForbidden)
+		session.WriteError(w, err)
 		return
 	}
 
-	websocket.ServeWs(websocket.GlobalHub, w, r, roomId, userId)
+	websocket.ServeWs(websocket.GlobalHub, w, r, roomId, currSession.UserId)
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/logic/room_logic"
+)
+
+const importUsage = "usage: scrum-poker import stories [flags] <roomId>"
+
+// runImportCommand implements `import stories`, which appends stories to a
+// room, one title per line. Blank lines are skipped.
+func runImportCommand(args []string) error {
+	roomId, input, err := parseImportArgs(args)
+	if err != nil {
+		return err
+	}
+
+	titles, err := readStoryTitles(input)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Open(); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stories, err := room_logic.AddStories(roomId, titles)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d stories into room %s\n", len(stories), roomId)
+	return nil
+}
+
+// parseImportArgs loads the configuration for `import stories` and returns
+// the room to add stories to and the file to read, if not standard input.
+func parseImportArgs(args []string) (string, string, error) {
+	if len(args) == 0 || args[0] != "stories" {
+		return "", "", errors.New(importUsage)
+	}
+
+	var input string
+	rest, err := loadCommand("import stories", args[1:], func(fs *flag.FlagSet) {
+		fs.StringVar(&input, "f", "", "read the titles from this file instead of standard input")
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if len(rest) != 1 {
+		return "", "", errors.New(importUsage)
+	}
+	return rest[0], input, nil
+}
+
+func readStoryTitles(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var titles []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if title := strings.TrimSpace(scanner.Text()); title != "" {
+			titles = append(titles, title)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return titles, nil
+}
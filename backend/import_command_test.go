@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImportArguments(t *testing.T) {
+	useCommandEnv(t)
+
+	roomId, input, err := parseImportArgs([]string{"stories", "-f", "backlog.txt", "room-1"})
+	if err != nil || roomId != "room-1" || input != "backlog.txt" {
+		t.Errorf("import stories -f backlog.txt room-1 = %q, %q, %v", roomId, input, err)
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"story", "room-1"},
+		{"stories"},
+		{"stories", "room-1", "room-2"},
+	} {
+		if _, _, err := parseImportArgs(args); err == nil {
+			t.Errorf("parseImportArgs(%q) was accepted", args)
+		}
+	}
+}
+
+func TestReadStoryTitlesSkipsBlankLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backlog.txt")
+	content := "Login page\n\n  Checkout  \r\n\t\nSearch"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write backlog: %v", err)
+	}
+
+	titles, err := readStoryTitles(path)
+	if err != nil {
+		t.Fatalf("readStoryTitles failed: %v", err)
+	}
+	if want := []string{"Login page", "Checkout", "Search"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("readStoryTitles() = %q, want %q", titles, want)
+	}
+
+	if _, err := readStoryTitles(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("readStoryTitles read a file that does not exist")
+	}
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/scrum-poker/backend/config"
+)
+
+type contextKey struct{}
+
+// Init installs the process-wide logger configured by config.Cfg.Log. Output
+// of the standard log package, including that of dependencies, goes through
+// it as well.
+func Init() {
+	cfg := config.Cfg.Log
+	opts := &slog.HandlerOptions{Level: cfg.SlogLevel()}
+
+	var handler slog.Handler
+	if cfg.Format == config.LogFormatJSON {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+}
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// FromRequest returns the logger of r, which carries its request ID and,
+// on room routes, the room ID.
+func FromRequest(r *http.Request) *slog.Logger {
+	return FromContext(r.Context())
+}
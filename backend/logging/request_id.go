@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+// Middleware gives every request an ID, taken from X-Request-ID when a proxy
+// in front already assigned one, and echoes it in the response. The request
+// context carries a logger with the ID and, on routes with a roomId, the room
+// ID, so every line logged while serving the request can be correlated.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get(RequestIDHeader)
+		if !isValidRequestID(requestId) {
+			requestId = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestId)
+
+		attrs := []interface{}{"request_id", requestId}
+		if roomId := mux.Vars(r)["roomId"]; roomId != "" {
+			attrs = append(attrs, "room_id", roomId)
+		}
+		logger := slog.Default().With(attrs...)
+
+		next.ServeHTTP(w, r.WithContext(WithLogger(r.Context(), logger)))
+	})
+}
+
+// isValidRequestID accepts short IDs made of printable ASCII without spaces,
+// which covers UUIDs and the formats common proxies generate.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package account_logic
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/validation"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against when an email is unknown, so that a failed
+// login takes as long whether or not the account exists.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("scrum-poker"), bcrypt.DefaultCost)
+
+var errInvalidCredentials = models.UnauthorizedError{Message: "Invalid email or password"}
+
+func Register(email, password, displayName string) (*models.Account, error) {
+	email, err := validation.Email("email", email)
+	if err != nil {
+		return nil, err
+	}
+	if err := validation.Password("password", password); err != nil {
+		return nil, err
+	}
+	displayName, err = validation.UserName("displayName", displayName)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	account := models.NewAccount(uuid.New().String(), email, displayName, string(hash))
+	if err := db.CreateAccount(account); err != nil {
+		if errors.Is(err, db.ErrEmailTaken) {
+			return nil, models.ValidationError{
+				Field:   "email",
+				Message: "An account with this email already exists",
+			}
+		}
+		return nil, models.DatabaseError{
+			Operation: "CreateAccount",
+			Message:   "Failed to create account",
+		}
+	}
+	return account, nil
+}
+
+func Authenticate(email, password string) (*models.Account, error) {
+	account, err := db.GetAccountByEmail(normalizeEmail(email))
+	if err != nil {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, errInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(password)); err != nil {
+		return nil, errInvalidCredentials
+	}
+	return account, nil
+}
+
+func GetAccount(accountId string) (*models.Account, error) {
+	if accountId == "" {
+		return nil, models.UnauthorizedError{Message: "Not signed in"}
+	}
+
+	account, err := db.GetAccount(accountId)
+	if err != nil {
+		return nil, models.NotFoundError{
+			Resource: "Account",
+			Message:  "Account not found",
+		}
+	}
+	return account, nil
+}
+
+// ChangePassword replaces the account's password after checking the current
+// one, and signs the account out of every other session.
+func ChangePassword(accountId, sessionId, currentPassword, newPassword string) error {
+	account, err := GetAccount(accountId)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(account.PasswordHash), []byte(currentPassword)); err != nil {
+		return models.ValidationError{
+			Field:   "currentPassword",
+			Message: "Current password is incorrect",
+		}
+	}
+	if err := validation.Password("newPassword", newPassword); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	account.PasswordHash = string(hash)
+	account.UpdatedAt = time.Now()
+	if err := db.UpdateAccountPassword(account); err != nil {
+		return models.DatabaseError{
+			Operation: "UpdateAccountPassword",
+			Message:   "Failed to change password",
+		}
+	}
+
+	if err := db.SignOutAccountSessions(account.Id, sessionId); err != nil {
+		return models.DatabaseError{
+			Operation: "SignOutAccountSessions",
+			Message:   "Failed to sign out other sessions",
+		}
+	}
+	return nil
+}
+
+func normalizeEmail(email string) string {
+	normalized, err := validation.Email("email", email)
+	if err != nil {
+		return email
+	}
+	return normalized
+}
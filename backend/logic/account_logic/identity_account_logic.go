@@ -0,0 +1,111 @@
+package account_logic
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/validation"
+)
+
+// identityStore holds the accounts SignInWithIdentity looks up, links and
+// creates.
+type identityStore interface {
+	GetAccountByIdentity(issuer, subject string) (*models.Account, error)
+	GetAccountByEmail(email string) (*models.Account, error)
+	CreateAccount(account *models.Account) error
+	LinkAccountIdentity(issuer, subject, accountId string) error
+	UpdateAccountProfile(account *models.Account) error
+}
+
+type dbIdentityStore struct{}
+
+func (dbIdentityStore) GetAccountByIdentity(issuer, subject string) (*models.Account, error) {
+	return db.GetAccountByIdentity(issuer, subject)
+}
+
+func (dbIdentityStore) GetAccountByEmail(email string) (*models.Account, error) {
+	return db.GetAccountByEmail(email)
+}
+
+func (dbIdentityStore) CreateAccount(account *models.Account) error {
+	return db.CreateAccount(account)
+}
+
+func (dbIdentityStore) LinkAccountIdentity(issuer, subject, accountId string) error {
+	return db.LinkAccountIdentity(issuer, subject, accountId)
+}
+
+func (dbIdentityStore) UpdateAccountProfile(account *models.Account) error {
+	return db.UpdateAccountProfile(account)
+}
+
+// identities is the store SignInWithIdentity uses; tests swap in one kept
+// in memory.
+var identities identityStore = dbIdentityStore{}
+
+// SignInWithIdentity returns the account linked to the provider subject,
+// linking or creating one on first sign-in. An existing local account is only
+// linked when the provider vouches for its email address, so an account
+// cannot be taken over by registering its address with the provider.
+func SignInWithIdentity(identity *models.ExternalIdentity) (*models.Account, error) {
+	if identity.Subject == "" {
+		return nil, models.UnauthorizedError{Message: "The identity provider did not return a subject"}
+	}
+
+	email, err := validation.Email("email", identity.Email)
+	if err != nil {
+		return nil, models.UnauthorizedError{Message: "The identity provider did not return a valid email address"}
+	}
+
+	name, err := validation.UserName("displayName", identity.Name)
+	if err != nil {
+		name = email[:strings.Index(email, "@")]
+	}
+
+	if account, err := identities.GetAccountByIdentity(identity.Issuer, identity.Subject); err == nil {
+		return refreshAccount(account, name, identity.EmailVerified && account.Email == email)
+	}
+
+	if account, err := identities.GetAccountByEmail(email); err == nil {
+		if !identity.EmailVerified {
+			return nil, models.ForbiddenError{Message: "An account with this email already exists; sign in with its password"}
+		}
+		if err := identities.LinkAccountIdentity(identity.Issuer, identity.Subject, account.Id); err != nil {
+			return nil, models.DatabaseError{Operation: "LinkAccountIdentity", Message: "Failed to link account"}
+		}
+		return refreshAccount(account, name, true)
+	}
+
+	// Accounts created through the provider have no password and can only
+	// sign in through it.
+	account := models.NewAccount(uuid.New().String(), email, name, "")
+	account.EmailVerified = identity.EmailVerified
+	if err := identities.CreateAccount(account); err != nil {
+		if errors.Is(err, db.ErrEmailTaken) {
+			return nil, models.ForbiddenError{Message: "An account with this email already exists"}
+		}
+		return nil, models.DatabaseError{Operation: "CreateAccount", Message: "Failed to create account"}
+	}
+	if err := identities.LinkAccountIdentity(identity.Issuer, identity.Subject, account.Id); err != nil {
+		return nil, models.DatabaseError{Operation: "LinkAccountIdentity", Message: "Failed to link account"}
+	}
+	return account, nil
+}
+
+func refreshAccount(account *models.Account, name string, emailVerified bool) (*models.Account, error) {
+	if account.DisplayName == name && account.EmailVerified == emailVerified {
+		return account, nil
+	}
+
+	account.DisplayName = name
+	account.EmailVerified = emailVerified
+	account.UpdatedAt = time.Now()
+	if err := identities.UpdateAccountProfile(account); err != nil {
+		return nil, models.DatabaseError{Operation: "UpdateAccountProfile", Message: "Failed to update account"}
+	}
+	return account, nil
+}
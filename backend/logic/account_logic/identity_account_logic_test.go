@@ -0,0 +1,223 @@
+package account_logic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/models"
+)
+
+type memoryIdentityStore struct {
+	accounts map[string]*models.Account
+	// links maps issuer and subject to an account id.
+	links map[[2]string]string
+}
+
+func newMemoryIdentityStore(accounts ...*models.Account) *memoryIdentityStore {
+	store := &memoryIdentityStore{
+		accounts: make(map[string]*models.Account),
+		links:    make(map[[2]string]string),
+	}
+	for _, account := range accounts {
+		store.accounts[account.Id] = account
+	}
+	return store
+}
+
+func (s *memoryIdentityStore) GetAccountByIdentity(issuer, subject string) (*models.Account, error) {
+	if id, ok := s.links[[2]string{issuer, subject}]; ok {
+		return s.accounts[id], nil
+	}
+	return nil, errors.New("account not found")
+}
+
+func (s *memoryIdentityStore) GetAccountByEmail(email string) (*models.Account, error) {
+	for _, account := range s.accounts {
+		if account.Email == email {
+			return account, nil
+		}
+	}
+	return nil, errors.New("account not found")
+}
+
+func (s *memoryIdentityStore) CreateAccount(account *models.Account) error {
+	if _, err := s.GetAccountByEmail(account.Email); err == nil {
+		return db.ErrEmailTaken
+	}
+	s.accounts[account.Id] = account
+	return nil
+}
+
+func (s *memoryIdentityStore) LinkAccountIdentity(issuer, subject, accountId string) error {
+	s.links[[2]string{issuer, subject}] = accountId
+	return nil
+}
+
+func (s *memoryIdentityStore) UpdateAccountProfile(account *models.Account) error {
+	s.accounts[account.Id] = account
+	return nil
+}
+
+func useIdentityStore(t *testing.T, store identityStore) {
+	t.Helper()
+	previous := identities
+	identities = store
+	t.Cleanup(func() { identities = previous })
+}
+
+const testIssuer = "https://issuer.example.com"
+
+func TestSignInWithIdentityLinksExistingAccount(t *testing.T) {
+	tests := []struct {
+		name          string
+		emailVerified bool
+		wantLinked    bool
+		wantErr       bool
+	}{
+		{name: "verified email links the account", emailVerified: true, wantLinked: true},
+		{name: "unverified email is refused", emailVerified: false, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := models.NewAccount("account-1", "alice@example.com", "Alice", "hash")
+			store := newMemoryIdentityStore(existing)
+			useIdentityStore(t, store)
+
+			account, err := SignInWithIdentity(&models.ExternalIdentity{
+				Issuer:        testIssuer,
+				Subject:       "subject-1",
+				Email:         "Alice@Example.com",
+				EmailVerified: tt.emailVerified,
+				Name:          "Alice",
+			})
+
+			if tt.wantErr {
+				var forbidden models.ForbiddenError
+				if !errors.As(err, &forbidden) {
+					t.Fatalf("SignInWithIdentity() error = %v, want ForbiddenError", err)
+				}
+			} else if err != nil {
+				t.Fatalf("SignInWithIdentity() error = %v", err)
+			} else if account.Id != existing.Id {
+				t.Errorf("SignInWithIdentity() account = %q, want %q", account.Id, existing.Id)
+			}
+
+			_, linked := store.links[[2]string{testIssuer, "subject-1"}]
+			if linked != tt.wantLinked {
+				t.Errorf("identity linked = %v, want %v", linked, tt.wantLinked)
+			}
+			if len(store.accounts) != 1 {
+				t.Errorf("accounts = %d, want 1", len(store.accounts))
+			}
+		})
+	}
+}
+
+func TestSignInWithIdentityCreatesAccount(t *testing.T) {
+	tests := []struct {
+		name          string
+		emailVerified bool
+	}{
+		{name: "verified email", emailVerified: true},
+		{name: "unverified email", emailVerified: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryIdentityStore()
+			useIdentityStore(t, store)
+
+			account, err := SignInWithIdentity(&models.ExternalIdentity{
+				Issuer:        testIssuer,
+				Subject:       "subject-1",
+				Email:         "bob@example.com",
+				EmailVerified: tt.emailVerified,
+			})
+			if err != nil {
+				t.Fatalf("SignInWithIdentity() error = %v", err)
+			}
+
+			if account.EmailVerified != tt.emailVerified {
+				t.Errorf("EmailVerified = %v, want %v", account.EmailVerified, tt.emailVerified)
+			}
+			if account.PasswordHash != "" {
+				t.Error("account created through the provider has a password")
+			}
+			if account.DisplayName != "bob" {
+				t.Errorf("DisplayName = %q, want the email's local part", account.DisplayName)
+			}
+			if store.links[[2]string{testIssuer, "subject-1"}] != account.Id {
+				t.Error("identity was not linked to the new account")
+			}
+		})
+	}
+}
+
+func TestSignInWithIdentityReturnsLinkedAccount(t *testing.T) {
+	tests := []struct {
+		name          string
+		email         string
+		emailVerified bool
+		wantVerified  bool
+	}{
+		{name: "verified email stays verified", email: "carol@example.com", emailVerified: true, wantVerified: true},
+		{name: "unverified email is no longer verified", email: "carol@example.com", emailVerified: false, wantVerified: false},
+		{name: "changed email is not verified", email: "carol@other.example.com", emailVerified: true, wantVerified: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := models.NewAccount("account-1", "carol@example.com", "Carol", "")
+			existing.EmailVerified = true
+			store := newMemoryIdentityStore(existing)
+			store.links[[2]string{testIssuer, "subject-1"}] = existing.Id
+			useIdentityStore(t, store)
+
+			account, err := SignInWithIdentity(&models.ExternalIdentity{
+				Issuer:        testIssuer,
+				Subject:       "subject-1",
+				Email:         tt.email,
+				EmailVerified: tt.emailVerified,
+				Name:          "Carol C.",
+			})
+			if err != nil {
+				t.Fatalf("SignInWithIdentity() error = %v", err)
+			}
+
+			if account.Id != existing.Id {
+				t.Errorf("account = %q, want %q", account.Id, existing.Id)
+			}
+			if account.EmailVerified != tt.wantVerified {
+				t.Errorf("EmailVerified = %v, want %v", account.EmailVerified, tt.wantVerified)
+			}
+			if account.DisplayName != "Carol C." {
+				t.Errorf("DisplayName = %q, want %q", account.DisplayName, "Carol C.")
+			}
+		})
+	}
+}
+
+func TestSignInWithIdentityRejectsIncompleteIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity models.ExternalIdentity
+	}{
+		{name: "missing subject", identity: models.ExternalIdentity{Issuer: testIssuer, Email: "dave@example.com"}},
+		{name: "missing email", identity: models.ExternalIdentity{Issuer: testIssuer, Subject: "subject-1"}},
+		{name: "invalid email", identity: models.ExternalIdentity{Issuer: testIssuer, Subject: "subject-1", Email: "dave"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useIdentityStore(t, newMemoryIdentityStore())
+
+			_, err := SignInWithIdentity(&tt.identity)
+			var unauthorized models.UnauthorizedError
+			if !errors.As(err, &unauthorized) {
+				t.Errorf("SignInWithIdentity() error = %v, want UnauthorizedError", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package admin_logic
+
+import (
+	"sort"
+
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/logic/room_logic"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/validation"
+)
+
+const defaultCloseReason = "The room was closed by an operator"
+
+// Hub is the part of the real-time hub operators act through.
+type Hub interface {
+	Broadcast(roomId string, msg *models.Message)
+	GetConnectedUserIds(roomId string) []string
+	ConnectedRoomIds() []string
+	CloseRoom(roomId, reason string) int
+	DisconnectUser(roomId, userId string) int
+}
+
+// ListRooms returns every room, oldest first, with how many of its
+// participants are connected.
+func ListRooms(hub Hub) ([]models.RoomSummary, error) {
+	rooms, err := db.GetAllRooms()
+	if err != nil {
+		return nil, models.DatabaseError{
+			Operation: "GetAllRooms",
+			Message:   "Failed to list rooms",
+		}
+	}
+
+	summaries := make([]models.RoomSummary, 0, len(rooms))
+	for _, room := range rooms {
+		summaries = append(summaries, models.RoomSummary{
+			Id:           room.Id,
+			Name:         room.Name,
+			CreatedAt:    room.CreatedAt,
+			ScrumMaster:  room.ScrumMaster,
+			Participants: len(room.Participants),
+			Online:       len(onlineParticipants(hub, room)),
+			Persistent:   room.Persistent,
+			ArchivedAt:   room.ArchivedAt,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].CreatedAt.Before(summaries[j].CreatedAt)
+	})
+	return summaries, nil
+}
+
+func InspectRoom(hub Hub, roomId string) (*models.RoomInspection, error) {
+	room, err := getRoom(roomId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RoomInspection{
+		Room:             room.ToJSON(),
+		OwnerAccountId:   room.OwnerAccountId,
+		ConnectedUserIds: onlineParticipants(hub, room),
+	}, nil
+}
+
+// CloseRoom disconnects everyone in the room, telling them why, and deletes
+// it along with its participants.
+func CloseRoom(hub Hub, roomId, reason string) error {
+	reason, err := validation.CloseReason("reason", reason)
+	if err != nil {
+		return err
+	}
+	if reason == "" {
+		reason = defaultCloseReason
+	}
+
+	if _, err := getRoom(roomId); err != nil {
+		return err
+	}
+
+	// Participants are read again under the room's lock, so that nobody who
+	// joins meanwhile is left behind without a room.
+	err = db.InTx(func(tx *db.Tx) error {
+		room, err := tx.GetRoomForUpdate(roomId)
+		if err != nil {
+			return err
+		}
+		for userId := range room.Participants {
+			if err := tx.DeleteUser(userId); err != nil {
+				return err
+			}
+		}
+		return tx.DeleteRoom(roomId)
+	})
+	if err != nil {
+		return models.DatabaseError{
+			Operation: "DeleteRoom",
+			Message:   "Failed to delete room",
+		}
+	}
+	hub.CloseRoom(roomId, reason)
+	return nil
+}
+
+// EvictUser removes userId from the room as if they had left, and closes
+// their connections. They may join again unless the room's access settings
+// keep them out.
+func EvictUser(hub Hub, roomId, userId string) error {
+	room, err := getRoom(roomId)
+	if err != nil {
+		return err
+	}
+	if _, ok := room.Participants[userId]; !ok {
+		return models.NotFoundError{
+			Resource: "User",
+			Message:  "User is not in the room",
+		}
+	}
+
+	if err := room_logic.LeaveRoom(roomId, userId, hub.Broadcast); err != nil {
+		return models.DatabaseError{
+			Operation: "LeaveRoom",
+			Message:   "Failed to remove user from room",
+		}
+	}
+
+	hub.Broadcast(roomId, &models.Message{
+		Action:  models.ActionTypeLeave,
+		Payload: &models.UserPayload{UserId: userId},
+	})
+	hub.DisconnectUser(roomId, userId)
+	return nil
+}
+
+// BroadcastNotice shows message in every room that has someone connected.
+func BroadcastNotice(hub Hub, message string) (*models.NoticeResult, error) {
+	message, err := validation.NoticeMessage("message", message)
+	if err != nil {
+		return nil, err
+	}
+
+	notice := &models.Message{
+		Action:  models.ActionTypeNotice,
+		Payload: &models.NoticePayload{Message: message},
+	}
+	roomIds := hub.ConnectedRoomIds()
+	for _, roomId := range roomIds {
+		hub.Broadcast(roomId, notice)
+	}
+	return &models.NoticeResult{Rooms: len(roomIds)}, nil
+}
+
+// RunSessionCleanup sweeps expired sessions without waiting for the next
+// scheduled sweep.
+func RunSessionCleanup() *models.CleanupReport {
+	return session.GlobalManager.RunCleanup()
+}
+
+func getRoom(roomId string) (*models.Room, error) {
+	room, err := db.GetRoom(roomId)
+	if err != nil {
+		return nil, models.NotFoundError{
+			Resource: "Room",
+			Message:  "Room not found",
+		}
+	}
+	return room, nil
+}
+
+// onlineParticipants returns the participants of room with a connection,
+// each once.
+func onlineParticipants(hub Hub, room *models.Room) []string {
+	online := []string{}
+	seen := make(map[string]bool)
+	for _, userId := range hub.GetConnectedUserIds(room.Id) {
+		if _, ok := room.Participants[userId]; ok && !seen[userId] {
+			seen[userId] = true
+			online = append(online, userId)
+		}
+	}
+	sort.Strings(online)
+	return online
+}
@@ -1,194 +1,256 @@
 package message_logic
 
 import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/scrum-poker/backend/config"
 	"github.com/scrum-poker/backend/db"
 	"github.com/scrum-poker/backend/logic/room_logic"
 	"github.com/scrum-poker/backend/logic/user_logic"
 	"github.com/scrum-poker/backend/logic/vote_logic"
+	"github.com/scrum-poker/backend/metrics"
 	"github.com/scrum-poker/backend/models"
-	"log"
 )
 
-func ProcessMessage(broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
+// ProcessMessage handles a message sent by senderId, the participant the
+// caller's session holds the membership of; it must come from
+// session.FromRequest, never from the client. Payloads naming another user
+// are rejected, so that clients cannot act on behalf of each other. logger
+// should identify the room and sender; the action is added here.
+func ProcessMessage(logger *slog.Logger, broadcastFunc models.BroadcastFunc, roomId, senderId string, msg *models.Message) {
+	metrics.CountMessageIn(msg.Action)
+	logger = logger.With("action", msg.Action)
+
+	if models.IsExtensionAction(msg.Action) {
+		handleExtension(logger, broadcastFunc, roomId, senderId, msg)
+		return
+	}
+
+	if !isAttributedTo(msg, senderId) {
+		logger.Warn("Dropping message that names another user")
+		return
+	}
+
 	switch msg.Action {
 	case models.ActionTypeSubmit:
-		handleSubmitVote(broadcastFunc, roomId, msg)
+		handleSubmitVote(logger, broadcastFunc, roomId, msg)
 	case models.ActionTypeReveal:
-		handleRevealVotes(broadcastFunc, roomId, msg)
+		handleRevealVotes(logger, broadcastFunc, roomId, msg)
 	case models.ActionTypeReset:
-		handleResetVotes(broadcastFunc, roomId, msg)
+		handleResetVotes(logger, broadcastFunc, roomId, msg)
 	case models.ActionTypeTransfer:
-		handleTransferScrumMaster(broadcastFunc, roomId, msg)
+		handleTransferScrumMaster(logger, broadcastFunc, roomId, msg)
 	case models.ActionTypeRename:
-		handleRenameUser(broadcastFunc, roomId, msg)
+		handleRenameUser(logger, broadcastFunc, roomId, senderId, msg)
 	case models.ActionTypeLeave:
-		handleLeaveRoom(broadcastFunc, roomId, msg)
+		handleLeaveRoom(logger, broadcastFunc, roomId, msg)
 	case models.ActionTypePing:
-		pongMsg := &models.Message{
-			Action:  models.ActionTypePong,
-			Payload: msg.Payload,
-		}
-		broadcastFunc(roomId, pongMsg)
+		handlePing(logger, broadcastFunc, roomId, msg)
 	default:
-		broadcastFunc(roomId, msg)
+		logger.Warn("Dropping message with unsupported action")
 	}
 }
 
-func handleSubmitVote(broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
-	payload, ok := msg.Payload.(map[string]interface{})
+func handleSubmitVote(logger *slog.Logger, broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
+	payload, ok := msg.Payload.(*models.SubmitPayload)
 	if !ok {
-		log.Printf("Invalid payload format for submit vote")
+		logger.Warn("Invalid payload format for submit vote")
 		return
 	}
 
-	userId, ok := payload["userId"].(string)
-	if !ok || userId == "" {
-		log.Printf("Invalid or missing userId in submit vote payload")
+	if payload.UserId == "" {
+		logger.Warn("Invalid or missing userId in submit vote payload")
 		return
 	}
 
-	voteValue, ok := payload["vote"].(string)
-	if !ok {
-		log.Printf("Invalid vote value")
-		return
-	}
-
-	err := vote_logic.SubmitVote(userId, roomId, voteValue)
+	err := vote_logic.SubmitVote(payload.UserId, roomId, payload.Vote)
 	if err != nil {
-		log.Printf("Failed to submit vote: %v", err)
+		logger.Warn("Failed to submit vote", "error", err)
 		return
 	}
 
 	submitMsg := &models.Message{
 		Action: models.ActionTypeSubmit,
-		Payload: map[string]interface{}{
-			"userId": userId,
-			"vote":   voteValue,
+		Payload: &models.SubmitPayload{
+			UserId: payload.UserId,
+			Vote:   payload.Vote,
 		},
 	}
 	broadcastFunc(roomId, submitMsg)
 }
 
-func handleRevealVotes(broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
-	payload, ok := msg.Payload.(map[string]interface{})
+func handleRevealVotes(logger *slog.Logger, broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
+	payload, ok := msg.Payload.(*models.UserPayload)
 	if !ok {
-		log.Printf("Invalid payload format for reveal votes")
+		logger.Warn("Invalid payload format for reveal votes")
 		return
 	}
 
-	userId, ok := payload["userId"].(string)
-	if !ok || userId == "" {
-		log.Printf("Invalid or missing userId in reveal votes payload")
+	if payload.UserId == "" {
+		logger.Warn("Invalid or missing userId in reveal votes payload")
 		return
 	}
 
 	room, err := db.GetRoom(roomId)
 	if err != nil {
-		log.Printf("Room not found: %v", err)
+		logger.Warn("Room not found", "error", err)
 		return
 	}
 
-	if room.ScrumMaster != userId {
-		log.Printf("Only the Scrum Master can reveal votes")
+	if room.ScrumMaster != payload.UserId {
+		logger.Warn("Only the Scrum Master can reveal votes")
 		return
 	}
 
 	revealMsg := &models.Message{
 		Action: models.ActionTypeReveal,
-		Payload: map[string]interface{}{
-			"votes": room.Votes,
+		Payload: &models.RevealedVotesPayload{
+			Votes: room.Votes,
 		},
 	}
 	broadcastFunc(roomId, revealMsg)
 }
 
-func handleResetVotes(broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
-	payload, ok := msg.Payload.(map[string]interface{})
+func handleResetVotes(logger *slog.Logger, broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
+	payload, ok := msg.Payload.(*models.UserPayload)
 	if !ok {
-		log.Printf("Invalid payload format for reset votes")
+		logger.Warn("Invalid payload format for reset votes")
 		return
 	}
 
-	userId, ok := payload["userId"].(string)
-	if !ok || userId == "" {
-		log.Printf("Invalid or missing userId in reset votes payload")
+	if payload.UserId == "" {
+		logger.Warn("Invalid or missing userId in reset votes payload")
 		return
 	}
-	if err := vote_logic.ResetVotes(userId, roomId); err != nil {
-		log.Printf("Failed to reset votes: %v", err)
+
+	if err := vote_logic.ResetVotes(payload.UserId, roomId); err != nil {
+		logger.Warn("Failed to reset votes", "error", err)
 		return
 	}
+
 	broadcastFunc(roomId, msg)
 }
 
-func handleTransferScrumMaster(broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
-	payload, ok := msg.Payload.(map[string]interface{})
+func handleTransferScrumMaster(logger *slog.Logger, broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
+	payload, ok := msg.Payload.(*models.TransferPayload)
 	if !ok {
-		log.Printf("Invalid payload format for transfer scrum master")
+		logger.Warn("Invalid payload format for transfer scrum master")
 		return
 	}
 
-	userId, ok := payload["userId"].(string)
-	if !ok || userId == "" {
-		log.Printf("Invalid or missing userId in transfer scrum master payload")
+	if payload.UserId == "" {
+		logger.Warn("Invalid or missing userId in transfer scrum master payload")
 		return
 	}
 
-	newScrumMasterId, ok := payload["newScrumMasterId"].(string)
-	if !ok || newScrumMasterId == "" {
-		log.Printf("Invalid or missing newScrumMasterId in transfer scrum master payload")
+	if payload.NewScrumMasterId == "" {
+		logger.Warn("Invalid or missing newScrumMasterId in transfer scrum master payload")
 		return
 	}
-	if err := room_logic.TransferScrumMaster(userId, roomId, newScrumMasterId); err != nil {
-		log.Printf("Failed to transfer scrum master: %v", err)
+	if err := room_logic.TransferScrumMaster(payload.UserId, roomId, payload.NewScrumMasterId); err != nil {
+		logger.Warn("Failed to transfer scrum master", "error", err)
 		return
 	}
 
 	broadcastFunc(roomId, msg)
 }
 
-func handleRenameUser(broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
-	payload, ok := msg.Payload.(map[string]interface{})
+func handleRenameUser(logger *slog.Logger, broadcastFunc models.BroadcastFunc, roomId, senderId string, msg *models.Message) {
+	payload, ok := msg.Payload.(*models.RenamePayload)
 	if !ok {
-		log.Printf("Invalid payload format for rename user")
+		logger.Warn("Invalid payload format for rename user")
+		return
+	}
+
+	if payload.UserId == "" {
+		logger.Warn("Invalid or missing userId in rename user payload")
+		return
+	}
+
+	name, err := user_logic.RenameUser(senderId, payload.UserId, roomId, payload.Name)
+	if err != nil {
+		logger.Warn("Failed to rename user", "error", err)
 		return
 	}
 
-	userId, ok := payload["userId"].(string)
-	if !ok || userId == "" {
-		log.Printf("Invalid or missing userId in rename user payload")
+	renameMsg := &models.Message{
+		Action: models.ActionTypeRename,
+		Payload: &models.RenamePayload{
+			UserId: payload.UserId,
+			Name:   name,
+		},
+	}
+	broadcastFunc(roomId, renameMsg)
+}
+
+func handleLeaveRoom(logger *slog.Logger, broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
+	payload, ok := msg.Payload.(*models.UserPayload)
+	if !ok {
+		logger.Warn("Invalid payload format for leave room")
 		return
 	}
 
-	name, ok := payload["name"].(string)
-	if !ok || name == "" {
-		log.Printf("Invalid or missing name in rename user payload")
+	if payload.UserId == "" {
+		logger.Warn("Invalid or missing userId in leave room payload")
 		return
 	}
-	if err := user_logic.RenameUser(userId, roomId, name); err != nil {
-		log.Printf("Failed to rename user: %v", err)
+
+	if err := room_logic.LeaveRoom(roomId, payload.UserId, broadcastFunc); err != nil {
+		logger.Warn("Failed to leave room", "error", err)
 		return
 	}
+
 	broadcastFunc(roomId, msg)
 }
 
-func handleLeaveRoom(broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
-	payload, ok := msg.Payload.(map[string]interface{})
+func handlePing(logger *slog.Logger, broadcastFunc models.BroadcastFunc, roomId string, msg *models.Message) {
+	payload, ok := msg.Payload.(*models.UserPayload)
 	if !ok {
-		log.Printf("Invalid payload format for leave room")
+		logger.Warn("Invalid payload format for ping")
 		return
 	}
 
-	userId, ok := payload["userId"].(string)
-	if !ok || userId == "" {
-		log.Printf("Invalid or missing userId in leave room payload")
+	pongMsg := &models.Message{
+		Action:  models.ActionTypePong,
+		Payload: payload,
+	}
+	broadcastFunc(roomId, pongMsg)
+}
+
+func handleExtension(logger *slog.Logger, broadcastFunc models.BroadcastFunc, roomId, senderId string, msg *models.Message) {
+	if !config.Cfg.Protocol.AllowsExtension(string(msg.Action)) {
+		logger.Info("Dropping extension action")
 		return
 	}
 
-	if err := room_logic.LeaveRoom(roomId, userId, broadcastFunc); err != nil {
-		log.Printf("Failed to leave room: %v", err)
+	data, ok := msg.Payload.(json.RawMessage)
+	if !ok {
+		logger.Warn("Invalid payload format for extension action")
 		return
 	}
 
-	broadcastFunc(roomId, msg)
+	broadcastFunc(roomId, &models.Message{
+		Action: msg.Action,
+		Payload: &models.ExtensionPayload{
+			From: senderId,
+			Data: data,
+		},
+	})
+}
+
+// isAttributedTo reports whether the user a payload acts for is the sender.
+// Renaming is the exception: the Scrum Master may rename other participants,
+// which user_logic.RenameUser verifies.
+func isAttributedTo(msg *models.Message, senderId string) bool {
+	switch payload := msg.Payload.(type) {
+	case *models.UserPayload:
+		return payload.UserId == senderId
+	case *models.SubmitPayload:
+		return payload.UserId == senderId
+	case *models.TransferPayload:
+		return payload.UserId == senderId
+	default:
+		return true
+	}
 }
@@ -0,0 +1,55 @@
+package message_logic
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/models"
+)
+
+func TestExtensionActionsFollowThePolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		protocol    config.ProtocolConfig
+		action      models.ActionType
+		wantForward bool
+	}{
+		{name: "dropped by default", protocol: config.ProtocolConfig{ExtensionPolicy: config.ExtensionPolicyDrop}, action: "x-timer"},
+		{name: "forwarded", protocol: config.ProtocolConfig{ExtensionPolicy: config.ExtensionPolicyForward}, action: "x-timer", wantForward: true},
+		{name: "forwarded when listed", protocol: config.ProtocolConfig{ExtensionPolicy: config.ExtensionPolicyForward, ExtensionActions: []string{"x-timer"}}, action: "x-timer", wantForward: true},
+		{name: "dropped when not listed", protocol: config.ProtocolConfig{ExtensionPolicy: config.ExtensionPolicyForward, ExtensionActions: []string{"x-timer"}}, action: "x-confetti"},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := config.Cfg
+			t.Cleanup(func() { config.Cfg = previous })
+			config.Cfg.Protocol = tt.protocol
+
+			var sent []*models.Message
+			broadcast := func(roomId string, msg *models.Message) { sent = append(sent, msg) }
+			ProcessMessage(logger, broadcast, "room-1", "user-1", &models.Message{
+				Action:  tt.action,
+				Payload: json.RawMessage(`{"seconds":30}`),
+			})
+
+			if !tt.wantForward {
+				if len(sent) != 0 {
+					t.Errorf("broadcast %v, want the action dropped", sent)
+				}
+				return
+			}
+			if len(sent) != 1 {
+				t.Fatalf("broadcast %d messages, want 1", len(sent))
+			}
+			payload, ok := sent[0].Payload.(*models.ExtensionPayload)
+			if !ok || payload.From != "user-1" || string(payload.Data) != `{"seconds":30}` {
+				t.Errorf("forwarded payload = %#v, want the data from user-1", sent[0].Payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package profile_logic
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/validation"
+)
+
+func GetProfile(profileId string) (*models.Profile, error) {
+	if profileId == "" {
+		return nil, models.NotFoundError{
+			Resource: "Profile",
+			Message:  "Profile not found",
+		}
+	}
+
+	profile, err := db.GetProfile(profileId)
+	if err != nil {
+		return nil, models.NotFoundError{
+			Resource: "Profile",
+			Message:  "Profile not found",
+		}
+	}
+	return profile, nil
+}
+
+// SaveProfile updates the profile profileId, or creates a new profile when
+// profileId is empty or no longer exists. An empty avatar seed or preferred
+// role keeps the current value; new profiles are seeded from their id and
+// default to voting.
+func SaveProfile(profileId, displayName, avatarSeed, preferredRole string) (*models.Profile, error) {
+	displayName, err := validation.UserName("displayName", displayName)
+	if err != nil {
+		return nil, err
+	}
+	avatarSeed, err = validation.AvatarSeed("avatarSeed", avatarSeed)
+	if err != nil {
+		return nil, err
+	}
+	preferredRole, err = validation.PreferredRole("preferredRole", preferredRole)
+	if err != nil {
+		return nil, err
+	}
+
+	if profileId != "" {
+		if profile, err := db.GetProfile(profileId); err == nil {
+			profile.DisplayName = displayName
+			if avatarSeed != "" {
+				profile.AvatarSeed = avatarSeed
+			}
+			if preferredRole != "" {
+				profile.PreferredRole = preferredRole
+			}
+			profile.UpdatedAt = time.Now()
+
+			if err := db.UpdateProfile(profile); err != nil {
+				return nil, models.DatabaseError{
+					Operation: "UpdateProfile",
+					Message:   "Failed to update profile",
+				}
+			}
+			return profile, nil
+		}
+	}
+
+	id := uuid.New().String()
+	if avatarSeed == "" {
+		avatarSeed = id
+	}
+	if preferredRole == "" {
+		preferredRole = models.ProfileRoleVoter
+	}
+	profile := models.NewProfile(id, displayName, avatarSeed, preferredRole)
+	if err := db.CreateProfile(profile); err != nil {
+		return nil, models.DatabaseError{
+			Operation: "CreateProfile",
+			Message:   "Failed to create profile",
+		}
+	}
+	return profile, nil
+}
+
+func DeleteProfile(profileId string) error {
+	if err := db.DeleteProfile(profileId); err != nil {
+		return models.DatabaseError{
+			Operation: "DeleteProfile",
+			Message:   "Failed to delete profile",
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package room_logic
+
+import (
+	"github.com/scrum-poker/backend/db"
+)
+
+// ArchiveRoom ends the room for good: its results are kept, read-only, and
+// its participants are removed. Only the account that owns the room may
+// archive it. Archiving an archived room does nothing.
+func ArchiveRoom(roomId, accountId string) error {
+	room, err := db.GetRoom(roomId)
+	if err != nil {
+		return NotFoundError{
+			Resource: "Room",
+			Message:  "Room not found",
+		}
+	}
+
+	if accountId == "" || room.OwnerAccountId != accountId {
+		return ForbiddenError{
+			Message: "Only the room owner can archive it",
+		}
+	}
+
+	err = db.InTx(func(tx *db.Tx) error {
+		room, err := tx.GetRoomForUpdate(roomId)
+		if err != nil {
+			return err
+		}
+		if room.IsArchived() {
+			return nil
+		}
+		return tx.ArchiveRoom(room)
+	})
+	if err != nil {
+		return DatabaseError{
+			Operation: "ArchiveRoom",
+			Message:   "Failed to archive room",
+		}
+	}
+	return nil
+}
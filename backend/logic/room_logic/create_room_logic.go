@@ -1,34 +1,119 @@
 package room_logic
 
 import (
-	"errors"
 	"github.com/google/uuid"
 	"github.com/scrum-poker/backend/db"
 	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/validation"
 )
 
-func CreateRoom(roomName, userName string) (*models.Room, *models.User, error) {
-	if roomName == "" {
-		return nil, nil, errors.New("room name is required")
+// RoomSettings controls who may join a room and whether it is kept while
+// empty.
+type RoomSettings struct {
+	AllowGuests         bool
+	AllowedEmailDomains []string
+	Persistent          bool
+}
+
+// CreateRoom creates a room with its creator as Scrum Master, and adds the
+// creator's membership to session sessionId, or to a new session. A room
+// created by a signed-in account is owned by it, and only such rooms may
+// restrict who joins or be persistent.
+func CreateRoom(roomName, userName string, identity models.Identity, access RoomSettings, sessionId string) (*models.Room, *models.Session, error) {
+	userName, profile := resolveUserName(userName, identity)
+
+	if !access.AllowGuests && !identity.IsAuthenticated() {
+		return nil, nil, ValidationError{
+			Field:   "allowGuests",
+			Message: "Sign in to create a room that is closed to guests",
+		}
+	}
+
+	domains, err := validation.EmailDomains("allowedEmailDomains", access.AllowedEmailDomains)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(domains) > 0 && !identity.IsAuthenticated() {
+		return nil, nil, ValidationError{
+			Field:   "allowedEmailDomains",
+			Message: "Sign in to create a room that is restricted to email domains",
+		}
+	}
+
+	if access.Persistent && !identity.IsAuthenticated() {
+		return nil, nil, ValidationError{
+			Field:   "persistent",
+			Message: "Sign in to create a persistent room",
+		}
+	}
+
+	roomName, err = validation.RoomName("name", roomName)
+	if err != nil {
+		return nil, nil, err
 	}
-	if userName == "" {
-		return nil, nil, errors.New("user name is required")
+	userName, err = validation.UserName("userName", userName)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	roomId := uuid.New().String()
 	userId := uuid.New().String()
 
 	user := models.NewUser(userId, userName)
+	if profile != nil {
+		user.ApplyProfile(profile)
+	}
 	room := models.NewRoom(roomId, roomName, userId)
+	room.OwnerAccountId = identity.AccountId
+	room.AllowGuests = access.AllowGuests && len(domains) == 0
+	room.AllowedEmailDomains = domains
+	room.Persistent = access.Persistent
 	room.AddParticipant(user)
 
-	if err := db.CreateRoom(room); err != nil {
-		return nil, nil, errors.New("failed to create room")
+	// The room, its creator and their membership are stored together, so
+	// that a failure cannot leave a room without its Scrum Master, or a
+	// Scrum Master without a session.
+	var membership *models.Session
+	err = db.InTx(func(tx *db.Tx) error {
+		if err := tx.CreateRoom(room); err != nil {
+			return err
+		}
+		if err := tx.AddParticipantToRoom(roomId, user); err != nil {
+			return err
+		}
+		var err error
+		membership, err = session.AddMembership(tx, sessionId, userId, roomId)
+		return err
+	})
+	if err != nil {
+		return nil, nil, DatabaseError{
+			Operation: "CreateRoom",
+			Message:   "Failed to create room",
+		}
 	}
 
-	if err := db.AddParticipantToRoom(roomId, user); err != nil {
-		return nil, nil, errors.New("failed to add participant to room")
+	return room, membership, nil
+}
+
+// resolveUserName returns the name for a new participant, falling back on the
+// display name of the identity's profile or account when userName is empty,
+// and the profile to link the participant to, if it still exists.
+func resolveUserName(userName string, identity models.Identity) (string, *models.Profile) {
+	var profile *models.Profile
+	if identity.ProfileId != "" {
+		if p, err := db.GetProfile(identity.ProfileId); err == nil {
+			profile = p
+			if userName == "" {
+				userName = profile.DisplayName
+			}
+		}
 	}
 
-	return room, user, nil
+	if userName == "" && identity.AccountId != "" {
+		if account, err := db.GetAccount(identity.AccountId); err == nil {
+			userName = account.DisplayName
+		}
+	}
+	return userName, profile
 }
@@ -0,0 +1,50 @@
+package room_logic
+
+import (
+	"time"
+
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/models"
+)
+
+// ExportRoom returns the room's results: its current state, or what was
+// kept when it was archived.
+func ExportRoom(roomId string) (*models.RoomExport, error) {
+	room, err := db.GetRoom(roomId)
+	if err != nil {
+		return nil, NotFoundError{
+			Resource: "Room",
+			Message:  "Room not found",
+		}
+	}
+	if !room.IsArchived() {
+		return room.ToExport(), nil
+	}
+
+	export, err := db.GetRoomArchive(roomId)
+	if err != nil {
+		return nil, DatabaseError{
+			Operation: "GetRoomArchive",
+			Message:   "Failed to read the archived room",
+		}
+	}
+	export.ExportedAt = time.Now()
+	return export, nil
+}
+
+// ExportOwnedRoom is ExportRoom for the account that owns the room.
+func ExportOwnedRoom(roomId, accountId string) (*models.RoomExport, error) {
+	room, err := db.GetRoom(roomId)
+	if err != nil {
+		return nil, NotFoundError{
+			Resource: "Room",
+			Message:  "Room not found",
+		}
+	}
+	if accountId == "" || room.OwnerAccountId != accountId {
+		return nil, ForbiddenError{
+			Message: "Only the room owner can export it",
+		}
+	}
+	return ExportRoom(roomId)
+}
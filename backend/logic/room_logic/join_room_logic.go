@@ -4,40 +4,25 @@ import (
 	"github.com/google/uuid"
 	"github.com/scrum-poker/backend/db"
 	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/validation"
 )
 
-type ValidationError struct {
-	Field   string
-	Message string
-}
-
-func (e ValidationError) Error() string {
-	return e.Message
-}
-
-type DatabaseError struct {
-	Operation string
-	Message   string
-}
-
-func (e DatabaseError) Error() string {
-	return e.Message
-}
-
-type NotFoundError struct {
-	Resource string
-	Message  string
-}
-
-func (e NotFoundError) Error() string {
-	return e.Message
-}
+type (
+	ValidationError = models.ValidationError
+	DatabaseError   = models.DatabaseError
+	NotFoundError   = models.NotFoundError
+	ForbiddenError  = models.ForbiddenError
+)
 
-func JoinRoom(roomId string, userName string, existingSession *models.Session, broadcastFunc models.BroadcastFunc) (string, error) {
+// JoinRoom adds a participant to the room, with their membership in session
+// sessionId, or in a new session, and returns the membership. A caller whose
+// existingSession is already a member rejoins as the same participant.
+func JoinRoom(roomId string, userName string, identity models.Identity, sessionId string, existingSession *models.Session, broadcastFunc models.BroadcastFunc) (*models.Session, error) {
 	if existingSession != nil {
 		user, err := db.GetUser(existingSession.UserId)
 		if err != nil {
-			return "", DatabaseError{
+			return nil, DatabaseError{
 				Operation: "GetUser",
 				Message:   "Failed to get user information",
 			}
@@ -45,44 +30,125 @@ func JoinRoom(roomId string, userName string, existingSession *models.Session, b
 
 		message := &models.Message{
 			Action:  models.ActionTypeJoin,
-			Payload: user.ToJSON(),
+			Payload: user,
 		}
 		broadcastFunc(roomId, message)
 
-		return existingSession.UserId, nil
+		return existingSession, nil
 	}
 
-	if userName == "" {
-		return "", ValidationError{
-			Field:   "userName",
-			Message: "User name is required",
-		}
-	}
+	userName, profile := resolveUserName(userName, identity)
 
-	room, err := db.GetRoom(roomId)
+	userName, err := validation.UserName("userName", userName)
 	if err != nil {
-		return "", NotFoundError{
-			Resource: "Room",
-			Message:  "Room not found",
-		}
+		return nil, err
 	}
 
+	// The room stays locked from the admission check until the participant
+	// and their membership are added, so that concurrent joins cannot pick
+	// the same name, and a participant is never added without a session.
 	userId := uuid.New().String()
-	user := models.NewUser(userId, userName)
-	room.AddParticipant(user)
+	var user *models.User
+	var membership *models.Session
+	var transfer *models.Message
+	err = db.InTx(func(tx *db.Tx) error {
+		room, err := tx.GetRoomForUpdate(roomId)
+		if err != nil {
+			return NotFoundError{
+				Resource: "Room",
+				Message:  "Room not found",
+			}
+		}
 
-	if err := db.AddParticipantToRoom(roomId, user); err != nil {
-		return "", DatabaseError{
-			Operation: "AddParticipantToRoom",
-			Message:   "Failed to join room",
+		if room.IsArchived() {
+			return ForbiddenError{
+				Message: "This room is archived",
+			}
+		}
+		if err := checkAdmission(room, identity); err != nil {
+			return err
+		}
+
+		user = models.NewUser(userId, validation.UniqueUserName(userName, room.ParticipantNames("")))
+		if profile != nil {
+			user.ApplyProfile(profile)
 		}
+		room.AddParticipant(user)
+
+		if err := tx.AddParticipantToRoom(roomId, user); err != nil {
+			return DatabaseError{
+				Operation: "AddParticipantToRoom",
+				Message:   "Failed to join room",
+			}
+		}
+
+		// A persistent room can have been left empty by its Scrum Master;
+		// whoever joins first takes over.
+		if _, ok := room.Participants[room.ScrumMaster]; !ok {
+			if err := tx.UpdateScrumMaster(roomId, userId); err != nil {
+				return DatabaseError{
+					Operation: "UpdateScrumMaster",
+					Message:   "Failed to join room",
+				}
+			}
+			transfer = &models.Message{
+				Action: models.ActionTypeTransfer,
+				Payload: &models.TransferPayload{
+					UserId:           room.ScrumMaster,
+					NewScrumMasterId: userId,
+				},
+			}
+		}
+
+		membership, err = session.AddMembership(tx, sessionId, userId, roomId)
+		if err != nil {
+			return DatabaseError{
+				Operation: "CreateSession",
+				Message:   "Failed to join room",
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	message := &models.Message{
 		Action:  models.ActionTypeJoin,
-		Payload: user.ToJSON(),
+		Payload: user,
 	}
 	broadcastFunc(roomId, message)
+	if transfer != nil {
+		broadcastFunc(roomId, transfer)
+	}
 
-	return userId, nil
+	return membership, nil
+}
+
+// checkAdmission enforces the room's access settings on a new participant.
+func checkAdmission(room *models.Room, identity models.Identity) error {
+	if !room.IsRestricted() {
+		return nil
+	}
+	if !identity.IsAuthenticated() {
+		return ForbiddenError{
+			Message: "This room only admits signed-in users",
+		}
+	}
+	if len(room.AllowedEmailDomains) == 0 {
+		return nil
+	}
+
+	account, err := db.GetAccount(identity.AccountId)
+	if err != nil {
+		return ForbiddenError{
+			Message: "This room only admits signed-in users",
+		}
+	}
+	if !account.EmailVerified || !room.AdmitsEmailDomain(account.EmailDomain()) {
+		return ForbiddenError{
+			Message: "This room only admits users with a verified email address in an allowed domain",
+		}
+	}
+	return nil
 }
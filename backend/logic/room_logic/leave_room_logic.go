@@ -6,56 +6,73 @@ import (
 	"github.com/scrum-poker/backend/models"
 )
 
+// LeaveRoom removes userId from the room and deletes their user, handing the
+// Scrum Master role to someone else first if needed. When the last
+// participant leaves a room that is not persistent, the room is archived
+// with them still in its results. Either all of it happens or none of it
+// does.
 func LeaveRoom(roomId, userId string, broadcastFunc models.BroadcastFunc) error {
+	var transfer *models.Message
+	err := db.InTx(func(tx *db.Tx) error {
+		room, err := tx.GetRoomForUpdate(roomId)
+		if err != nil {
+			return fmt.Errorf("room not found: %w", err)
+		}
 
-	room, err := db.GetRoom(roomId)
-	if err != nil {
-		return fmt.Errorf("room not found: %w", err)
-	}
-
-	if _, ok := room.Participants[userId]; !ok {
-		return fmt.Errorf("user not in room")
-	}
-
-	if room.ScrumMaster == userId {
-		participantsCopy := make(map[string]*models.User)
-		for id, user := range room.Participants {
-			participantsCopy[id] = user
+		if _, ok := room.Participants[userId]; !ok {
+			return fmt.Errorf("user not in room")
 		}
-		delete(participantsCopy, userId)
 
-		if len(participantsCopy) > 0 {
-			room.AssignRandomScrumMaster(participantsCopy)
+		if len(room.Participants) == 1 && !room.Persistent {
+			if err := tx.ArchiveRoom(room); err != nil {
+				return fmt.Errorf("failed to archive room: %w", err)
+			}
+			return nil
+		}
 
-			if err := db.UpdateScrumMaster(roomId, room.ScrumMaster); err != nil {
-				return fmt.Errorf("failed to update Scrum Master: %w", err)
+		if room.ScrumMaster == userId {
+			participantsCopy := make(map[string]*models.User)
+			for id, user := range room.Participants {
+				participantsCopy[id] = user
 			}
+			delete(participantsCopy, userId)
 
-			message := &models.Message{
-				Action: models.ActionTypeTransfer,
-				Payload: map[string]interface{}{
-					"userId":           userId,
-					"newScrumMasterId": room.ScrumMaster,
-				},
+			if len(participantsCopy) > 0 {
+				room.AssignRandomScrumMaster(participantsCopy)
+
+				if err := tx.UpdateScrumMaster(roomId, room.ScrumMaster); err != nil {
+					return fmt.Errorf("failed to update Scrum Master: %w", err)
+				}
+
+				transfer = &models.Message{
+					Action: models.ActionTypeTransfer,
+					Payload: &models.TransferPayload{
+						UserId:           userId,
+						NewScrumMasterId: room.ScrumMaster,
+					},
+				}
 			}
-			broadcastFunc(roomId, message)
 		}
-	}
 
-	room.RemoveParticipant(userId)
+		room.RemoveParticipant(userId)
 
-	if err := db.RemoveParticipantFromRoom(roomId, userId); err != nil {
-		return fmt.Errorf("failed to leave room: %w", err)
-	}
+		if err := tx.RemoveParticipantFromRoom(roomId, userId); err != nil {
+			return fmt.Errorf("failed to leave room: %w", err)
+		}
 
-	if err := db.DeleteUser(userId); err != nil {
-		return fmt.Errorf("failed to delete user: %w", err)
+		if err := tx.DeleteUser(userId); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	if len(room.Participants) == 0 {
-		if err := db.DeleteRoom(roomId); err != nil {
-			return fmt.Errorf("failed to delete room: %w", err)
-		}
+	// Only announced once committed, so that clients never see a transfer
+	// that was rolled back.
+	if transfer != nil {
+		broadcastFunc(roomId, transfer)
 	}
 	return nil
 }
@@ -0,0 +1,112 @@
+package room_logic
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/validation"
+)
+
+func GetStories(roomId string) ([]*models.Story, error) {
+	stories, err := db.GetStories(roomId)
+	if err != nil {
+		return nil, DatabaseError{
+			Operation: "GetStories",
+			Message:   "Failed to get stories",
+		}
+	}
+	return stories, nil
+}
+
+// AddStories appends stories with the given titles to the room's list and
+// returns them. Any participant may add stories while the room is open.
+func AddStories(roomId string, titles []string) ([]*models.Story, error) {
+	titles, err := validation.StoryTitles("stories", titles)
+	if err != nil {
+		return nil, err
+	}
+
+	stories := make([]*models.Story, 0, len(titles))
+	for _, title := range titles {
+		stories = append(stories, models.NewStory(uuid.New().String(), roomId, title))
+	}
+
+	err = db.InTx(func(tx *db.Tx) error {
+		room, err := tx.GetRoomForUpdate(roomId)
+		if err != nil {
+			return NotFoundError{
+				Resource: "Room",
+				Message:  "Room not found",
+			}
+		}
+		if room.IsArchived() {
+			return ForbiddenError{
+				Message: "Archived rooms cannot be changed",
+			}
+		}
+
+		count, err := tx.CountStories(roomId)
+		if err != nil {
+			return DatabaseError{
+				Operation: "CountStories",
+				Message:   "Failed to add stories",
+			}
+		}
+		if count+len(stories) > validation.MaxStoriesPerRoom {
+			return ValidationError{
+				Field:   "stories",
+				Message: fmt.Sprintf("A room can have at most %d stories", validation.MaxStoriesPerRoom),
+			}
+		}
+
+		if err := tx.AddStories(roomId, stories); err != nil {
+			return DatabaseError{
+				Operation: "AddStories",
+				Message:   "Failed to add stories",
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stories, nil
+}
+
+// DeleteStory removes a story from the room. Only the Scrum Master may do so.
+func DeleteStory(roomId, userId, storyId string) error {
+	room, err := db.GetRoom(roomId)
+	if err != nil {
+		return NotFoundError{
+			Resource: "Room",
+			Message:  "Room not found",
+		}
+	}
+	if room.ScrumMaster != userId {
+		return ForbiddenError{
+			Message: "Only the Scrum Master can remove stories",
+		}
+	}
+	if room.IsArchived() {
+		return ForbiddenError{
+			Message: "Archived rooms cannot be changed",
+		}
+	}
+
+	deleted, err := db.DeleteStory(roomId, storyId)
+	if err != nil {
+		return DatabaseError{
+			Operation: "DeleteStory",
+			Message:   "Failed to remove story",
+		}
+	}
+	if !deleted {
+		return NotFoundError{
+			Resource: "Story",
+			Message:  "Story not found",
+		}
+	}
+	return nil
+}
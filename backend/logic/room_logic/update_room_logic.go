@@ -0,0 +1,70 @@
+package room_logic
+
+import (
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/validation"
+)
+
+// UpdateRoomSettings changes who may join the room and whether it is kept
+// while empty. Only the account that owns the room may do so; nil arguments
+// leave the setting unchanged. Participants already in the room are not
+// affected.
+func UpdateRoomSettings(roomId, accountId string, allowGuests *bool, allowedEmailDomains []string, persistent *bool) error {
+	var domains []string
+	if allowedEmailDomains != nil {
+		var err error
+		domains, err = validation.EmailDomains("allowedEmailDomains", allowedEmailDomains)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The room stays locked from the ownership check until it is updated,
+	// so that the settings cannot change in between, e.g. by archiving.
+	return db.InTx(func(tx *db.Tx) error {
+		room, err := tx.GetRoomForUpdate(roomId)
+		if err != nil {
+			return NotFoundError{
+				Resource: "Room",
+				Message:  "Room not found",
+			}
+		}
+
+		if accountId == "" || room.OwnerAccountId != accountId {
+			return ForbiddenError{
+				Message: "Only the room owner can change its settings",
+			}
+		}
+		if room.IsArchived() {
+			return ForbiddenError{
+				Message: "Archived rooms cannot be changed",
+			}
+		}
+
+		access := RoomSettings{
+			AllowGuests:         room.AllowGuests,
+			AllowedEmailDomains: room.AllowedEmailDomains,
+			Persistent:          room.Persistent,
+		}
+		if allowGuests != nil {
+			access.AllowGuests = *allowGuests
+		}
+		if allowedEmailDomains != nil {
+			access.AllowedEmailDomains = domains
+		}
+		if len(access.AllowedEmailDomains) > 0 {
+			access.AllowGuests = false
+		}
+		if persistent != nil {
+			access.Persistent = *persistent
+		}
+
+		if err := tx.UpdateRoomSettings(roomId, access.AllowGuests, access.AllowedEmailDomains, access.Persistent); err != nil {
+			return DatabaseError{
+				Operation: "UpdateRoomSettings",
+				Message:   "Failed to update room",
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,95 @@
+package token_logic
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/validation"
+)
+
+// IssueToken creates an API token for accountId and returns it along with
+// its secret value, which is not stored and cannot be retrieved later. A
+// token limited to roomId can only be used on that room's routes.
+func IssueToken(accountId, name string, scopes []string, roomId string, lifetimeDays int) (*models.APIToken, string, error) {
+	if accountId == "" {
+		return nil, "", models.UnauthorizedError{Message: "Sign in to issue API tokens"}
+	}
+
+	name, err := validation.TokenName("name", name)
+	if err != nil {
+		return nil, "", err
+	}
+	scopes, err = validation.Scopes("scopes", scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	lifetimeDays, err = validation.TokenLifetimeDays("expiresInDays", lifetimeDays)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if roomId != "" {
+		if _, err := db.GetRoom(roomId); err != nil {
+			return nil, "", models.NotFoundError{
+				Resource: "Room",
+				Message:  "Room not found",
+			}
+		}
+	}
+
+	secret, hash, err := session.GenerateAPIToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	ttl := time.Duration(lifetimeDays) * 24 * time.Hour
+	token := models.NewAPIToken(uuid.New().String(), name, accountId, roomId, uuid.New().String(), hash, scopes, ttl)
+	if err := db.CreateAPIToken(token); err != nil {
+		return nil, "", models.DatabaseError{
+			Operation: "CreateAPIToken",
+			Message:   "Failed to create API token",
+		}
+	}
+	return token, secret, nil
+}
+
+func ListTokens(accountId string) ([]*models.APIToken, error) {
+	if accountId == "" {
+		return nil, models.UnauthorizedError{Message: "Sign in to manage API tokens"}
+	}
+
+	tokens, err := db.GetAccountAPITokens(accountId)
+	if err != nil {
+		return nil, models.DatabaseError{
+			Operation: "GetAccountAPITokens",
+			Message:   "Failed to get API tokens",
+		}
+	}
+	return tokens, nil
+}
+
+// RevokeToken deletes a token of accountId. Rooms it joined lose its
+// memberships.
+func RevokeToken(accountId, tokenId string) error {
+	if accountId == "" {
+		return models.UnauthorizedError{Message: "Sign in to manage API tokens"}
+	}
+
+	found, err := db.DeleteAPIToken(tokenId, accountId)
+	if err != nil {
+		return models.DatabaseError{
+			Operation: "DeleteAPIToken",
+			Message:   "Failed to revoke API token",
+		}
+	}
+	if !found {
+		return models.NotFoundError{
+			Resource: "APIToken",
+			Message:  "API token not found",
+		}
+	}
+	return nil
+}
@@ -4,21 +4,34 @@ import (
 	"fmt"
 
 	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/validation"
 )
 
-func RenameUser(userId, roomId, newName string) error {
+// RenameUser stores the sanitised form of newName, suffixed if another
+// participant already uses it, and returns the name that was stored.
+func RenameUser(requesterId, userId, roomId, newName string) (string, error) {
+	name, err := validation.UserName("name", newName)
+	if err != nil {
+		return "", err
+	}
+
 	room, err := db.GetRoom(roomId)
 	if err != nil {
-		return fmt.Errorf("room not found: %w", err)
+		return "", fmt.Errorf("room not found: %w", err)
+	}
+
+	if requesterId != userId && room.ScrumMaster != requesterId {
+		return "", fmt.Errorf("only the Scrum Master can rename other participants")
 	}
 
 	if _, ok := room.Participants[userId]; !ok {
-		return fmt.Errorf("user not in room")
+		return "", fmt.Errorf("user not in room")
 	}
 
-	if err := db.UpdateUserName(userId, newName); err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
+	name = validation.UniqueUserName(name, room.ParticipantNames(userId))
+	if err := db.UpdateUserName(userId, name); err != nil {
+		return "", fmt.Errorf("failed to update user: %w", err)
 	}
 
-	return nil
+	return name, nil
 }
@@ -12,9 +12,13 @@ func SubmitVote(userId, roomId, vote string) error {
 		return fmt.Errorf("room not found: %w", err)
 	}
 
-	if _, ok := room.Participants[userId]; !ok {
+	user, ok := room.Participants[userId]
+	if !ok {
 		return fmt.Errorf("user %s not in room %s", userId, roomId)
 	}
+	if user.IsObserver() {
+		return fmt.Errorf("observers cannot vote")
+	}
 
 	if vote == "" {
 		if err := db.DeleteVote(roomId, userId); err != nil {
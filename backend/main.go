@@ -1,71 +1,96 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/scrum-poker/backend/websocket"
-	"log"
-	"net/http"
+	"io"
+	"log/slog"
 	"os"
-	"os/signal"
-	"strings"
-	"syscall"
+	"text/tabwriter"
 
-	"github.com/gorilla/mux"
-	"github.com/rs/cors"
-	"github.com/scrum-poker/backend/db"
-	"github.com/scrum-poker/backend/handlers"
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/logging"
 )
 
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "serve [flags]", "Run the server (the default)", runServeCommand},
+	{"migrate", "migrate [flags]", "Create or update the database tables", runMigrateCommand},
+	{"rooms", "rooms list|show|delete [flags] [roomId]", "List, inspect or delete rooms", runRoomsCommand},
+	{"sessions", "sessions purge-expired [flags]", "Remove participants whose sessions have expired", runSessionsCommand},
+	{"export", "export room [flags] <roomId>", "Write a room and its votes as JSON", runExportCommand},
+	{"import", "import stories [flags] <roomId>", "Add stories to a room, one title per line", runImportCommand},
+	{"config", "config print [flags]", "Show the effective configuration", runConfigCommand},
+}
+
 func main() {
-	port := os.Getenv("BACKEND_PORT")
-	if port == "" {
-		port = "8080"
+	args := os.Args[1:]
+	// Without a command, or when only flags are given, the server runs, so
+	// that existing deployments keep working.
+	name := "serve"
+	if len(args) > 0 && !isFlag(args[0]) {
+		name, args = args[0], args[1:]
 	}
 
-	err := db.Connect()
-	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+	if name == "help" {
+		printUsage(os.Stdout)
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			fatal("Command failed", err)
+		}
+		return
 	}
-	defer db.Close()
-
-	websocket.Init()
-
-	r := mux.NewRouter()
-
-	r.HandleFunc("/health", handlers.HealthCheckHandler).Methods("GET")
-
-	r.HandleFunc("/rooms", handlers.CreateRoomHandler).Methods("POST")
-	r.HandleFunc("/rooms/{roomId}", handlers.GetRoomHandler).Methods("GET")
-	r.HandleFunc("/rooms/{roomId}/join", handlers.JoinRoomHandler).Methods("POST")
-
-	r.HandleFunc("/sessions/{userId}/{roomId}", handlers.CreateSessionHandler).Methods("POST")
-	r.HandleFunc("/sessions", handlers.GetSessionHandler).Methods("GET")
-	r.HandleFunc("/sessions", handlers.DeleteSessionHandler).Methods("DELETE")
 
-	r.HandleFunc("/ws/{roomId}", handlers.WebSocketHandler)
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	printUsage(os.Stderr)
+	os.Exit(2)
+}
 
-	c := cors.New(cors.Options{
-		AllowedOrigins:   strings.Split(os.Getenv("ALLOWED_ORIGINS"), ","),
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"*"},
-		AllowCredentials: true,
-	})
-	handler := c.Handler(r)
+func isFlag(arg string) bool {
+	return len(arg) > 0 && arg[0] == '-'
+}
 
-	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: handler,
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: scrum-poker <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.usage, cmd.summary)
 	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Every command accepts the configuration flags, which come before any other\narguments; run a command with -h to list them.")
+}
 
-	go func() {
-		fmt.Printf("Server is running on port %s...\n", port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
-		}
-	}()
+// loadCommand loads the configuration for the command called name, with
+// the command's own flags added by define, and returns the positional
+// arguments.
+func loadCommand(name string, args []string, define func(fs *flag.FlagSet)) ([]string, error) {
+	rest, err := config.LoadCommand("scrum-poker "+name, args, define)
+	if err != nil {
+		return nil, err
+	}
+	logging.Init()
+	return rest, nil
+}
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("Shutting down server...")
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/scrum-poker/backend/config"
+)
+
+// useCommandEnv keeps the environment the tests run in out of the commands'
+// configuration, and restores the configuration and logger they replace.
+func useCommandEnv(t *testing.T) {
+	t.Helper()
+	previousCfg, previousLogger := config.Cfg, slog.Default()
+	t.Cleanup(func() {
+		config.Cfg = previousCfg
+		slog.SetDefault(previousLogger)
+	})
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("SESSION_CLEANUP_INTERVAL", "")
+	t.Setenv("ENV", "dev")
+}
+
+func TestUsageListsEveryCommand(t *testing.T) {
+	var out strings.Builder
+	printUsage(&out)
+	for _, cmd := range commands {
+		if !strings.Contains(out.String(), cmd.usage) {
+			t.Errorf("usage does not list %q", cmd.usage)
+		}
+	}
+}
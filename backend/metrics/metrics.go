@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/utils"
+)
+
+const namespace = "scrum_poker"
+
+var registry = prometheus.NewRegistry()
+
+var factory = promauto.With(registry)
+
+var (
+	ActiveRooms = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_rooms",
+		Help:      "Rooms with at least one real-time connection.",
+	})
+	// Connections are not broken down by room: room IDs are enough to
+	// join a room and would leak to anyone able to read the metrics.
+	ConnectedClients = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "connected_clients",
+		Help:      "Open WebSocket and SSE connections.",
+	})
+
+	messagesIn = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "Real-time messages received from clients, by action.",
+	}, []string{"action"})
+	messagesOut = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_sent_total",
+		Help:      "Real-time messages queued for clients, by action.",
+	}, []string{"action"})
+	BroadcastDrops = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "broadcast_drops_total",
+		Help:      "Clients disconnected because their send buffer was full during a broadcast.",
+	})
+
+	dbQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of database calls, by repository function.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"function"})
+
+	SessionCleanupDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "session_cleanup_duration_seconds",
+		Help:      "Duration of expired session sweeps.",
+		Buckets:   prometheus.ExponentialBuckets(.005, 2, 12),
+	})
+	SessionCleanupMemberships = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "session_cleanup_memberships_total",
+		Help:      "Expired room memberships handled by the cleanup, by outcome: removed, or refreshed because the participant is still connected.",
+	}, []string{"outcome"})
+	SessionCleanupSessions = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "session_cleanup_sessions_deleted_total",
+		Help:      "Sessions without memberships or an account deleted by the cleanup.",
+	})
+	RoomsArchived = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rooms_archived_total",
+		Help:      "Rooms archived by the cleanup for being idle or left empty.",
+	})
+	RoomsPurged = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rooms_purged_total",
+		Help:      "Archived rooms deleted by the cleanup once their retention ended.",
+	})
+
+	httpRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of HTTP requests, by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// CountMessageIn records a message received from a client.
+func CountMessageIn(action models.ActionType) {
+	messagesIn.WithLabelValues(actionLabel(action)).Inc()
+}
+
+// CountMessageOut records a message queued for a client.
+func CountMessageOut(action models.ActionType) {
+	messagesOut.WithLabelValues(actionLabel(action)).Inc()
+}
+
+// actionLabel keeps the action label bounded: extension actions are named
+// by clients, so they are counted together, as are unknown ones.
+func actionLabel(action models.ActionType) string {
+	switch {
+	case models.IsExtensionAction(action):
+		return "extension"
+	case models.IsKnownAction(action):
+		return string(action)
+	default:
+		return "unknown"
+	}
+}
+
+// TimeQuery starts timing a database call; call the returned function when
+// it is done, usually with defer.
+func TimeQuery(function string) func() {
+	start := time.Now()
+	return func() {
+		dbQueryDuration.WithLabelValues(function).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format. When
+// METRICS_TOKEN is set, scrapers must send it as a Bearer token.
+func Handler() http.Handler {
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := config.Cfg.Metrics.Token; token != "" {
+			if !utils.HasBearerToken(r, token) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+				utils.PrepareErrorResponse(w, models.UnauthorizedError{Message: "A valid metrics token is required"})
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
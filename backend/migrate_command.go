@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/scrum-poker/backend/db"
+)
+
+// runMigrateCommand brings the database schema up to date without starting
+// the server.
+func runMigrateCommand(args []string) error {
+	rest, err := loadCommand("migrate", args, nil)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected argument %q", rest[0])
+	}
+
+	if err := db.Open(); err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Migrate()
+}
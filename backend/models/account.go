@@ -0,0 +1,36 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type Account struct {
+	Id          string `json:"id"`
+	Email       string `json:"email"`
+	DisplayName string `json:"displayName"`
+	// EmailVerified is set for accounts whose address was confirmed by an
+	// identity provider. Only verified addresses satisfy room email domain
+	// restrictions.
+	EmailVerified bool      `json:"emailVerified"`
+	PasswordHash  string    `json:"-"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func NewAccount(id, email, displayName, passwordHash string) *Account {
+	now := time.Now()
+	return &Account{
+		Id:           id,
+		Email:        email,
+		DisplayName:  displayName,
+		PasswordHash: passwordHash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+// EmailDomain returns the part of the account's email after the @.
+func (a *Account) EmailDomain() string {
+	return a.Email[strings.LastIndex(a.Email, "@")+1:]
+}
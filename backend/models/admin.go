@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// RoomSummary describes a room in the operator's room list.
+type RoomSummary struct {
+	Id           string     `json:"id"`
+	Name         string     `json:"name"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	ScrumMaster  string     `json:"scrumMaster"`
+	Participants int        `json:"participants"`
+	Online       int        `json:"online"`
+	Persistent   bool       `json:"persistent"`
+	ArchivedAt   *time.Time `json:"archivedAt,omitempty"`
+}
+
+// RoomInspection is a room as operators see it: its state plus the
+// participants that currently have a real-time connection.
+type RoomInspection struct {
+	Room             map[string]interface{} `json:"room"`
+	OwnerAccountId   string                 `json:"ownerAccountId,omitempty"`
+	ConnectedUserIds []string               `json:"connectedUserIds"`
+}
+
+type NoticeResult struct {
+	Rooms int `json:"rooms"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ScopeRead       = "read"
+	ScopeWrite      = "write"
+	ScopeFacilitate = "facilitate"
+)
+
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeFacilitate}
+
+// APIToken lets scripts act for an account without a browser. Every token
+// owns a session, so rooms joined with it are memberships of that session
+// like any other. Scopes and the optional room only narrow what the account
+// could do anyway.
+type APIToken struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	AccountId  string     `json:"-"`
+	RoomId     string     `json:"roomId,omitempty"`
+	SessionId  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	TokenHash  string     `json:"-"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+func NewAPIToken(id, name, accountId, roomId, sessionId, tokenHash string, scopes []string, ttl time.Duration) *APIToken {
+	now := time.Now()
+	return &APIToken{
+		Id:        id,
+		Name:      name,
+		AccountId: accountId,
+		RoomId:    roomId,
+		SessionId: sessionId,
+		Scopes:    scopes,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Admits reports whether the token may be used in roomId. Tokens without a
+// room are valid in every room.
+func (t *APIToken) Admits(roomId string) bool {
+	return t.RoomId == "" || t.RoomId == roomId
+}
+
+func (t *APIToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+// ActionScope returns the scope needed to send msg as senderId: running the
+// round and renaming others is facilitation, everything else is writing.
+func ActionScope(msg *Message, senderId string) string {
+	switch msg.Action {
+	case ActionTypeReveal, ActionTypeReset, ActionTypeTransfer:
+		return ScopeFacilitate
+	case ActionTypeRename:
+		if payload, ok := msg.Payload.(*RenamePayload); ok && payload.UserId != senderId {
+			return ScopeFacilitate
+		}
+	}
+	return ScopeWrite
+}
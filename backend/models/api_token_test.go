@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActionScope(t *testing.T) {
+	scopes := map[ActionType]string{
+		ActionTypeSubmit:   ScopeWrite,
+		ActionTypeLeave:    ScopeWrite,
+		ActionTypeReveal:   ScopeFacilitate,
+		ActionTypeReset:    ScopeFacilitate,
+		ActionTypeTransfer: ScopeFacilitate,
+	}
+	for action, want := range scopes {
+		if got := ActionScope(&Message{Action: action}, "alice"); got != want {
+			t.Errorf("ActionScope(%s) = %q, want %q", action, got, want)
+		}
+	}
+
+	// Renaming yourself is writing; renaming someone else facilitates.
+	renames := map[string]string{
+		"alice": ScopeWrite,
+		"bob":   ScopeFacilitate,
+	}
+	for userId, want := range renames {
+		msg := &Message{Action: ActionTypeRename, Payload: &RenamePayload{UserId: userId, Name: "Carol"}}
+		if got := ActionScope(msg, "alice"); got != want {
+			t.Errorf("ActionScope(rename %s) = %q, want %q", userId, got, want)
+		}
+	}
+}
+
+func TestAPITokenNarrowsAccess(t *testing.T) {
+	token := NewAPIToken("token-1", "CI", "account-1", "room-1", "session-1", "hash", []string{ScopeRead, ScopeWrite}, time.Hour)
+
+	if !token.HasScope(ScopeWrite) || token.HasScope(ScopeFacilitate) {
+		t.Errorf("token with scopes %q reports the wrong scopes", token.Scopes)
+	}
+	if !token.Admits("room-1") || token.Admits("room-2") {
+		t.Error("a token for room-1 must admit only room-1")
+	}
+	if token.IsExpired() {
+		t.Error("a new token is expired")
+	}
+
+	token.RoomId = ""
+	if !token.Admits("room-2") {
+		t.Error("a token without a room must admit every room")
+	}
+
+	token.ExpiresAt = time.Now().Add(-time.Second)
+	if !token.IsExpired() {
+		t.Error("a token past its expiry is not expired")
+	}
+}
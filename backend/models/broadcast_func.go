@@ -2,3 +2,4 @@ package models
 
 type BroadcastFunc func(roomId string, msg *Message)
 type ConnectionChecker func(roomId, userId string) bool
+type RoomCloser func(roomId, reason string) int
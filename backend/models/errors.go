@@ -0,0 +1,44 @@
+package models
+
+type ValidationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return e.Message
+}
+
+type DatabaseError struct {
+	Operation string
+	Message   string
+}
+
+func (e DatabaseError) Error() string {
+	return e.Message
+}
+
+type NotFoundError struct {
+	Resource string
+	Message  string
+}
+
+func (e NotFoundError) Error() string {
+	return e.Message
+}
+
+type ForbiddenError struct {
+	Message string
+}
+
+func (e ForbiddenError) Error() string {
+	return e.Message
+}
+
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e UnauthorizedError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,23 @@
+package models
+
+// Identity describes who is behind a request beyond the room membership of
+// its session: the device profile and the signed-in account, either of which
+// may be empty.
+type Identity struct {
+	ProfileId string
+	AccountId string
+}
+
+func (i Identity) IsAuthenticated() bool {
+	return i.AccountId != ""
+}
+
+// ExternalIdentity is what an identity provider asserts about the person
+// signing in, after claim mapping.
+type ExternalIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
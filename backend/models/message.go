@@ -3,20 +3,43 @@ package models
 type ActionType string
 
 const (
-	ActionTypeJoin     ActionType = "join"
-	ActionTypeOffline  ActionType = "offline"
-	ActionTypeOnline   ActionType = "online"
-	ActionTypeLeave    ActionType = "leave"
-	ActionTypeRename   ActionType = "rename"
-	ActionTypeSubmit   ActionType = "submit"
-	ActionTypeReveal   ActionType = "reveal"
-	ActionTypeReset    ActionType = "reset"
-	ActionTypeTransfer ActionType = "transfer"
-	ActionTypePing     ActionType = "ping"
-	ActionTypePong     ActionType = "pong"
+	ActionTypeJoin      ActionType = "join"
+	ActionTypeOffline   ActionType = "offline"
+	ActionTypeOnline    ActionType = "online"
+	ActionTypeLeave     ActionType = "leave"
+	ActionTypeRename    ActionType = "rename"
+	ActionTypeSubmit    ActionType = "submit"
+	ActionTypeReveal    ActionType = "reveal"
+	ActionTypeReset     ActionType = "reset"
+	ActionTypeTransfer  ActionType = "transfer"
+	ActionTypePing      ActionType = "ping"
+	ActionTypePong      ActionType = "pong"
+	ActionTypeSnapshot  ActionType = "snapshot"
+	ActionTypeHello     ActionType = "hello"
+	ActionTypeThrottled ActionType = "throttled"
+
+	ActionTypeServerRestarting ActionType = "server_restarting"
+	ActionTypeNotice           ActionType = "notice"
+	ActionTypeRoomClosed       ActionType = "room_closed"
 )
 
 type Message struct {
+	// Epoch identifies the numbering Seq belongs to, which restarts when the
+	// server loses a room's events.
+	Epoch   string      `json:"epoch,omitempty"`
+	Seq     uint64      `json:"seq,omitempty"`
 	Action  ActionType  `json:"action"`
 	Payload interface{} `json:"payload"`
 }
+
+// IsSequenced reports whether the action is a room event that is numbered
+// and kept for replay. Heartbeats are transient and never replayed.
+func (m *Message) IsSequenced() bool {
+	switch m.Action {
+	case ActionTypePing, ActionTypePong, ActionTypeHello, ActionTypeThrottled, ActionTypeServerRestarting,
+		ActionTypeNotice, ActionTypeRoomClosed:
+		return false
+	default:
+		return true
+	}
+}
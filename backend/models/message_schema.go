@@ -0,0 +1,156 @@
+package models
+
+//go:generate go run ../cmd/protocol-schema -o ../docs/protocol.schema.json
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProtocolSchema builds a JSON Schema document describing every message the
+// client may send and every message the server may emit, derived from the
+// payload types registered in protocol.go.
+func ProtocolSchema() map[string]interface{} {
+	defs := make(map[string]interface{})
+
+	clientMessage := messageSchema(clientPayloads, defs, false)
+	serverMessage := messageSchema(serverPayloads, defs, true)
+	appendExtensionSchema(clientMessage, map[string]interface{}{})
+	appendExtensionSchema(serverMessage, typeSchema(reflect.TypeOf(ExtensionPayload{}), defs))
+	defs["ClientMessage"] = clientMessage
+	defs["ServerMessage"] = serverMessage
+
+	return map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         fmt.Sprintf("https://scrumpoker.harunergen.com/protocol/v%d.schema.json", ProtocolVersion),
+		"title":       "Scrum Poker real-time protocol",
+		"description": fmt.Sprintf("Protocol version %d (minimum supported %d).", ProtocolVersion, MinProtocolVersion),
+		"oneOf": []interface{}{
+			map[string]interface{}{"$ref": "#/$defs/ClientMessage"},
+			map[string]interface{}{"$ref": "#/$defs/ServerMessage"},
+		},
+		"$defs": defs,
+	}
+}
+
+func messageSchema(payloads map[ActionType]func() interface{}, defs map[string]interface{}, fromServer bool) map[string]interface{} {
+	actions := make([]string, 0, len(payloads))
+	for action := range payloads {
+		actions = append(actions, string(action))
+	}
+	sort.Strings(actions)
+
+	variants := make([]interface{}, 0, len(actions))
+	for _, action := range actions {
+		payloadType := reflect.TypeOf(payloads[ActionType(action)]())
+
+		properties := map[string]interface{}{
+			"action":  map[string]interface{}{"const": action},
+			"payload": typeSchema(payloadType, defs),
+		}
+		if fromServer {
+			properties["epoch"] = map[string]interface{}{"type": "string"}
+			properties["seq"] = map[string]interface{}{"type": "integer", "minimum": 1}
+		}
+
+		variants = append(variants, map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             []string{"action", "payload"},
+			"additionalProperties": false,
+		})
+	}
+
+	return map[string]interface{}{"oneOf": variants}
+}
+
+// appendExtensionSchema documents namespaced extension actions, which are
+// matched by prefix rather than listed individually.
+func appendExtensionSchema(message map[string]interface{}, payload map[string]interface{}) {
+	message["oneOf"] = append(message["oneOf"].([]interface{}), map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action":  map[string]interface{}{"type": "string", "pattern": "^" + ExtensionActionPrefix + ".+"},
+			"payload": payload,
+		},
+		"required":             []string{"action", "payload"},
+		"additionalProperties": false,
+	})
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func typeSchema(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t == rawMessageType {
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), defs)}
+	case reflect.Struct:
+		name := t.Name()
+		if _, exists := defs[name]; !exists {
+			defs[name] = nil
+			defs[name] = structSchema(t, defs)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func structSchema(t reflect.Type, defs map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = typeSchema(field.Type, defs)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
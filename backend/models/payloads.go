@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type UserPayload struct {
+	UserId string `json:"userId"`
+}
+
+type SubmitPayload struct {
+	UserId string `json:"userId"`
+	Vote   string `json:"vote"`
+}
+
+type RenamePayload struct {
+	UserId string `json:"userId"`
+	Name   string `json:"name"`
+}
+
+type TransferPayload struct {
+	UserId           string `json:"userId"`
+	NewScrumMasterId string `json:"newScrumMasterId"`
+}
+
+type HelloPayload struct {
+	Version int `json:"version"`
+}
+
+type ThrottledPayload struct {
+	Action       ActionType `json:"action"`
+	RetryAfterMs int64      `json:"retryAfterMs"`
+}
+
+// ServerRestartingPayload tells clients how long to wait before reconnecting
+// when the server shuts down for a restart.
+type ServerRestartingPayload struct {
+	ReconnectAfterMs int64 `json:"reconnectAfterMs"`
+}
+
+// NoticePayload is an announcement from the operators, such as upcoming
+// maintenance, shown to everyone in the room.
+type NoticePayload struct {
+	Message string `json:"message"`
+}
+
+// RoomClosedPayload tells clients that the room was closed by an operator
+// and will not accept them again.
+type RoomClosedPayload struct {
+	Reason string `json:"reason"`
+}
+
+type RevealedVotesPayload struct {
+	Votes map[string]string `json:"votes"`
+}
+
+type RoomSnapshotPayload struct {
+	Id            string            `json:"id"`
+	Name          string            `json:"name"`
+	CreatedAt     time.Time         `json:"createdAt"`
+	ScrumMaster   string            `json:"scrumMaster"`
+	Participants  map[string]*User  `json:"participants"`
+	Votes         map[string]string `json:"votes"`
+	VotesRevealed bool              `json:"votesRevealed"`
+}
+
+type ExtensionPayload struct {
+	From string          `json:"from"`
+	Data json.RawMessage `json:"data"`
+}
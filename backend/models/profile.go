@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ProfileRoleVoter       = "voter"
+	ProfileRoleObserver    = "observer"
+	ProfileRoleFacilitator = "facilitator"
+)
+
+// Profile is a durable, device-bound identity. Users are still created per
+// room, but users created from the same device link back to its profile.
+type Profile struct {
+	Id            string    `json:"id"`
+	DisplayName   string    `json:"displayName"`
+	AvatarSeed    string    `json:"avatarSeed"`
+	PreferredRole string    `json:"preferredRole"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func NewProfile(id, displayName, avatarSeed, preferredRole string) *Profile {
+	now := time.Now()
+	return &Profile{
+		Id:            id,
+		DisplayName:   displayName,
+		AvatarSeed:    avatarSeed,
+		PreferredRole: preferredRole,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
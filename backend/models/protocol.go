@@ -0,0 +1,170 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	ProtocolVersion    = 1
+	MinProtocolVersion = 1
+
+	// ExtensionActionPrefix namespaces custom actions that are not part of
+	// the protocol. They are never mistaken for server events.
+	ExtensionActionPrefix = "x-"
+)
+
+var (
+	ErrUnknownAction      = errors.New("unknown action")
+	ErrServerOnlyAction   = errors.New("action may only be sent by the server")
+	ErrMissingPayload     = errors.New("payload is required")
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+)
+
+// clientPayloads is the allow-list of actions a client may send together with
+// the payload type each of them is decoded into.
+var clientPayloads = map[ActionType]func() interface{}{
+	ActionTypeHello:    func() interface{} { return new(HelloPayload) },
+	ActionTypeSubmit:   func() interface{} { return new(SubmitPayload) },
+	ActionTypeReveal:   func() interface{} { return new(UserPayload) },
+	ActionTypeReset:    func() interface{} { return new(UserPayload) },
+	ActionTypeTransfer: func() interface{} { return new(TransferPayload) },
+	ActionTypeRename:   func() interface{} { return new(RenamePayload) },
+	ActionTypeLeave:    func() interface{} { return new(UserPayload) },
+	ActionTypePing:     func() interface{} { return new(UserPayload) },
+}
+
+// serverPayloads lists the actions the server emits with their payload types.
+// Those without a clientPayloads entry can never be sent by a client.
+var serverPayloads = map[ActionType]func() interface{}{
+	ActionTypeHello:     func() interface{} { return new(HelloPayload) },
+	ActionTypeJoin:      func() interface{} { return new(User) },
+	ActionTypeOnline:    func() interface{} { return new(UserPayload) },
+	ActionTypeOffline:   func() interface{} { return new(UserPayload) },
+	ActionTypeLeave:     func() interface{} { return new(UserPayload) },
+	ActionTypeRename:    func() interface{} { return new(RenamePayload) },
+	ActionTypeSubmit:    func() interface{} { return new(SubmitPayload) },
+	ActionTypeReveal:    func() interface{} { return new(RevealedVotesPayload) },
+	ActionTypeReset:     func() interface{} { return new(UserPayload) },
+	ActionTypeTransfer:  func() interface{} { return new(TransferPayload) },
+	ActionTypePong:      func() interface{} { return new(UserPayload) },
+	ActionTypeSnapshot:  func() interface{} { return new(RoomSnapshotPayload) },
+	ActionTypeThrottled: func() interface{} { return new(ThrottledPayload) },
+
+	ActionTypeServerRestarting: func() interface{} { return new(ServerRestartingPayload) },
+	ActionTypeNotice:           func() interface{} { return new(NoticePayload) },
+	ActionTypeRoomClosed:       func() interface{} { return new(RoomClosedPayload) },
+}
+
+type envelope struct {
+	Action  ActionType      `json:"action"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// NewClientPayload returns an empty payload of the type expected for a
+// client-sent action.
+func NewClientPayload(action ActionType) (interface{}, bool) {
+	newPayload, ok := clientPayloads[action]
+	if !ok {
+		return nil, false
+	}
+	return newPayload(), true
+}
+
+// PayloadDecoder decodes a message payload into v and must reject unknown
+// fields. Extension payloads are requested as *json.RawMessage and have to be
+// delivered as JSON whatever the wire encoding.
+type PayloadDecoder func(v interface{}) error
+
+// NewClientMessage validates a client-sent action against the allow-list and
+// decodes its payload into the matching typed struct. It is shared by every
+// wire encoding.
+func NewClientMessage(action ActionType, hasPayload bool, decodePayload PayloadDecoder) (*Message, error) {
+	if !hasPayload {
+		return nil, fmt.Errorf("%s: %w", action, ErrMissingPayload)
+	}
+
+	if IsExtensionAction(action) {
+		var data json.RawMessage
+		if err := decodePayload(&data); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", action, err)
+		}
+		return &Message{Action: action, Payload: data}, nil
+	}
+
+	payload, ok := NewClientPayload(action)
+	if !ok {
+		if IsServerOnlyAction(action) {
+			return nil, fmt.Errorf("%w: %q", ErrServerOnlyAction, action)
+		}
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAction, action)
+	}
+
+	if err := decodePayload(payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", action, err)
+	}
+
+	return &Message{Action: action, Payload: payload}, nil
+}
+
+// DecodeClientMessage strictly decodes a JSON message sent by a client.
+// Unknown actions and unknown fields are rejected.
+func DecodeClientMessage(data []byte) (*Message, error) {
+	var env envelope
+	if err := decodeStrict(data, &env); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	hasPayload := len(env.Payload) > 0 && !bytes.Equal(env.Payload, []byte("null"))
+	return NewClientMessage(env.Action, hasPayload, func(v interface{}) error {
+		return decodeStrict(env.Payload, v)
+	})
+}
+
+func IsServerOnlyAction(action ActionType) bool {
+	_, fromClient := clientPayloads[action]
+	_, fromServer := serverPayloads[action]
+	return fromServer && !fromClient
+}
+
+// IsKnownAction reports whether action is part of the protocol in either
+// direction. Extension actions are not.
+func IsKnownAction(action ActionType) bool {
+	_, fromClient := clientPayloads[action]
+	_, fromServer := serverPayloads[action]
+	return fromClient || fromServer
+}
+
+func IsExtensionAction(action ActionType) bool {
+	name := string(action)
+	return strings.HasPrefix(name, ExtensionActionPrefix) && len(name) > len(ExtensionActionPrefix)
+}
+
+// NegotiateVersion picks the protocol version to speak with a client that
+// supports versions up to requested.
+func NegotiateVersion(requested int) (int, error) {
+	if requested < MinProtocolVersion {
+		return 0, fmt.Errorf("%w: %d", ErrUnsupportedVersion, requested)
+	}
+	if requested > ProtocolVersion {
+		return ProtocolVersion, nil
+	}
+	return requested, nil
+}
+
+// decodeStrict decodes data, which must hold exactly one JSON value, into v.
+func decodeStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after the message")
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeClientMessageTypesPayloads(t *testing.T) {
+	msg, err := DecodeClientMessage([]byte(`{"action":"submit","payload":{"userId":"u1","vote":"5"}}`))
+	if err != nil {
+		t.Fatalf("DecodeClientMessage() error = %v", err)
+	}
+	payload, ok := msg.Payload.(*SubmitPayload)
+	if !ok {
+		t.Fatalf("payload is %T, want *SubmitPayload", msg.Payload)
+	}
+	if payload.UserId != "u1" || payload.Vote != "5" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	msg, err = DecodeClientMessage([]byte(`{"action":"transfer","payload":{"userId":"u1","newScrumMasterId":"u2"}}`))
+	if err != nil {
+		t.Fatalf("DecodeClientMessage() error = %v", err)
+	}
+	if transfer, ok := msg.Payload.(*TransferPayload); !ok || transfer.NewScrumMasterId != "u2" {
+		t.Errorf("payload = %#v, want a transfer to u2", msg.Payload)
+	}
+}
+
+func TestDecodeClientMessageRejects(t *testing.T) {
+	for name, data := range map[string]string{
+		"extra envelope field":   `{"action":"ping","payload":{"userId":"u1"},"seq":3}`,
+		"extra payload field":    `{"action":"ping","payload":{"userId":"u1","admin":true}}`,
+		"payload of wrong type":  `{"action":"submit","payload":{"userId":"u1","vote":5}}`,
+		"payload is not object":  `{"action":"submit","payload":"5"}`,
+		"action is not a string": `{"action":1,"payload":{}}`,
+		"not JSON":               `submit 5`,
+		"trailing garbage":       `{"action":"ping","payload":{"userId":"u1"}}}`,
+	} {
+		if _, err := DecodeClientMessage([]byte(data)); err == nil {
+			t.Errorf("%s: DecodeClientMessage(%s) accepted the message", name, data)
+		}
+	}
+}
+
+func TestDecodeClientMessageActionErrors(t *testing.T) {
+	if _, err := DecodeClientMessage([]byte(`{"action":"vote","payload":{}}`)); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("unknown action: error = %v, want ErrUnknownAction", err)
+	}
+
+	// Actions that only the server emits must not be forged by clients.
+	for _, action := range []ActionType{ActionTypeJoin, ActionTypeOnline, ActionTypeOffline, ActionTypePong, ActionTypeSnapshot, ActionTypeRoomClosed} {
+		data := `{"action":"` + string(action) + `","payload":{"userId":"u1"}}`
+		if _, err := DecodeClientMessage([]byte(data)); !errors.Is(err, ErrServerOnlyAction) {
+			t.Errorf("%s: error = %v, want ErrServerOnlyAction", action, err)
+		}
+	}
+
+	for _, data := range []string{`{"action":"reveal"}`, `{"action":"reveal","payload":null}`} {
+		if _, err := DecodeClientMessage([]byte(data)); !errors.Is(err, ErrMissingPayload) {
+			t.Errorf("DecodeClientMessage(%s) error = %v, want ErrMissingPayload", data, err)
+		}
+	}
+}
+
+func TestExtensionActions(t *testing.T) {
+	msg, err := DecodeClientMessage([]byte(`{"action":"x-timer","payload":{"seconds":30,"labels":["a"]}}`))
+	if err != nil {
+		t.Fatalf("DecodeClientMessage() error = %v", err)
+	}
+	data, ok := msg.Payload.(json.RawMessage)
+	if !ok {
+		t.Fatalf("extension payload is %T, want json.RawMessage", msg.Payload)
+	}
+	if !strings.Contains(string(data), `"seconds":30`) {
+		t.Errorf("extension payload = %s, want it passed through", data)
+	}
+
+	// The bare prefix names no extension, and extensions are never protocol
+	// actions, whichever direction.
+	if _, err := DecodeClientMessage([]byte(`{"action":"x-","payload":{}}`)); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf(`"x-": error = %v, want ErrUnknownAction`, err)
+	}
+	if IsExtensionAction(ActionTypeSubmit) || IsKnownAction("x-timer") {
+		t.Error("extension and protocol actions overlap")
+	}
+	if _, err := DecodeClientMessage([]byte(`{"action":"x-timer"}`)); !errors.Is(err, ErrMissingPayload) {
+		t.Errorf("extension without payload: error = %v, want ErrMissingPayload", err)
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	if got, err := NegotiateVersion(ProtocolVersion); err != nil || got != ProtocolVersion {
+		t.Errorf("NegotiateVersion(current) = %d, %v", got, err)
+	}
+	if got, err := NegotiateVersion(ProtocolVersion + 5); err != nil || got != ProtocolVersion {
+		t.Errorf("a newer client is spoken to in %d (%v), want %d", got, err, ProtocolVersion)
+	}
+	if _, err := NegotiateVersion(MinProtocolVersion - 1); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("a client older than %d: error = %v, want ErrUnsupportedVersion", MinProtocolVersion, err)
+	}
+}
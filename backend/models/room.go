@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"sync"
 	"time"
 )
@@ -13,21 +14,48 @@ type Room struct {
 	Participants  map[string]*User  `json:"participants"`
 	Votes         map[string]string `json:"votes"`
 	VotesRevealed bool              `json:"votesRevealed"`
-	Mu            sync.Mutex        `json:"-"`
+	// OwnerAccountId is the account that created the room, if any. Only
+	// the owner may stop guests without an account from joining.
+	OwnerAccountId string `json:"-"`
+	AllowGuests    bool   `json:"allowGuests"`
+	// AllowedEmailDomains, when set, limits the room to accounts with a
+	// verified email address in one of the domains.
+	AllowedEmailDomains []string `json:"allowedEmailDomains"`
+	// Persistent rooms are kept while nobody is in them.
+	Persistent   bool      `json:"persistent"`
+	LastActiveAt time.Time `json:"-"`
+	// ArchivedAt is set once the room is archived. Archived rooms have no
+	// participants and only their results are kept.
+	ArchivedAt *time.Time `json:"archivedAt"`
+	Mu         sync.Mutex `json:"-"`
 }
 
 func NewRoom(id, name, scrumMasterID string) *Room {
+	now := time.Now()
 	return &Room{
-		Id:            id,
-		Name:          name,
-		CreatedAt:     time.Now(),
-		ScrumMaster:   scrumMasterID,
-		Participants:  make(map[string]*User),
-		Votes:         make(map[string]string),
-		VotesRevealed: false,
+		Id:                  id,
+		Name:                name,
+		CreatedAt:           now,
+		ScrumMaster:         scrumMasterID,
+		Participants:        make(map[string]*User),
+		Votes:               make(map[string]string),
+		VotesRevealed:       false,
+		AllowGuests:         true,
+		AllowedEmailDomains: []string{},
+		LastActiveAt:        now,
 	}
 }
 
+func (r *Room) IsArchived() bool {
+	return r.ArchivedAt != nil
+}
+
+// IsRestricted reports whether the room admits only signed-in users, and
+// so shows its participants and votes only to its members.
+func (r *Room) IsRestricted() bool {
+	return !r.AllowGuests || len(r.AllowedEmailDomains) > 0
+}
+
 func (r *Room) AddParticipant(user *User) {
 	r.Mu.Lock()
 	defer r.Mu.Unlock()
@@ -100,14 +128,76 @@ func (r *Room) ToJSON() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"id":            r.Id,
-		"name":          r.Name,
-		"createdAt":     r.CreatedAt,
-		"scrumMaster":   r.ScrumMaster,
-		"participants":  participants,
-		"votes":         votes,
-		"votesRevealed": r.VotesRevealed,
+		"id":                  r.Id,
+		"name":                r.Name,
+		"createdAt":           r.CreatedAt,
+		"scrumMaster":         r.ScrumMaster,
+		"participants":        participants,
+		"votes":               votes,
+		"votesRevealed":       r.VotesRevealed,
+		"allowGuests":         r.AllowGuests,
+		"allowedEmailDomains": r.AllowedEmailDomains,
+		"persistent":          r.Persistent,
+		"archivedAt":          r.ArchivedAt,
+	}
+}
+
+func (r *Room) ToSnapshot() *RoomSnapshotPayload {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+
+	participants := make(map[string]*User, len(r.Participants))
+	for id, user := range r.Participants {
+		participants[id] = user
+	}
+
+	votes := make(map[string]string, len(r.Votes))
+	for id, vote := range r.Votes {
+		if r.VotesRevealed {
+			votes[id] = vote
+		} else {
+			votes[id] = "voted"
+		}
+	}
+
+	return &RoomSnapshotPayload{
+		Id:            r.Id,
+		Name:          r.Name,
+		CreatedAt:     r.CreatedAt,
+		ScrumMaster:   r.ScrumMaster,
+		Participants:  participants,
+		Votes:         votes,
+		VotesRevealed: r.VotesRevealed,
+	}
+}
+
+// ParticipantNames lists the names in use in the room, leaving out the
+// participant exceptId.
+func (r *Room) ParticipantNames(exceptId string) []string {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+
+	names := make([]string, 0, len(r.Participants))
+	for id, user := range r.Participants {
+		if id != exceptId {
+			names = append(names, user.Name)
+		}
+	}
+	return names
+}
+
+// AdmitsEmailDomain reports whether a verified address in domain satisfies
+// the room's email domain restriction.
+func (r *Room) AdmitsEmailDomain(domain string) bool {
+	if len(r.AllowedEmailDomains) == 0 {
+		return true
+	}
+	for _, allowed := range r.AllowedEmailDomains {
+		if strings.EqualFold(allowed, domain) {
+			return true
+		}
 	}
+	return false
 }
 
 func (r *Room) RemoveVote(userId string) {
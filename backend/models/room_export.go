@@ -0,0 +1,57 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// RoomExport is a complete copy of a room's state, votes included, for
+// keeping or moving results outside the application.
+type RoomExport struct {
+	Id                  string          `json:"id"`
+	Name                string          `json:"name"`
+	CreatedAt           time.Time       `json:"createdAt"`
+	ExportedAt          time.Time       `json:"exportedAt"`
+	ArchivedAt          *time.Time      `json:"archivedAt,omitempty"`
+	ScrumMaster         string          `json:"scrumMaster"`
+	AllowGuests         bool            `json:"allowGuests"`
+	AllowedEmailDomains []string        `json:"allowedEmailDomains"`
+	Participants        []*ExportedVote `json:"participants"`
+}
+
+// ExportedVote is a participant with their current vote, empty if they
+// have not voted.
+type ExportedVote struct {
+	UserId string `json:"userId"`
+	Name   string `json:"name"`
+	Vote   string `json:"vote"`
+}
+
+func (r *Room) ToExport() *RoomExport {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+
+	participants := make([]*ExportedVote, 0, len(r.Participants))
+	for id, user := range r.Participants {
+		participants = append(participants, &ExportedVote{
+			UserId: id,
+			Name:   user.Name,
+			Vote:   r.Votes[id],
+		})
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].Name < participants[j].Name
+	})
+
+	return &RoomExport{
+		Id:                  r.Id,
+		Name:                r.Name,
+		CreatedAt:           r.CreatedAt,
+		ExportedAt:          time.Now(),
+		ArchivedAt:          r.ArchivedAt,
+		ScrumMaster:         r.ScrumMaster,
+		AllowGuests:         r.AllowGuests,
+		AllowedEmailDomains: r.AllowedEmailDomains,
+		Participants:        participants,
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "testing"
+
+func TestRoomAdmitsEmailDomains(t *testing.T) {
+	room := NewRoom("room-1", "Refinement", "user-1")
+	if room.IsRestricted() || !room.AdmitsEmailDomain("anywhere.org") {
+		t.Fatal("a new room should admit everyone")
+	}
+
+	room.AllowedEmailDomains = []string{"example.com"}
+	if !room.IsRestricted() {
+		t.Error("a room limited to email domains should be restricted")
+	}
+
+	account := &Account{Email: "alice@mail.example.com"}
+	if room.AdmitsEmailDomain(account.EmailDomain()) {
+		t.Error("a subdomain of an allowed domain was admitted")
+	}
+	account.Email = "alice@example.com"
+	if !room.AdmitsEmailDomain(account.EmailDomain()) || !room.AdmitsEmailDomain("EXAMPLE.com") {
+		t.Error("the allowed domain was not admitted")
+	}
+
+	room.AllowedEmailDomains = nil
+	room.AllowGuests = false
+	if !room.IsRestricted() {
+		t.Error("a room closed to guests should be restricted")
+	}
+}
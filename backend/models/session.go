@@ -4,6 +4,9 @@ import (
 	"time"
 )
 
+// Session is a browser session's membership in one room. A session may hold
+// memberships in several rooms, each with its own user and expiry; Id is
+// shared between them.
 type Session struct {
 	Id        string    `json:"id"`
 	UserId    string    `json:"userId"`
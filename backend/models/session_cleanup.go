@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// CleanupReport describes what a session cleanup sweep did.
+type CleanupReport struct {
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	// Expired counts the expired memberships found, across Rooms rooms.
+	Expired int `json:"expired"`
+	Rooms   int `json:"rooms"`
+	// Refreshed counts the memberships extended because their participant
+	// is still connected, Removed the participants removed.
+	Refreshed       int   `json:"refreshed"`
+	Removed         int   `json:"removed"`
+	SessionsDeleted int64 `json:"sessionsDeleted"`
+	// RoomsArchived counts the rooms archived for being idle or left empty,
+	// RoomsPurged the archived rooms deleted once their retention ended.
+	RoomsArchived int   `json:"roomsArchived"`
+	RoomsPurged   int64 `json:"roomsPurged"`
+	// Errors counts the steps that failed; the sweep carries on past them.
+	Errors int `json:"errors"`
+}
+
+// RoomCleanup is the outcome of removing a room's expired participants.
+type RoomCleanup struct {
+	RemovedUserIds []string
+	// Archived is set when the room was left empty and archived.
+	Archived bool
+	// PreviousScrumMaster differs from ScrumMaster when the Scrum Master was
+	// removed and another participant took over.
+	PreviousScrumMaster string
+	ScrumMaster         string
+}
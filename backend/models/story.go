@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Story is an item for the room to estimate, such as a backlog entry loaded
+// by a script before the meeting. A room's stories keep the order in which
+// they were added.
+type Story struct {
+	Id        string    `json:"id"`
+	RoomId    string    `json:"roomId"`
+	Title     string    `json:"title"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NewStory(id, roomId, title string) *Story {
+	return &Story{
+		Id:        id,
+		RoomId:    roomId,
+		Title:     title,
+		CreatedAt: time.Now(),
+	}
+}
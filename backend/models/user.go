@@ -4,24 +4,54 @@ import (
 	"time"
 )
 
+// A participant's role in its room. Observers follow the room but cannot
+// vote.
+const (
+	UserRoleVoter    = "voter"
+	UserRoleObserver = "observer"
+)
+
 type User struct {
-	Id        string    `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"createdAt"`
+	Id         string    `json:"id"`
+	Name       string    `json:"name"`
+	Role       string    `json:"role"`
+	AvatarSeed string    `json:"avatarSeed"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ProfileId  string    `json:"-"`
 }
 
 func NewUser(id, name string) *User {
 	return &User{
 		Id:        id,
 		Name:      name,
+		Role:      UserRoleVoter,
 		CreatedAt: time.Now(),
 	}
 }
 
+// ApplyProfile links the user to profile, taking its avatar seed and joining
+// as an observer if that is the profile's preferred role. Every other
+// preference joins as a voter; facilitating is decided by the room.
+func (u *User) ApplyProfile(profile *Profile) {
+	u.ProfileId = profile.Id
+	u.AvatarSeed = profile.AvatarSeed
+	if profile.PreferredRole == ProfileRoleObserver {
+		u.Role = UserRoleObserver
+	} else {
+		u.Role = UserRoleVoter
+	}
+}
+
+func (u *User) IsObserver() bool {
+	return u.Role == UserRoleObserver
+}
+
 func (u *User) ToJSON() map[string]interface{} {
 	return map[string]interface{}{
-		"id":        u.Id,
-		"name":      u.Name,
-		"createdAt": u.CreatedAt,
+		"id":         u.Id,
+		"name":       u.Name,
+		"role":       u.Role,
+		"avatarSeed": u.AvatarSeed,
+		"createdAt":  u.CreatedAt,
 	}
 }
@@ -0,0 +1,25 @@
+package models
+
+import "testing"
+
+func TestApplyProfile(t *testing.T) {
+	roles := map[string]string{
+		ProfileRoleVoter:       UserRoleVoter,
+		ProfileRoleObserver:    UserRoleObserver,
+		ProfileRoleFacilitator: UserRoleVoter,
+		"":                     UserRoleVoter,
+	}
+
+	for preferred, want := range roles {
+		user := NewUser("user-1", "Alice")
+		user.Role = UserRoleObserver
+		user.ApplyProfile(NewProfile("profile-1", "Alice", "seed-1", preferred))
+
+		if user.Role != want {
+			t.Errorf("user with preferred role %q joined as %q, want %q", preferred, user.Role, want)
+		}
+		if user.ProfileId != "profile-1" || user.AvatarSeed != "seed-1" {
+			t.Errorf("user has profile %q and avatar seed %q, want profile-1 and seed-1", user.ProfileId, user.AvatarSeed)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Bucket is a token bucket. A nil Bucket allows everything, which is how
+// disabled limits are represented.
+type Bucket struct {
+	limiter *rate.Limiter
+}
+
+func NewBucket(perSecond float64, burst int) *Bucket {
+	return &Bucket{limiter: rate.NewLimiter(rate.Limit(perSecond), burst)}
+}
+
+// Allow takes a token if one is available. Otherwise it reports how long the
+// caller should wait before trying again.
+func (b *Bucket) Allow() (bool, time.Duration) {
+	ok, retryAfter, _ := b.reserve()
+	return ok, retryAfter
+}
+
+// reserve takes a token like Allow and also returns a function that puts it
+// back, for when a later check turns the request down.
+func (b *Bucket) reserve() (bool, time.Duration, func()) {
+	if b == nil {
+		return true, 0, func() {}
+	}
+
+	// Cancelling at the time of the reservation, rather than later, returns
+	// a token that was due at once.
+	now := time.Now()
+	reservation := b.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, time.Second, nil
+	}
+
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay, nil
+	}
+	return true, 0, func() { reservation.CancelAt(now) }
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+const idleTTL = 10 * time.Minute
+
+type entry struct {
+	bucket   *Bucket
+	lastSeen time.Time
+}
+
+// Limiter keeps one Bucket per key, e.g. per user id or per IP address.
+// Buckets that have not been used for a while are dropped.
+type Limiter struct {
+	perSecond float64
+	burst     int
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+func NewLimiter(perSecond float64, burst int) *Limiter {
+	return &Limiter{
+		perSecond: perSecond,
+		burst:     burst,
+		entries:   make(map[string]*entry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	ok, retryAfter, _ := l.reserve(key)
+	return ok, retryAfter
+}
+
+// reserve takes a token from key's bucket like Allow and also returns a
+// function that puts it back.
+func (l *Limiter) reserve(key string) (bool, time.Duration, func()) {
+	if l == nil {
+		return true, 0, func() {}
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+	if now.Sub(l.lastSweep) > idleTTL {
+		l.sweep(now)
+	}
+
+	e, exists := l.entries[key]
+	if !exists {
+		e = &entry{bucket: NewBucket(l.perSecond, l.burst)}
+		l.entries[key] = e
+	}
+	e.lastSeen = now
+	l.mu.Unlock()
+
+	return e.bucket.reserve()
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for key, e := range l.entries {
+		if now.Sub(e.lastSeen) > idleTTL {
+			delete(l.entries, key)
+		}
+	}
+	l.lastSweep = now
+}
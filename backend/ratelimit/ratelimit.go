@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/logging"
+)
+
+// Route names a kind of request that is limited per source IP with a
+// budget of its own.
+type Route string
+
+const (
+	// RouteRooms covers creating, joining and setting up rooms.
+	RouteRooms Route = "rooms"
+	// RouteAuth covers registering, signing in and issuing API tokens.
+	RouteAuth Route = "auth"
+	// RouteUpgrade covers opening WebSocket and SSE connections.
+	RouteUpgrade Route = "upgrade"
+)
+
+var (
+	users     *Limiter
+	addresses *Limiter
+	requests  map[Route]*Limiter
+)
+
+func Init() {
+	cfg := config.Cfg.RateLimit
+	if !cfg.Enabled {
+		slog.Info("Rate limiting is disabled")
+		return
+	}
+
+	users = NewLimiter(cfg.UserRate, cfg.UserBurst)
+	addresses = NewLimiter(cfg.AddressRate, cfg.AddressBurst)
+	requests = map[Route]*Limiter{
+		RouteRooms:   NewLimiter(cfg.RequestRate, cfg.RequestBurst),
+		RouteAuth:    NewLimiter(cfg.AuthRate, cfg.AuthBurst),
+		RouteUpgrade: NewLimiter(cfg.UpgradeRate, cfg.UpgradeBurst),
+	}
+}
+
+// NewConnectionBucket returns the bucket for a single WebSocket connection,
+// or nil when rate limiting is disabled.
+func NewConnectionBucket() *Bucket {
+	cfg := config.Cfg.RateLimit
+	if !cfg.Enabled {
+		return nil
+	}
+	return NewBucket(cfg.ConnectionRate, cfg.ConnectionBurst)
+}
+
+// AllowAction checks a real-time action against the connection, user and
+// source IP limits. An action turned down by one limit spends no tokens of
+// the others.
+func AllowAction(connection *Bucket, userId, ip string) (bool, time.Duration) {
+	ok, retryAfter, cancelConnection := connection.reserve()
+	if !ok {
+		return false, retryAfter
+	}
+	ok, retryAfter, cancelUser := users.reserve(userId)
+	if !ok {
+		cancelConnection()
+		return false, retryAfter
+	}
+	if ok, retryAfter, _ := addresses.reserve(ip); !ok {
+		cancelUser()
+		cancelConnection()
+		return false, retryAfter
+	}
+	return true, 0
+}
+
+// Middleware limits requests per source IP against route's budget and
+// answers 429 when it is exceeded.
+func Middleware(route Route, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := ClientIP(r)
+		if ok, retryAfter := requests[route].Allow(ip); !ok {
+			logging.FromRequest(r).Warn("Throttled request", "method", r.Method, "path", r.URL.Path, "route", route, "ip", ip)
+			WriteThrottled(w, retryAfter)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func WriteThrottled(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, "Too many requests", http.StatusTooManyRequests)
+}
+
+// ClientIP returns the address the request originates from. Proxy headers are
+// only honoured when RATE_LIMIT_TRUST_PROXY is set.
+func ClientIP(r *http.Request) string {
+	if config.Cfg.RateLimit.TrustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return strings.TrimSpace(realIP)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowActionSpendsNothingWhenTurnedDown(t *testing.T) {
+	tests := []struct {
+		name      string
+		userBurst int
+		ipBurst   int
+	}{
+		{name: "user limit", userBurst: 1, ipBurst: 10},
+		{name: "address limit", userBurst: 10, ipBurst: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previousUsers, previousAddresses := users, addresses
+			users = NewLimiter(0.001, tt.userBurst)
+			addresses = NewLimiter(0.001, tt.ipBurst)
+			t.Cleanup(func() { users, addresses = previousUsers, previousAddresses })
+
+			connection := NewBucket(0.001, 3)
+			if ok, _ := AllowAction(connection, "user", "10.0.0.1"); !ok {
+				t.Fatal("first action was turned down")
+			}
+			if ok, retryAfter := AllowAction(connection, "user", "10.0.0.1"); ok || retryAfter <= 0 {
+				t.Fatalf("second action = %v, %v; want it turned down with a delay", ok, retryAfter)
+			}
+
+			// The rejected action must have left the connection its
+			// remaining two tokens, and the limit that was not exceeded its
+			// own.
+			for i := 0; i < 2; i++ {
+				if ok, _ := connection.Allow(); !ok {
+					t.Fatalf("connection token %d was spent by the rejected action", i+1)
+				}
+			}
+			if tt.userBurst > 1 {
+				for i := 0; i < tt.userBurst-1; i++ {
+					if ok, _ := users.Allow("user"); !ok {
+						t.Fatalf("user token %d was spent by the rejected action", i+1)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDisabledLimitsAllowEverything(t *testing.T) {
+	previousUsers, previousAddresses := users, addresses
+	users, addresses = nil, nil
+	t.Cleanup(func() { users, addresses = previousUsers, previousAddresses })
+
+	for i := 0; i < 100; i++ {
+		if ok, _ := AllowAction(nil, "user", "10.0.0.1"); !ok {
+			t.Fatal("action turned down with rate limiting disabled")
+		}
+	}
+}
+
+func TestRoutesHaveBudgetsOfTheirOwn(t *testing.T) {
+	previous := requests
+	requests = map[Route]*Limiter{
+		RouteRooms:   NewLimiter(0.001, 2),
+		RouteAuth:    NewLimiter(0.001, 2),
+		RouteUpgrade: NewLimiter(0.001, 2),
+	}
+	t.Cleanup(func() { requests = previous })
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handlers := map[Route]http.Handler{
+		RouteRooms:   Middleware(RouteRooms, ok),
+		RouteAuth:    Middleware(RouteAuth, ok),
+		RouteUpgrade: Middleware(RouteUpgrade, ok),
+	}
+	call := func(route Route, remoteAddr string) int {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handlers[route].ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// A team behind one address reconnects until its upgrade budget runs
+	// out...
+	for i := 0; i < 2; i++ {
+		if code := call(RouteUpgrade, "203.0.113.7:5000"); code != http.StatusOK {
+			t.Fatalf("upgrade %d answered %d", i+1, code)
+		}
+	}
+	if code := call(RouteUpgrade, "203.0.113.7:5001"); code != http.StatusTooManyRequests {
+		t.Fatalf("upgrade past the burst answered %d, want 429", code)
+	}
+
+	// ...and can still join rooms and sign in, while other addresses are
+	// unaffected.
+	if code := call(RouteRooms, "203.0.113.7:5002"); code != http.StatusOK {
+		t.Errorf("join after the upgrades ran out answered %d", code)
+	}
+	if code := call(RouteAuth, "203.0.113.7:5003"); code != http.StatusOK {
+		t.Errorf("sign-in after the upgrades ran out answered %d", code)
+	}
+	if code := call(RouteUpgrade, "198.51.100.1:5000"); code != http.StatusOK {
+		t.Errorf("upgrade from another address answered %d", code)
+	}
+}
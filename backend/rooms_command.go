@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/logic/admin_logic"
+	"github.com/scrum-poker/backend/models"
+)
+
+const roomsUsage = "usage: scrum-poker rooms list|show|delete [flags] [roomId]"
+
+// detachedHub stands in for the real-time hub outside the server. The
+// command line cannot see the server's connections, so every participant
+// counts as offline and nobody is notified or disconnected.
+type detachedHub struct{}
+
+func (detachedHub) Broadcast(string, *models.Message)   {}
+func (detachedHub) GetConnectedUserIds(string) []string { return nil }
+func (detachedHub) ConnectedRoomIds() []string          { return nil }
+func (detachedHub) CloseRoom(string, string) int        { return 0 }
+func (detachedHub) DisconnectUser(string, string) int   { return 0 }
+
+func runRoomsCommand(args []string) error {
+	action, roomId, err := parseRoomsArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Open(); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch action {
+	case "list":
+		return listRooms()
+	case "show":
+		return showRoom(roomId)
+	default:
+		if err := admin_logic.CloseRoom(detachedHub{}, roomId, ""); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted room %s\n", roomId)
+		return nil
+	}
+}
+
+// parseRoomsArgs loads the configuration for a `rooms` subcommand and returns
+// the subcommand and, except for list, the room it acts on.
+func parseRoomsArgs(args []string) (string, string, error) {
+	if len(args) == 0 {
+		return "", "", errors.New(roomsUsage)
+	}
+
+	want := 1
+	switch args[0] {
+	case "list":
+		want = 0
+	case "show", "delete":
+	default:
+		return "", "", errors.New(roomsUsage)
+	}
+
+	rest, err := loadCommand("rooms "+args[0], args[1:], nil)
+	if err != nil {
+		return "", "", err
+	}
+	if len(rest) != want {
+		return "", "", errors.New(roomsUsage)
+	}
+
+	if want == 0 {
+		return args[0], "", nil
+	}
+	return args[0], rest[0], nil
+}
+
+func listRooms() error {
+	rooms, err := admin_logic.ListRooms(detachedHub{})
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tPARTICIPANTS\tCREATED\tSTATE")
+	for _, room := range rooms {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", room.Id, room.Name, room.Participants, room.CreatedAt.Format(time.RFC3339), roomState(room))
+	}
+	return tw.Flush()
+}
+
+func roomState(room models.RoomSummary) string {
+	switch {
+	case room.ArchivedAt != nil:
+		return "archived"
+	case room.Persistent:
+		return "persistent"
+	default:
+		return "active"
+	}
+}
+
+func showRoom(roomId string) error {
+	inspection, err := admin_logic.InspectRoom(detachedHub{}, roomId)
+	if err != nil {
+		return err
+	}
+	return writeJSON(os.Stdout, inspection)
+}
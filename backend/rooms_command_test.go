@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+func TestRoomsArguments(t *testing.T) {
+	useCommandEnv(t)
+
+	valid := []struct {
+		args   []string
+		action string
+		roomId string
+	}{
+		{[]string{"list"}, "list", ""},
+		{[]string{"show", "room-1"}, "show", "room-1"},
+		{[]string{"delete", "-log-level", "warn", "room-1"}, "delete", "room-1"},
+	}
+	for _, v := range valid {
+		action, roomId, err := parseRoomsArgs(v.args)
+		if err != nil || action != v.action || roomId != v.roomId {
+			t.Errorf("parseRoomsArgs(%q) = %q, %q, %v; want %s %s", v.args, action, roomId, err, v.action, v.roomId)
+		}
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"inspect", "room-1"},
+		{"list", "room-1"},
+		{"show"},
+		{"delete", "room-1", "room-2"},
+		// Flags come before the room.
+		{"show", "room-1", "-log-level", "warn"},
+	} {
+		if _, _, err := parseRoomsArgs(args); err == nil {
+			t.Errorf("parseRoomsArgs(%q) was accepted", args)
+		}
+	}
+}
+
+func TestRoomState(t *testing.T) {
+	archivedAt := time.Now()
+	states := map[string]models.RoomSummary{
+		"active":     {},
+		"persistent": {Persistent: true},
+		"archived":   {Persistent: true, ArchivedAt: &archivedAt},
+	}
+	for want, room := range states {
+		if got := roomState(room); got != want {
+			t.Errorf("roomState() = %q, want %q", got, want)
+		}
+	}
+}
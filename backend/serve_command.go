@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/cors"
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/csrf"
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/handlers"
+	"github.com/scrum-poker/backend/logging"
+	"github.com/scrum-poker/backend/metrics"
+	"github.com/scrum-poker/backend/models"
+	"github.com/scrum-poker/backend/ratelimit"
+	"github.com/scrum-poker/backend/session"
+	"github.com/scrum-poker/backend/websocket"
+)
+
+// runServeCommand runs the HTTP server until it receives SIGINT or SIGTERM.
+func runServeCommand(args []string) error {
+	if err := config.Load(args); err != nil {
+		return err
+	}
+	logging.Init()
+	port := config.Cfg.Server.Port
+
+	if err := db.Connect(); err != nil {
+		return err
+	}
+
+	ratelimit.Init()
+	websocket.Init()
+
+	r := mux.NewRouter()
+	r.Use(logging.Middleware)
+	if config.Cfg.Metrics.Enabled {
+		r.Use(metrics.Middleware)
+	}
+	r.Use(csrf.Middleware)
+
+	r.HandleFunc("/health", handlers.HealthCheckHandler).Methods("GET")
+	r.HandleFunc("/livez", handlers.LivezHandler).Methods("GET")
+	r.HandleFunc("/readyz", handlers.ReadyzHandler).Methods("GET")
+	if config.Cfg.Metrics.Enabled {
+		r.Handle("/metrics", metrics.Handler()).Methods("GET")
+	}
+	r.HandleFunc("/protocol/schema", handlers.ProtocolSchemaHandler).Methods("GET")
+
+	r.Handle("/rooms", ratelimit.Middleware(ratelimit.RouteRooms, session.RequireScope(models.ScopeWrite, http.HandlerFunc(handlers.CreateRoomHandler)))).Methods("POST")
+	r.Handle("/rooms/{roomId}", session.RequireScope(models.ScopeRead, http.HandlerFunc(handlers.GetRoomHandler))).Methods("GET")
+	r.Handle("/rooms/{roomId}", session.RequireScope(models.ScopeFacilitate, http.HandlerFunc(handlers.UpdateRoomHandler))).Methods("PATCH")
+	r.Handle("/rooms/{roomId}/export", session.RequireScope(models.ScopeRead, http.HandlerFunc(handlers.ExportRoomHandler))).Methods("GET")
+	r.Handle("/rooms/{roomId}/stories", session.RequireScope(models.ScopeRead, http.HandlerFunc(handlers.GetStoriesHandler))).Methods("GET")
+	r.Handle("/rooms/{roomId}/stories", session.RequireScope(models.ScopeWrite, http.HandlerFunc(handlers.AddStoriesHandler))).Methods("POST")
+	r.Handle("/rooms/{roomId}/stories/{storyId}", session.RequireScope(models.ScopeFacilitate, http.HandlerFunc(handlers.DeleteStoryHandler))).Methods("DELETE")
+	r.Handle("/rooms/{roomId}/join", ratelimit.Middleware(ratelimit.RouteRooms, session.RequireScope(models.ScopeWrite, http.HandlerFunc(handlers.JoinRoomHandler)))).Methods("POST")
+	r.Handle("/rooms/{roomId}/events", ratelimit.Middleware(ratelimit.RouteUpgrade, session.RequireScope(models.ScopeRead, http.HandlerFunc(handlers.EventsHandler)))).Methods("GET")
+	r.Handle("/rooms/{roomId}/actions", session.RequireScope(models.ScopeWrite, http.HandlerFunc(handlers.ActionsHandler))).Methods("POST")
+
+	r.HandleFunc("/sessions", handlers.GetSessionHandler).Methods("GET")
+	r.HandleFunc("/sessions", handlers.DeleteSessionHandler).Methods("DELETE")
+
+	r.Handle("/accounts", ratelimit.Middleware(ratelimit.RouteAuth, http.HandlerFunc(handlers.RegisterHandler))).Methods("POST")
+	r.Handle("/accounts/login", ratelimit.Middleware(ratelimit.RouteAuth, http.HandlerFunc(handlers.LoginHandler))).Methods("POST")
+	r.HandleFunc("/accounts/logout", handlers.LogoutHandler).Methods("POST")
+	r.HandleFunc("/accounts/me", handlers.GetAccountHandler).Methods("GET")
+	r.Handle("/accounts/me/password", ratelimit.Middleware(ratelimit.RouteAuth, http.HandlerFunc(handlers.ChangePasswordHandler))).Methods("PUT")
+
+	r.Handle("/auth/oidc/login", ratelimit.Middleware(ratelimit.RouteAuth, http.HandlerFunc(handlers.OIDCLoginHandler))).Methods("GET")
+	r.HandleFunc("/auth/oidc/callback", handlers.OIDCCallbackHandler).Methods("GET")
+
+	r.Handle("/tokens", ratelimit.Middleware(ratelimit.RouteAuth, http.HandlerFunc(handlers.IssueTokenHandler))).Methods("POST")
+	r.HandleFunc("/tokens", handlers.ListTokensHandler).Methods("GET")
+	r.HandleFunc("/tokens/{tokenId}", handlers.RevokeTokenHandler).Methods("DELETE")
+
+	r.HandleFunc("/profiles/me", handlers.GetProfileHandler).Methods("GET")
+	r.Handle("/profiles/me", ratelimit.Middleware(ratelimit.RouteRooms, http.HandlerFunc(handlers.SaveProfileHandler))).Methods("PUT")
+	r.HandleFunc("/profiles/me", handlers.DeleteProfileHandler).Methods("DELETE")
+
+	r.Handle("/ws/{roomId}", ratelimit.Middleware(ratelimit.RouteUpgrade, http.HandlerFunc(handlers.WebSocketHandler)))
+
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(handlers.RequireAdmin)
+	admin.HandleFunc("/rooms", handlers.AdminListRoomsHandler).Methods("GET")
+	admin.HandleFunc("/rooms/{roomId}", handlers.AdminInspectRoomHandler).Methods("GET")
+	admin.HandleFunc("/rooms/{roomId}/close", handlers.AdminCloseRoomHandler).Methods("POST")
+	admin.HandleFunc("/rooms/{roomId}/participants/{userId}", handlers.AdminEvictUserHandler).Methods("DELETE")
+	admin.HandleFunc("/notices", handlers.AdminNoticeHandler).Methods("POST")
+	admin.HandleFunc("/session-cleanup", handlers.AdminSessionCleanupHandler).Methods("POST")
+
+	c := cors.New(cors.Options{
+		AllowedOrigins:   config.Cfg.AllowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{logging.RequestIDHeader},
+		AllowCredentials: true,
+	})
+	handler := c.Handler(r)
+
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: handler,
+	}
+	// Shutdown does not wait for hijacked WebSocket connections and would
+	// wait for SSE streams until the deadline, so the hub closes both once
+	// the listener stops accepting.
+	server.RegisterOnShutdown(websocket.GlobalHub.Shutdown)
+
+	go func() {
+		slog.Info("Server is running", "port", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fatal("Failed to start server", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	slog.Info("Shutting down server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Cfg.Server.ShutdownTimeout)
+	defer cancel()
+	shutdown(ctx, server)
+	slog.Info("Server stopped")
+	return nil
+}
+
+// shutdown stops accepting requests, drains the open ones and real-time
+// connections, then stops background work and closes the database. Steps
+// still running when ctx ends are abandoned.
+func shutdown(ctx context.Context, server *http.Server) {
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("HTTP server did not shut down cleanly", "error", err)
+	}
+	if err := websocket.GlobalHub.Wait(ctx); err != nil {
+		slog.Warn("WebSocket connections did not close in time", "error", err)
+	}
+
+	runWithin(ctx, "session cleanup", session.GlobalManager.StopCleanupProcess)
+	runWithin(ctx, "database", db.Close)
+}
+
+func runWithin(ctx context.Context, name string, stop func()) {
+	done := make(chan struct{})
+	go func() {
+		stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		slog.Warn("Gave up waiting for shutdown step", "step", name, "error", ctx.Err())
+	}
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/scrum-poker/backend/config"
+)
+
+const (
+	LoginStateCookieName = "oidcLogin"
+	loginStateCookiePath = "/auth/oidc"
+	loginStateTTL        = 10 * time.Minute
+)
+
+var ErrInvalidLoginState = errors.New("login state is missing or invalid")
+
+// LoginState carries what the OIDC callback must check between redirecting
+// the browser to the identity provider and it coming back.
+type LoginState struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"verifier"`
+	ReturnTo     string `json:"returnTo"`
+	ExpiresAt    int64  `json:"exp"`
+}
+
+// SetLoginStateCookie stores state in a short-lived signed cookie that is
+// only sent to the OIDC endpoints.
+func SetLoginStateCookie(w http.ResponseWriter, state LoginState) error {
+	state.ExpiresAt = time.Now().Add(loginStateTTL).Unix()
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     LoginStateCookieName,
+		Value:    signPayload(loginStatePurpose, payload),
+		Path:     loginStateCookiePath,
+		MaxAge:   int(loginStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   config.Cfg.Cookie.Secure,
+		// The identity provider redirects back with a top-level GET, which
+		// Lax cookies accompany.
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func LoginStateFromRequest(r *http.Request) (*LoginState, error) {
+	cookie, err := r.Cookie(LoginStateCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, ErrInvalidLoginState
+	}
+
+	payload, err := verifyPayload(loginStatePurpose, cookie.Value)
+	if err != nil {
+		return nil, ErrInvalidLoginState
+	}
+
+	var state LoginState
+	if err := json.Unmarshal(payload, &state); err != nil || time.Now().Unix() >= state.ExpiresAt {
+		return nil, ErrInvalidLoginState
+	}
+	return &state, nil
+}
+
+func ClearLoginStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     LoginStateCookieName,
+		Value:    "",
+		Path:     loginStateCookiePath,
+		HttpOnly: true,
+		MaxAge:   -1,
+		Secure:   config.Cfg.Cookie.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,54 @@
+package session
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/scrum-poker/backend/config"
+)
+
+const (
+	ProfileCookieName   = "profileId"
+	profileCookieMaxAge = 365 * 24 * time.Hour
+)
+
+// ProfileIdFromRequest returns the profile id bound to the requesting device,
+// or an empty string when the request carries no valid profile cookie.
+func ProfileIdFromRequest(r *http.Request) string {
+	cookie, err := r.Cookie(ProfileCookieName)
+	if err != nil || cookie.Value == "" {
+		return ""
+	}
+
+	payload, err := verifyPayload(profileTokenPurpose, cookie.Value)
+	if err != nil {
+		return ""
+	}
+	return string(payload)
+}
+
+// SetProfileCookie binds profileId to the device with a long-lived signed
+// cookie.
+func SetProfileCookie(w http.ResponseWriter, profileId string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     ProfileCookieName,
+		Value:    signPayload(profileTokenPurpose, []byte(profileId)),
+		Path:     "/",
+		MaxAge:   int(profileCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   config.Cfg.Cookie.Secure,
+		SameSite: config.Cfg.Cookie.SameSite,
+	})
+}
+
+func ClearProfileCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     ProfileCookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   -1,
+		Secure:   config.Cfg.Cookie.Secure,
+		SameSite: config.Cfg.Cookie.SameSite,
+	})
+}
@@ -0,0 +1,92 @@
+package session
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/metrics"
+	"github.com/scrum-poker/backend/models"
+)
+
+const idleArchivedReason = "The room was archived after a period of inactivity"
+
+// archiveIdleRooms archives the rooms that have gone without activity for
+// longer than their idle limit.
+func (m *Manager) archiveIdleRooms(report *models.CleanupReport) {
+	idleBefore := policyCutoff(report.StartedAt, config.Cfg.Rooms.IdleTTL)
+	persistentIdleBefore := policyCutoff(report.StartedAt, config.Cfg.Rooms.PersistentIdleTTL)
+	if idleBefore.IsZero() && persistentIdleBefore.IsZero() {
+		return
+	}
+
+	roomIds, err := db.GetIdleRoomIds(idleBefore, persistentIdleBefore)
+	if err != nil {
+		slog.Error("Error getting idle rooms", "error", err)
+		report.Errors++
+		return
+	}
+
+	for _, roomId := range roomIds {
+		logger := slog.With("room_id", roomId)
+
+		archived := false
+		err := db.InTx(func(tx *db.Tx) error {
+			room, err := tx.GetRoomForUpdate(roomId)
+			if err != nil {
+				return err
+			}
+			// Someone may have been active since the rooms were listed.
+			limit := idleBefore
+			if room.Persistent {
+				limit = persistentIdleBefore
+			}
+			if room.IsArchived() || limit.IsZero() || !room.LastActiveAt.Before(limit) {
+				return nil
+			}
+			archived = true
+			return tx.ArchiveRoom(room)
+		})
+		if err != nil {
+			logger.Error("Error archiving idle room", "error", err)
+			report.Errors++
+			continue
+		}
+		if !archived {
+			continue
+		}
+
+		logger.Info("Room was idle, archived")
+		report.RoomsArchived++
+		metrics.RoomsArchived.Inc()
+		m.roomCloser(roomId, idleArchivedReason)
+	}
+}
+
+// purgeArchivedRooms deletes the rooms archived longer ago than the
+// retention period.
+func (m *Manager) purgeArchivedRooms(report *models.CleanupReport) {
+	archivedBefore := policyCutoff(report.StartedAt, config.Cfg.Rooms.ArchiveRetention)
+	if archivedBefore.IsZero() {
+		return
+	}
+
+	purged, err := db.PurgeArchivedRooms(archivedBefore)
+	if err != nil {
+		slog.Error("Error purging archived rooms", "error", err)
+		report.Errors++
+		return
+	}
+	report.RoomsPurged = purged
+	metrics.RoomsPurged.Add(float64(purged))
+}
+
+// policyCutoff returns the time ttl before now, or the zero time when ttl turns
+// the policy off.
+func policyCutoff(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-ttl)
+}
@@ -1,142 +1,327 @@
 package session
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/scrum-poker/backend/config"
 	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/metrics"
 	"github.com/scrum-poker/backend/models"
 )
 
-const (
-	TTL             = 20 * time.Minute
-	CleanupInterval = 1 * time.Minute
-)
-
 var GlobalManager *Manager
 
 type Manager struct {
 	broadcastFunc     models.BroadcastFunc
 	connectionChecker models.ConnectionChecker
+	roomCloser        models.RoomCloser
+	// grace is how long past its expiry a membership is kept.
+	grace time.Duration
+
+	stop    chan struct{}
+	stopped chan struct{}
+	// sweep keeps sweeps triggered on demand from overlapping with the
+	// periodic ones.
+	sweep sync.Mutex
+
+	// heartbeat is when the cleanup loop last finished a sweep, in Unix
+	// nanoseconds.
+	heartbeat atomic.Int64
 }
 
-func NewManager(broadcastFunc models.BroadcastFunc, connectionChecker models.ConnectionChecker) *Manager {
+// staleHeartbeatIntervals is how many cleanup intervals may pass without a
+// finished sweep before the cleanup loop is considered stuck.
+const staleHeartbeatIntervals = 3
+
+func NewManager(broadcastFunc models.BroadcastFunc, connectionChecker models.ConnectionChecker, roomCloser models.RoomCloser) *Manager {
 	return &Manager{
 		broadcastFunc:     broadcastFunc,
 		connectionChecker: connectionChecker,
+		roomCloser:        roomCloser,
 	}
 }
 
-func InitSessionManager(broadcastFunc models.BroadcastFunc, connectionChecker models.ConnectionChecker) {
-	GlobalManager = NewManager(broadcastFunc, connectionChecker)
+// NewDetachedManager returns a manager for use outside the server, such as
+// from the command line: nothing is broadcast, nobody counts as connected
+// and no connections are closed. Only memberships that expired more than
+// grace ago are cleaned up, so that those a running server keeps refreshing
+// for connected participants are left alone; see DetachedGrace.
+func NewDetachedManager(grace time.Duration) *Manager {
+	m := NewManager(
+		func(string, *models.Message) {},
+		func(string, string) bool { return false },
+		func(string, string) int { return 0 },
+	)
+	m.grace = grace
+	return m
+}
+
+// DetachedGrace is the least grace for a detached manager. A running server
+// refreshes the memberships of connected participants on every sweep, so
+// they are never expired for longer than it takes /readyz to report the
+// cleanup stuck.
+func DetachedGrace() time.Duration {
+	return staleHeartbeatIntervals * config.Cfg.Session.CleanupInterval
+}
+
+func InitSessionManager(broadcastFunc models.BroadcastFunc, connectionChecker models.ConnectionChecker, roomCloser models.RoomCloser) {
+	GlobalManager = NewManager(broadcastFunc, connectionChecker, roomCloser)
 	GlobalManager.StartCleanupProcess()
-	log.Println("Session manager initialized and cleanup process started")
+	slog.Info("Session manager initialized and cleanup process started")
 }
 
-func (m *Manager) CreateSession(userId, roomId string) (string, error) {
-	sessionID := uuid.New().String()
-	session := models.NewSession(sessionID, userId, roomId, TTL)
+// CreateSession adds a membership in roomId as userId to session sessionId.
+// A new session is started when sessionId is empty or no longer exists.
+func (m *Manager) CreateSession(sessionId, userId, roomId string) (*models.Session, error) {
+	var session *models.Session
+	err := db.InTx(func(tx *db.Tx) error {
+		var err error
+		session, err = AddMembership(tx, sessionId, userId, roomId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
 
-	if err := db.CreateSession(session); err != nil {
-		return "", err
+// AddMembership is CreateSession as part of tx, for commands that add the
+// participant and their membership together.
+func AddMembership(tx *db.Tx, sessionId, userId, roomId string) (*models.Session, error) {
+	if sessionId != "" {
+		exists, err := tx.SessionExists(sessionId)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			sessionId = ""
+		}
+	}
+	if sessionId == "" {
+		sessionId = uuid.New().String()
 	}
 
-	return sessionID, nil
+	session := models.NewSession(sessionId, userId, roomId, config.Cfg.Session.MembershipTTL)
+	if err := tx.CreateSession(session); err != nil {
+		return nil, err
+	}
+	return session, nil
 }
 
 func (m *Manager) DeleteSession(sessionID string) error {
 	return db.DeleteSession(sessionID)
 }
 
+// SignIn signs accountId in to session sessionId, starting a new session when
+// sessionId is empty, and returns the session's id.
+func (m *Manager) SignIn(sessionId, accountId string) (string, error) {
+	if sessionId == "" {
+		sessionId = uuid.New().String()
+	}
+
+	now := time.Now()
+	if err := db.SignInSession(sessionId, accountId, now, now.Add(config.Cfg.Session.AccountTTL)); err != nil {
+		return "", err
+	}
+	return sessionId, nil
+}
+
+func (m *Manager) SignOut(sessionId string) error {
+	if err := db.SignOutSession(sessionId); err != nil {
+		return err
+	}
+	_, err := db.DeleteEmptySessions()
+	return err
+}
+
+// LeaveRoom ends the membership of session sessionId in roomId, deleting the
+// session once it has no rooms left.
+func (m *Manager) LeaveRoom(sessionID, roomId string) error {
+	return db.DeleteSessionMembership(sessionID, roomId)
+}
+
 func (m *Manager) StartCleanupProcess() {
-	ticker := time.NewTicker(CleanupInterval)
+	m.stop = make(chan struct{})
+	m.stopped = make(chan struct{})
+
+	m.heartbeat.Store(time.Now().UnixNano())
+
+	ticker := time.NewTicker(config.Cfg.Session.CleanupInterval)
 	go func() {
+		defer close(m.stopped)
+		defer ticker.Stop()
 		for {
-			<-ticker.C
-			m.cleanupExpiredSessions()
+			select {
+			case <-ticker.C:
+				m.RunCleanup()
+				m.heartbeat.Store(time.Now().UnixNano())
+			case <-m.stop:
+				return
+			}
 		}
 	}()
 }
 
-func (m *Manager) cleanupExpiredSessions() {
-	rooms, err := db.GetAllRooms()
-	if err != nil {
-		log.Printf("Error getting rooms: %v", err)
+// StopCleanupProcess stops the cleanup ticker and waits for a sweep that is
+// already running to finish.
+func (m *Manager) StopCleanupProcess() {
+	if m.stop == nil {
 		return
 	}
+	close(m.stop)
+	<-m.stopped
+	slog.Info("Session cleanup process stopped")
+}
 
-	for _, room := range rooms {
-		sessions, err := db.GetSessionsByRoomID(room.Id)
-		if err != nil {
-			log.Printf("Error getting sessions for room %s: %v", room.Id, err)
-			continue
-		}
+// CheckCleanup reports whether the cleanup loop is running and has finished
+// a sweep recently.
+func (m *Manager) CheckCleanup() error {
+	if m.stop == nil {
+		return errors.New("cleanup process not started")
+	}
+	select {
+	case <-m.stopped:
+		return errors.New("cleanup process stopped")
+	default:
+	}
+
+	age := time.Since(time.Unix(0, m.heartbeat.Load()))
+	if age > staleHeartbeatIntervals*config.Cfg.Session.CleanupInterval {
+		return fmt.Errorf("last sweep finished %s ago", age.Round(time.Second))
+	}
+	return nil
+}
+
+// RunCleanup sweeps expired sessions now, after any sweep in progress, and
+// reports what it did.
+func (m *Manager) RunCleanup() *models.CleanupReport {
+	m.sweep.Lock()
+	defer m.sweep.Unlock()
+	return m.cleanupExpiredSessions()
+}
+
+// cleanupExpiredSessions handles only the memberships that expired, room by
+// room: those of connected participants are refreshed in one batch, and the
+// others are removed in one transaction per room. Rooms are then archived
+// or purged according to their lifecycle.
+func (m *Manager) cleanupExpiredSessions() *models.CleanupReport {
+	defer prometheus.NewTimer(metrics.SessionCleanupDuration).ObserveDuration()
+
+	report := &models.CleanupReport{StartedAt: time.Now()}
+	defer func() {
+		report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	}()
 
-		for _, session := range sessions {
-			if session.IsExpired() {
-				userId := session.UserId
-
-				if _, exists := room.Participants[userId]; exists && m.connectionChecker(room.Id, userId) {
-					session.Refresh(TTL)
-					if err := db.UpdateSession(session); err != nil {
-						log.Printf("Error refreshing session: %v", err)
-					}
-					continue
-				} else if exists {
-					log.Printf("Session %s expired, cleaning up", session.Id)
-
-					if err := db.RemoveParticipantFromRoom(room.Id, userId); err != nil {
-						log.Printf("Error removing participant from room: %v", err)
-					}
-
-					if room.ScrumMaster == userId && len(room.Participants) > 0 {
-						participantsCopy := make(map[string]*models.User)
-						for id, user := range room.Participants {
-							if id != userId {
-								participantsCopy[id] = user
-							}
-						}
-
-						if len(participantsCopy) > 0 {
-							room.AssignRandomScrumMaster(participantsCopy)
-							if err := db.UpdateScrumMaster(room.Id, room.ScrumMaster); err != nil {
-								log.Printf("Error updating scrum master: %v", err)
-							}
-						}
-						message := &models.Message{
-							Action: models.ActionTypeTransfer,
-							Payload: map[string]interface{}{
-								"userId":           userId,
-								"newScrumMasterId": room.ScrumMaster,
-							},
-						}
-						m.broadcastFunc(room.Id, message)
-					}
-
-					if err := db.DeleteUser(userId); err != nil {
-						log.Printf("Error deleting user: %v", err)
-					}
-					if err := db.DeleteSession(session.Id); err != nil {
-						log.Printf("Error deleting session: %v", err)
-					}
-
-					message := &models.Message{
-						Action: models.ActionTypeLeave,
-						Payload: map[string]interface{}{
-							"userId": userId,
-						},
-					}
-					m.broadcastFunc(room.Id, message)
-				}
+	cutoff := report.StartedAt.Add(-m.grace)
+	expired, err := db.GetExpiredMemberships(cutoff)
+	if err != nil {
+		slog.Error("Error getting expired sessions", "error", err)
+		report.Errors++
+		return report
+	}
+	report.Expired = len(expired)
+
+	var connected []*models.Session
+	for start := 0; start < len(expired); {
+		roomId := expired[start].RoomId
+		end := start
+		var userIds []string
+		for ; end < len(expired) && expired[end].RoomId == roomId; end++ {
+			membership := expired[end]
+			if m.connectionChecker(roomId, membership.UserId) {
+				connected = append(connected, membership)
+			} else {
+				userIds = append(userIds, membership.UserId)
 			}
 		}
+		start = end
+		report.Rooms++
 
-		if len(room.Participants) == 0 {
-			log.Printf("Room %s is empty, deleting", room.Id)
-			if err := db.DeleteRoom(room.Id); err != nil {
-				log.Printf("Error deleting room: %v", err)
-			}
+		if len(userIds) > 0 {
+			m.removeExpiredParticipants(roomId, userIds, cutoff, report)
+		}
+	}
+
+	if len(connected) > 0 {
+		if err := db.RefreshMemberships(connected, time.Now().Add(config.Cfg.Session.MembershipTTL)); err != nil {
+			slog.Error("Error refreshing sessions", "error", err)
+			report.Errors++
+		} else {
+			report.Refreshed = len(connected)
+			metrics.SessionCleanupMemberships.WithLabelValues("refreshed").Add(float64(len(connected)))
+		}
+	}
+
+	m.archiveIdleRooms(report)
+	m.purgeArchivedRooms(report)
+
+	deleted, err := db.DeleteEmptySessions()
+	if err != nil {
+		slog.Error("Error deleting empty sessions", "error", err)
+		report.Errors++
+	}
+	report.SessionsDeleted = deleted
+	metrics.SessionCleanupSessions.Add(float64(deleted))
+
+	if report.Expired > 0 || report.SessionsDeleted > 0 || report.RoomsArchived > 0 || report.RoomsPurged > 0 || report.Errors > 0 {
+		slog.Info("Session cleanup finished",
+			"expired", report.Expired,
+			"rooms", report.Rooms,
+			"refreshed", report.Refreshed,
+			"removed", report.Removed,
+			"sessions_deleted", report.SessionsDeleted,
+			"rooms_archived", report.RoomsArchived,
+			"rooms_purged", report.RoomsPurged,
+			"errors", report.Errors,
+		)
+	}
+	return report
+}
+
+// removeExpiredParticipants removes the participants of roomId whose
+// memberships expired and tells the room.
+func (m *Manager) removeExpiredParticipants(roomId string, userIds []string, cutoff time.Time, report *models.CleanupReport) {
+	logger := slog.With("room_id", roomId)
+
+	cleanup, err := db.RemoveExpiredParticipants(roomId, userIds, cutoff)
+	if err != nil {
+		logger.Error("Error removing expired participants", "error", err)
+		report.Errors++
+		return
+	}
+	report.Removed += len(cleanup.RemovedUserIds)
+	metrics.SessionCleanupMemberships.WithLabelValues("removed").Add(float64(len(cleanup.RemovedUserIds)))
+
+	if cleanup.Archived {
+		logger.Info("Room is empty, archived", "removed", len(cleanup.RemovedUserIds))
+		report.RoomsArchived++
+		metrics.RoomsArchived.Inc()
+		return
+	}
+
+	for _, userId := range cleanup.RemovedUserIds {
+		logger.Info("Session expired, removed participant", "user_id", userId)
+		if userId == cleanup.PreviousScrumMaster && cleanup.ScrumMaster != userId {
+			m.broadcastFunc(roomId, &models.Message{
+				Action: models.ActionTypeTransfer,
+				Payload: &models.TransferPayload{
+					UserId:           userId,
+					NewScrumMasterId: cleanup.ScrumMaster,
+				},
+			})
 		}
+		m.broadcastFunc(roomId, &models.Message{
+			Action: models.ActionTypeLeave,
+			Payload: &models.UserPayload{
+				UserId: userId,
+			},
+		})
 	}
 }
@@ -0,0 +1,171 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/models"
+)
+
+const CookieName = "sessionId"
+
+var (
+	ErrNoSession      = errors.New("session cookie not found")
+	ErrSessionUnknown = errors.New("session not found")
+	ErrSessionExpired = errors.New("session expired")
+	ErrRoomMismatch   = errors.New("session is not a member of this room")
+)
+
+// ClaimsFromRequest verifies the token in the request's session cookie
+// without consulting the database.
+func ClaimsFromRequest(r *http.Request) (*Claims, error) {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, ErrNoSession
+	}
+	return VerifyToken(cookie.Value)
+}
+
+// IdFromRequest returns the id of the session the request's cookie or API
+// token refers to, or an empty string when it carries no valid token. New memberships are
+// added to this session so that one browser can take part in several rooms.
+func IdFromRequest(r *http.Request) string {
+	if token := APITokenFromRequest(r); token != nil {
+		return token.SessionId
+	}
+
+	claims, err := ClaimsFromRequest(r)
+	if err != nil {
+		return ""
+	}
+	return claims.SessionId
+}
+
+// AccountIdFromRequest returns the account signed in to the request's
+// session, or an empty string. The account named by the token is checked
+// against the database so that signing out takes effect immediately.
+func AccountIdFromRequest(r *http.Request) string {
+	if token := APITokenFromRequest(r); token != nil {
+		return token.AccountId
+	}
+
+	claims, err := ClaimsFromRequest(r)
+	if err != nil || claims.AccountId == "" {
+		return ""
+	}
+
+	accountId, expiresAt, err := db.GetSessionAccount(claims.SessionId)
+	if err != nil || accountId != claims.AccountId || time.Now().After(expiresAt) {
+		return ""
+	}
+	return accountId
+}
+
+// IdentityFromRequest gathers the device profile and signed-in account of
+// the request.
+func IdentityFromRequest(r *http.Request) models.Identity {
+	return models.Identity{
+		ProfileId: ProfileIdFromRequest(r),
+		AccountId: AccountIdFromRequest(r),
+	}
+}
+
+// FromRequest resolves the membership in roomId of the session referenced by
+// the request's session cookie or API token. The cookie's token is verified
+// first; the database is only consulted for tokens that name roomId, to
+// detect memberships that were revoked or expired since the token was issued.
+func FromRequest(r *http.Request, roomId string) (*models.Session, error) {
+	if token := APITokenFromRequest(r); token != nil {
+		return tokenMembership(token, roomId)
+	}
+
+	claims, err := ClaimsFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, ok := claims.Rooms[roomId]
+	if !ok {
+		return nil, ErrRoomMismatch
+	}
+
+	currSession, err := db.GetSession(claims.SessionId, roomId)
+	if err != nil {
+		return nil, ErrSessionUnknown
+	}
+
+	if currSession.UserId != userId {
+		return nil, ErrInvalidToken
+	}
+
+	if currSession.IsExpired() {
+		return nil, ErrSessionExpired
+	}
+
+	return currSession, nil
+}
+
+// SetCookie issues a fresh token listing the current memberships and account
+// of session sessionId and stores it in the session cookie. The cookie is
+// cleared when the session has neither left.
+func SetCookie(w http.ResponseWriter, sessionId string) error {
+	memberships, err := db.GetSessionMemberships(sessionId)
+	if err != nil {
+		return err
+	}
+
+	accountId, expiresAt, err := db.GetSessionAccount(sessionId)
+	if err != nil || time.Now().After(expiresAt) {
+		accountId = ""
+	}
+
+	active := make([]*models.Session, 0, len(memberships))
+	for _, membership := range memberships {
+		if !membership.IsExpired() {
+			active = append(active, membership)
+		}
+	}
+	if len(active) == 0 && accountId == "" {
+		ClearCookie(w)
+		return nil
+	}
+
+	token, err := IssueToken(sessionId, accountId, active)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   config.Cfg.Cookie.Secure,
+		SameSite: config.Cfg.Cookie.SameSite,
+	})
+	return nil
+}
+
+func ClearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		MaxAge:   -1,
+		Secure:   config.Cfg.Cookie.Secure,
+		SameSite: config.Cfg.Cookie.SameSite,
+	})
+}
+
+// WriteError translates a FromRequest error into an HTTP response.
+func WriteError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrRoomMismatch) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
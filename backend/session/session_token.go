@@ -0,0 +1,123 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/models"
+)
+
+var ErrInvalidToken = errors.New("invalid session token")
+
+// Claims are the session details embedded in a token, which lets requests be
+// rejected before the sessions table is consulted. The table rows remain the
+// source of truth for revocation.
+type Claims struct {
+	SessionId string `json:"sid"`
+	AccountId string `json:"acc,omitempty"`
+	// Rooms maps the id of every room the session is a member of to the
+	// user it joined that room as.
+	Rooms     map[string]string `json:"rooms"`
+	ExpiresAt int64             `json:"exp"`
+}
+
+func (c *Claims) IsExpired() bool {
+	return time.Now().Unix() >= c.ExpiresAt
+}
+
+// IssueToken returns a signed token for session sessionId, the account signed
+// in to it, if any, and its memberships.
+func IssueToken(sessionId, accountId string, memberships []*models.Session) (string, error) {
+	rooms := make(map[string]string, len(memberships))
+	for _, membership := range memberships {
+		rooms[membership.RoomId] = membership.UserId
+	}
+
+	payload, err := json.Marshal(Claims{
+		SessionId: sessionId,
+		AccountId: accountId,
+		Rooms:     rooms,
+		ExpiresAt: time.Now().Add(config.Cfg.Session.TokenTTL).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return signPayload(sessionTokenPurpose, payload), nil
+}
+
+// VerifyToken checks the signature of token against the configured keys and
+// returns its claims. Expired tokens are reported with ErrSessionExpired.
+func VerifyToken(token string) (*Claims, error) {
+	payload, err := verifyPayload(sessionTokenPurpose, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.SessionId == "" {
+		return nil, ErrInvalidToken
+	}
+
+	if claims.IsExpired() {
+		return nil, ErrSessionExpired
+	}
+	return &claims, nil
+}
+
+// Tokens of different purposes share the signing keys, so the purpose is
+// bound into the signature to stop one kind being accepted as another.
+// Changing a purpose invalidates every token already issued for it: renaming
+// sessionTokenPurpose signs out every session on deploy, and renaming
+// profileTokenPurpose detaches every device from its profile.
+const (
+	sessionTokenPurpose = "session"
+	profileTokenPurpose = "profile"
+	loginStatePurpose   = "oidc-login"
+)
+
+// signPayload returns a token of the form keyId.payload.signature, where the
+// payload is base64url encoded and the signature is an HMAC-SHA256 over the
+// purpose and keyId.payload, made with the active signing key.
+func signPayload(purpose string, payload []byte) string {
+	key := config.Cfg.Session.ActiveSigningKey()
+	signed := key.Id + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + sign(key, purpose, signed)
+}
+
+// verifyPayload checks the signature of a signPayload token against the
+// configured keys and returns its decoded payload.
+func verifyPayload(purpose, token string) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	key, ok := config.Cfg.Session.SigningKey(parts[0])
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	expected := sign(key, purpose, parts[0]+"."+parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return payload, nil
+}
+
+func sign(key config.SigningKey, purpose, data string) string {
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte(purpose + ":" + data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
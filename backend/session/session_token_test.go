@@ -0,0 +1,114 @@
+package session
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/models"
+)
+
+var (
+	currentKey = config.SigningKey{Id: "current", Secret: []byte("0123456789abcdef0123456789abcdef")}
+	retiredKey = config.SigningKey{Id: "retired", Secret: []byte("fedcba9876543210fedcba9876543210")}
+)
+
+// useSigningKeys signs with the first of keys and verifies with all of them.
+func useSigningKeys(t *testing.T, keys ...config.SigningKey) {
+	t.Helper()
+	previous := config.Cfg
+	t.Cleanup(func() { config.Cfg = previous })
+	config.Cfg.Session.SigningKeys = keys
+	config.Cfg.Session.TokenTTL = time.Hour
+}
+
+func issue(t *testing.T, sessionId string) string {
+	t.Helper()
+	token, err := IssueToken(sessionId, "account-1", []*models.Session{
+		{RoomId: "room-1", UserId: "user-1"},
+		{RoomId: "room-2", UserId: "user-2"},
+	})
+	if err != nil {
+		t.Fatalf("IssueToken() error = %v", err)
+	}
+	return token
+}
+
+func TestTokenCarriesTheSessionAndItsMemberships(t *testing.T) {
+	useSigningKeys(t, currentKey)
+
+	claims, err := VerifyToken(issue(t, "session-1"))
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if claims.SessionId != "session-1" || claims.AccountId != "account-1" {
+		t.Errorf("claims = %+v, want session-1 signed in as account-1", claims)
+	}
+	if len(claims.Rooms) != 2 || claims.Rooms["room-1"] != "user-1" || claims.Rooms["room-2"] != "user-2" {
+		t.Errorf("Rooms = %v, want the user of each membership by room", claims.Rooms)
+	}
+	if until := time.Until(time.Unix(claims.ExpiresAt, 0)); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("token expires in %v, want SESSION_TOKEN_TTL", until)
+	}
+}
+
+func TestTokensSurviveKeyRotation(t *testing.T) {
+	useSigningKeys(t, retiredKey)
+	token := issue(t, "session-1")
+
+	// The new key is prepended and the old one is kept for verification.
+	config.Cfg.Session.SigningKeys = []config.SigningKey{currentKey, retiredKey}
+	if _, err := VerifyToken(token); err != nil {
+		t.Fatalf("a token of the previous key was rejected during rotation: %v", err)
+	}
+	if !strings.HasPrefix(issue(t, "session-1"), currentKey.Id+".") {
+		t.Error("new tokens are not signed with the first key")
+	}
+
+	// Once the old key is removed its tokens stop working.
+	config.Cfg.Session.SigningKeys = []config.SigningKey{currentKey}
+	if _, err := VerifyToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("a token of a removed key: error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestTamperedTokensAreRejected(t *testing.T) {
+	useSigningKeys(t, currentKey)
+	parts := strings.Split(issue(t, "session-1"), ".")
+	other := strings.Split(issue(t, "session-2"), ".")
+
+	forgedClaims := base64.RawURLEncoding.EncodeToString([]byte(`{"sid":"session-1","rooms":{"room-3":"user-3"},"exp":9999999999}`))
+	tampered := map[string]string{
+		"payload of another token":     parts[0] + "." + other[1] + "." + parts[2],
+		"rewritten claims":             parts[0] + "." + forgedClaims + "." + parts[2],
+		"signature of another token":   parts[0] + "." + parts[1] + "." + other[2],
+		"signed for another purpose":   parts[0] + "." + parts[1] + "." + sign(currentKey, profileTokenPurpose, parts[0]+"."+parts[1]),
+		"key id swapped":               retiredKey.Id + "." + parts[1] + "." + parts[2],
+		"signature missing":            parts[0] + "." + parts[1],
+		"extra segment":                strings.Join(append(parts, "x"), "."),
+		"empty":                        "",
+		"raw session id, as once used": "session-1",
+	}
+	for name, token := range tampered {
+		if _, err := VerifyToken(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: error = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func TestExpiredAndEmptyTokens(t *testing.T) {
+	useSigningKeys(t, currentKey)
+
+	config.Cfg.Session.TokenTTL = -time.Second
+	if _, err := VerifyToken(issue(t, "session-1")); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("expired token: error = %v, want ErrSessionExpired", err)
+	}
+
+	config.Cfg.Session.TokenTTL = time.Hour
+	if _, err := VerifyToken(issue(t, "")); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("token without a session: error = %v, want ErrInvalidToken", err)
+	}
+}
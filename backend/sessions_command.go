@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/session"
+)
+
+const sessionsUsage = "usage: scrum-poker sessions purge-expired [flags]"
+
+// runSessionsCommand implements `sessions purge-expired`, one sweep of the
+// cleanup the server runs periodically. The server keeps refreshing the
+// memberships of connected participants, which the command line cannot
+// see, so only memberships that expired more than -grace ago are removed.
+// The sweep's report is written as JSON.
+func runSessionsCommand(args []string) error {
+	grace, err := parseSessionsArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Open(); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report := session.NewDetachedManager(grace).RunCleanup()
+	if err := writeJSON(os.Stdout, report); err != nil {
+		return err
+	}
+	if report.Errors > 0 {
+		return fmt.Errorf("cleanup finished with %d errors", report.Errors)
+	}
+	return nil
+}
+
+// parseSessionsArgs loads the configuration for `sessions purge-expired` and
+// returns its grace, which defaults to session.DetachedGrace.
+func parseSessionsArgs(args []string) (time.Duration, error) {
+	if len(args) == 0 || args[0] != "purge-expired" {
+		return 0, errors.New(sessionsUsage)
+	}
+
+	var grace *time.Duration
+	rest, err := loadCommand("sessions purge-expired", args[1:], func(fs *flag.FlagSet) {
+		fs.Func("grace", "only remove memberships that expired at least this long ago (default: three cleanup intervals)", func(value string) error {
+			d, err := time.ParseDuration(value)
+			grace = &d
+			return err
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(rest) > 0 {
+		return 0, errors.New(sessionsUsage)
+	}
+
+	if grace == nil {
+		return session.DetachedGrace(), nil
+	}
+	if *grace < 0 {
+		return 0, errors.New("grace must not be negative")
+	}
+	return *grace, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPurgeExpiredGraceFollowsTheCleanupInterval(t *testing.T) {
+	useCommandEnv(t)
+
+	grace, err := parseSessionsArgs([]string{"purge-expired"})
+	if err != nil {
+		t.Fatalf("parseSessionsArgs failed: %v", err)
+	}
+	if grace != 3*time.Minute {
+		t.Errorf("grace = %v, want three of the default one-minute cleanup intervals", grace)
+	}
+
+	grace, err = parseSessionsArgs([]string{"purge-expired", "-cleanup-interval", "5m"})
+	if err != nil || grace != 15*time.Minute {
+		t.Errorf("grace with -cleanup-interval 5m = %v, %v; want 15m", grace, err)
+	}
+
+	t.Setenv("SESSION_CLEANUP_INTERVAL", "2m")
+	grace, err = parseSessionsArgs([]string{"purge-expired"})
+	if err != nil || grace != 6*time.Minute {
+		t.Errorf("grace with SESSION_CLEANUP_INTERVAL=2m = %v, %v; want 6m", grace, err)
+	}
+}
+
+func TestPurgeExpiredGraceFlag(t *testing.T) {
+	useCommandEnv(t)
+
+	for args, want := range map[string]time.Duration{"30s": 30 * time.Second, "0": 0, "1h": time.Hour} {
+		grace, err := parseSessionsArgs([]string{"purge-expired", "-grace", args})
+		if err != nil || grace != want {
+			t.Errorf("-grace %s: grace = %v, %v; want %v", args, grace, err, want)
+		}
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"purge"},
+		{"purge-expired", "room-1"},
+		{"purge-expired", "-grace", "-1m"},
+		{"purge-expired", "-grace", "soon"},
+	} {
+		if _, err := parseSessionsArgs(args); err == nil {
+			t.Errorf("parseSessionsArgs(%q) was accepted", args)
+		}
+	}
+}
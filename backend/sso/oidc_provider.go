@@ -0,0 +1,135 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/models"
+	"golang.org/x/oauth2"
+)
+
+var ErrNotConfigured = errors.New("single sign-on is not configured")
+
+type provider struct {
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+var (
+	mu      sync.Mutex
+	current *provider
+)
+
+// getProvider discovers the configured issuer on first use and keeps the
+// result. Failed discovery is retried on the next login. The verifier caches
+// the issuer's signing keys and refetches them when a token names an unknown
+// key.
+func getProvider(ctx context.Context) (*provider, error) {
+	cfg := config.Cfg.OIDC
+	if !cfg.Enabled() {
+		return nil, ErrNotConfigured
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if current != nil {
+		return current, nil
+	}
+
+	discoveryURL := cfg.IssuerURL
+	if cfg.DiscoveryURL != "" {
+		discoveryURL = cfg.DiscoveryURL
+		ctx = oidc.InsecureIssuerURLContext(ctx, cfg.IssuerURL)
+	}
+
+	p, err := oidc.NewProvider(ctx, discoveryURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+
+	current = &provider{
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     p.Endpoint(),
+			Scopes:       cfg.Scopes,
+		},
+		verifier: p.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}
+	return current, nil
+}
+
+// AuthCodeURL returns the identity provider's authorization URL for an
+// authorization code flow bound to state, nonce and the PKCE code verifier.
+func AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	p, err := getProvider(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier)), nil
+}
+
+// Exchange redeems an authorization code, verifies the returned ID token and
+// maps its claims as configured.
+func Exchange(ctx context.Context, code, codeVerifier, nonce string) (*models.ExternalIdentity, error) {
+	p, err := getProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response did not include an ID token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("ID token nonce does not match")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to decode ID token claims: %w", err)
+	}
+
+	cfg := config.Cfg.OIDC
+	return &models.ExternalIdentity{
+		Issuer:        idToken.Issuer,
+		Subject:       stringClaim(claims, cfg.SubjectClaim),
+		Email:         stringClaim(claims, cfg.EmailClaim),
+		EmailVerified: boolClaim(claims, cfg.EmailVerifiedClaim),
+		Name:          stringClaim(claims, cfg.NameClaim),
+	}, nil
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// boolClaim reads a boolean claim, which some providers send as a string.
+func boolClaim(claims map[string]interface{}, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		parsed, _ := strconv.ParseBool(value)
+		return parsed
+	default:
+		return false
+	}
+}
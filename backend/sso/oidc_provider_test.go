@@ -0,0 +1,369 @@
+package sso
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scrum-poker/backend/config"
+	"golang.org/x/oauth2"
+)
+
+const (
+	testClientID     = "scrum-poker"
+	testClientSecret = "secret"
+	testRedirectURL  = "https://poker.example.com/auth/oidc/callback"
+	testKeyID        = "test-key"
+)
+
+// fakeIssuer is an OpenID provider serving discovery, its signing keys and a
+// token endpoint. Authorization is skipped: tests register a code with the
+// PKCE challenge and nonce the authorization request carried.
+type fakeIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]authorization
+	claims map[string]interface{}
+	// forgeKey, when set, signs ID tokens instead of the published key.
+	forgeKey *rsa.PrivateKey
+}
+
+type authorization struct {
+	challenge string
+	nonce     string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &fakeIssuer{key: key, codes: make(map[string]authorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/keys", issuer.keys)
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (f *fakeIssuer) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"issuer":                                f.server.URL,
+		"authorization_endpoint":                f.server.URL + "/authorize",
+		"token_endpoint":                        f.server.URL + "/token",
+		"jwks_uri":                              f.server.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (f *fakeIssuer) keys(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": testKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(f.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(f.key.E)).Bytes()),
+		}},
+	})
+}
+
+// token redeems a registered code once its PKCE verifier matches the
+// challenge, and returns an ID token for the configured claims.
+func (f *fakeIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	auth, ok := f.codes[r.PostForm.Get("code")]
+	delete(f.codes, r.PostForm.Get("code"))
+	claims := f.claims
+	key := f.key
+	if f.forgeKey != nil {
+		key = f.forgeKey
+	}
+	f.mu.Unlock()
+
+	if !ok || oauth2.S256ChallengeFromVerifier(r.PostForm.Get("code_verifier")) != auth.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	payload := map[string]interface{}{
+		"iss":   f.server.URL,
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": auth.nonce,
+	}
+	for name, value := range claims {
+		payload[name] = value
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     sign(key, payload),
+	})
+}
+
+func sign(key *rsa.PrivateKey, payload map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": testKeyID})
+	body, _ := json.Marshal(payload)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// authorize stands in for the user signing in at the provider: it records
+// the challenge and nonce of authURL under a new code.
+func (f *fakeIssuer) authorize(t *testing.T, authURL string) string {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", query.Get("code_challenge_method"))
+	}
+
+	code := oauth2.GenerateVerifier()
+	f.mu.Lock()
+	f.codes[code] = authorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	f.mu.Unlock()
+	return code
+}
+
+func (f *fakeIssuer) setClaims(claims map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.claims = claims
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// useIssuer points the configuration at issuer and forgets any provider
+// discovered by an earlier test.
+func useIssuer(t *testing.T, issuer *fakeIssuer) {
+	t.Helper()
+	previous := config.Cfg.OIDC
+	oidcConfig := config.Cfg.OIDC
+	oidcConfig.IssuerURL = issuer.server.URL
+	oidcConfig.ClientID = testClientID
+	oidcConfig.ClientSecret = testClientSecret
+	oidcConfig.RedirectURL = testRedirectURL
+	oidcConfig.Scopes = []string{"openid", "profile", "email"}
+	oidcConfig.SubjectClaim = "sub"
+	oidcConfig.NameClaim = "name"
+	oidcConfig.EmailClaim = "email"
+	oidcConfig.EmailVerifiedClaim = "email_verified"
+	config.Cfg.OIDC = oidcConfig
+	current = nil
+	t.Cleanup(func() {
+		config.Cfg.OIDC = previous
+		current = nil
+	})
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	useIssuer(t, issuer)
+
+	verifier := oauth2.GenerateVerifier()
+	authURL, err := AuthCodeURL(context.Background(), "the-state", "the-nonce", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsed.Scheme + "://" + parsed.Host + parsed.Path; got != issuer.server.URL+"/authorize" {
+		t.Errorf("authorization endpoint = %q", got)
+	}
+
+	query := parsed.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"state":                 "the-state",
+		"nonce":                 "the-nonce",
+		"code_challenge":        oauth2.S256ChallengeFromVerifier(verifier),
+		"code_challenge_method": "S256",
+		"scope":                 "openid profile email",
+	}
+	for name, value := range want {
+		if got := query.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if query.Has("code_verifier") {
+		t.Error("the code verifier was sent to the authorization endpoint")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	tests := []struct {
+		name          string
+		claims        map[string]interface{}
+		wrongVerifier bool
+		wrongNonce    bool
+		wantErr       bool
+		wantEmail     string
+		wantVerified  bool
+	}{
+		{
+			name:         "verified email",
+			claims:       map[string]interface{}{"sub": "subject-1", "email": "alice@example.com", "email_verified": true, "name": "Alice"},
+			wantEmail:    "alice@example.com",
+			wantVerified: true,
+		},
+		{
+			name:         "verified email sent as a string",
+			claims:       map[string]interface{}{"sub": "subject-1", "email": "alice@example.com", "email_verified": "true"},
+			wantEmail:    "alice@example.com",
+			wantVerified: true,
+		},
+		{
+			name:      "unverified email",
+			claims:    map[string]interface{}{"sub": "subject-1", "email": "alice@example.com", "email_verified": false},
+			wantEmail: "alice@example.com",
+		},
+		{
+			name:      "missing email_verified claim",
+			claims:    map[string]interface{}{"sub": "subject-1", "email": "alice@example.com"},
+			wantEmail: "alice@example.com",
+		},
+		{
+			name:          "code verifier does not match the challenge",
+			claims:        map[string]interface{}{"sub": "subject-1"},
+			wrongVerifier: true,
+			wantErr:       true,
+		},
+		{
+			name:       "nonce does not match",
+			claims:     map[string]interface{}{"sub": "subject-1"},
+			wrongNonce: true,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newFakeIssuer(t)
+			useIssuer(t, issuer)
+			issuer.setClaims(tt.claims)
+
+			ctx := context.Background()
+			verifier := oauth2.GenerateVerifier()
+			authURL, err := AuthCodeURL(ctx, "state", "nonce", verifier)
+			if err != nil {
+				t.Fatalf("AuthCodeURL() error = %v", err)
+			}
+			code := issuer.authorize(t, authURL)
+
+			if tt.wrongVerifier {
+				verifier = oauth2.GenerateVerifier()
+			}
+			nonce := "nonce"
+			if tt.wrongNonce {
+				nonce = "another-nonce"
+			}
+
+			identity, err := Exchange(ctx, code, verifier, nonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Exchange() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+
+			if identity.Issuer != issuer.server.URL {
+				t.Errorf("Issuer = %q, want %q", identity.Issuer, issuer.server.URL)
+			}
+			if identity.Subject != "subject-1" {
+				t.Errorf("Subject = %q, want %q", identity.Subject, "subject-1")
+			}
+			if identity.Email != tt.wantEmail {
+				t.Errorf("Email = %q, want %q", identity.Email, tt.wantEmail)
+			}
+			if identity.EmailVerified != tt.wantVerified {
+				t.Errorf("EmailVerified = %v, want %v", identity.EmailVerified, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsForgedToken(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	useIssuer(t, issuer)
+	issuer.setClaims(map[string]interface{}{"sub": "subject-1"})
+
+	ctx := context.Background()
+	verifier := oauth2.GenerateVerifier()
+	authURL, err := AuthCodeURL(ctx, "state", "nonce", verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	code := issuer.authorize(t, authURL)
+	// The token is signed with a key the issuer does not publish.
+	forgeKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.mu.Lock()
+	issuer.forgeKey = forgeKey
+	issuer.mu.Unlock()
+
+	if _, err := Exchange(ctx, code, verifier, "nonce"); err == nil {
+		t.Fatal("Exchange() accepted an ID token with an unknown signature")
+	}
+}
+
+func TestProviderNotConfigured(t *testing.T) {
+	previous := config.Cfg.OIDC
+	config.Cfg.OIDC = config.OIDCConfig{}
+	current = nil
+	t.Cleanup(func() { config.Cfg.OIDC = previous })
+
+	if _, err := AuthCodeURL(context.Background(), "state", "nonce", "verifier"); err != ErrNotConfigured {
+		t.Errorf("AuthCodeURL() error = %v, want ErrNotConfigured", err)
+	}
+}
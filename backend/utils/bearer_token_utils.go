@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// HasBearerToken reports whether r carries token in an
+// `Authorization: Bearer` header. The comparison takes constant time.
+func HasBearerToken(r *http.Request, token string) bool {
+	bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Field string `json:"field,omitempty"`
+}
+
+// PrepareErrorResponse writes err as a JSON error body. Validation errors name
+// the offending request field so that clients can highlight it.
+func PrepareErrorResponse(w http.ResponseWriter, err error) {
+	switch e := err.(type) {
+	case models.ValidationError:
+		PrepareJSONResponse(w, http.StatusBadRequest, ErrorResponse{Error: e.Message, Field: e.Field})
+	case models.UnauthorizedError:
+		PrepareJSONResponse(w, http.StatusUnauthorized, ErrorResponse{Error: e.Message})
+	case models.ForbiddenError:
+		PrepareJSONResponse(w, http.StatusForbidden, ErrorResponse{Error: e.Message})
+	case models.NotFoundError:
+		PrepareJSONResponse(w, http.StatusNotFound, ErrorResponse{Error: e.Message})
+	case models.DatabaseError:
+		slog.Error("Database operation failed", "operation", e.Operation, "message", e.Message)
+		PrepareJSONResponse(w, http.StatusInternalServerError, ErrorResponse{Error: e.Message})
+	default:
+		slog.Error("Unexpected error", "error", err)
+		PrepareJSONResponse(w, http.StatusInternalServerError, ErrorResponse{Error: "Internal server error"})
+	}
+}
@@ -2,7 +2,7 @@ package utils
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 )
 
@@ -11,7 +11,7 @@ func PrepareJSONResponse(w http.ResponseWriter, statusCode int, data interface{}
 	w.WriteHeader(statusCode)
 	if data != nil {
 		if err := json.NewEncoder(w).Encode(data); err != nil {
-			log.Printf("Error encoding JSON response: %v", err)
+			slog.Error("Error encoding JSON response", "error", err)
 		}
 	}
 }
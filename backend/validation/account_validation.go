@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+const (
+	MaxEmailLength    = 255
+	MinPasswordLength = 8
+	// bcrypt ignores everything past 72 bytes.
+	MaxPasswordBytes = 72
+)
+
+// Email returns the lower-cased address, which is how accounts are keyed.
+func Email(field, value string) (string, error) {
+	email := strings.ToLower(strings.TrimSpace(value))
+	if email == "" {
+		return "", models.ValidationError{
+			Field:   field,
+			Message: "Email is required",
+		}
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || utf8.RuneCountInString(email) > MaxEmailLength {
+		return "", models.ValidationError{
+			Field:   field,
+			Message: "Email is not a valid address",
+		}
+	}
+	return email, nil
+}
+
+// Password checks the length of a new password. Passwords are not otherwise
+// altered, so they are compared exactly as typed.
+func Password(field, value string) error {
+	if utf8.RuneCountInString(value) < MinPasswordLength {
+		return models.ValidationError{
+			Field:   field,
+			Message: "Password must be at least 8 characters",
+		}
+	}
+	if len(value) > MaxPasswordBytes {
+		return models.ValidationError{
+			Field:   field,
+			Message: "Password must be at most 72 bytes",
+		}
+	}
+	return nil
+}
+
+const maxEmailDomains = 20
+
+// EmailDomains normalises a list of email domains, accepting entries written
+// with a leading @, and drops duplicates.
+func EmailDomains(field string, values []string) ([]string, error) {
+	if len(values) > maxEmailDomains {
+		return nil, models.ValidationError{
+			Field:   field,
+			Message: "Too many email domains",
+		}
+	}
+
+	domains := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		domain := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "@")
+		if _, err := Email(field, "user@"+domain); err != nil || strings.Contains(domain, ",") {
+			return nil, models.ValidationError{
+				Field:   field,
+				Message: "Invalid email domain " + value,
+			}
+		}
+		if !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	return domains, nil
+}
@@ -0,0 +1,79 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEmailIsTheAccountKey(t *testing.T) {
+	// Addresses differing in case or surrounding space name one account.
+	for _, value := range []string{"alice@example.com", " Alice@Example.COM ", "ALICE@EXAMPLE.COM\n"} {
+		if got, err := Email("email", value); err != nil || got != "alice@example.com" {
+			t.Errorf("Email(%q) = %q, %v; want alice@example.com", value, got, err)
+		}
+	}
+
+	for _, value := range []string{
+		"",
+		"alice",
+		"alice@",
+		"Alice <alice@example.com>",
+		"alice@example.com, bob@example.com",
+		strings.Repeat("a", MaxEmailLength) + "@example.com",
+	} {
+		if _, err := Email("email", value); err == nil {
+			t.Errorf("Email(%q) was accepted", value)
+		}
+	}
+}
+
+func TestPasswordLengthCountsCharactersButCapsBytes(t *testing.T) {
+	accepted := []string{
+		"correct horse",
+		"éééééééé", // 8 characters in 16 bytes
+		strings.Repeat("a", MaxPasswordBytes),
+	}
+	for _, password := range accepted {
+		if err := Password("password", password); err != nil {
+			t.Errorf("Password(%q) = %v, want it accepted", password, err)
+		}
+	}
+
+	// bcrypt would silently ignore the bytes past its limit.
+	rejected := []string{
+		"short",
+		strings.Repeat("a", MaxPasswordBytes+1),
+		strings.Repeat("é", MaxPasswordBytes/2+1),
+	}
+	for _, password := range rejected {
+		if err := Password("password", password); err == nil {
+			t.Errorf("Password(%q) was accepted", password)
+		}
+	}
+}
+
+func TestEmailDomains(t *testing.T) {
+	got, err := EmailDomains("allowedEmailDomains", []string{" @Example.com", "corp.example.org", "example.COM"})
+	if err != nil {
+		t.Fatalf("EmailDomains failed: %v", err)
+	}
+	if want := []string{"example.com", "corp.example.org"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("EmailDomains() = %q, want %q", got, want)
+	}
+
+	if got, err := EmailDomains("allowedEmailDomains", nil); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("EmailDomains(nil) = %#v, %v; want an empty list", got, err)
+	}
+
+	for _, domains := range [][]string{
+		{"not a domain"},
+		{"alice@example.com"},
+		{"example.com,example.org"},
+		make([]string, maxEmailDomains+1),
+	} {
+		if _, err := EmailDomains("allowedEmailDomains", domains); err == nil {
+			t.Errorf("EmailDomains(%q) was accepted", domains)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package validation
+
+import (
+	"fmt"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+const (
+	MaxTokenNameLength       = 100
+	DefaultTokenLifetimeDays = 90
+	MaxTokenLifetimeDays     = 365
+)
+
+func TokenName(field, value string) (string, error) {
+	return name(field, "Token name", value, MaxTokenNameLength)
+}
+
+// Scopes checks that values only names known scopes and removes duplicates.
+// At least one scope is required.
+func Scopes(field string, values []string) ([]string, error) {
+	seen := make(map[string]bool, len(values))
+	scopes := make([]string, 0, len(values))
+	for _, value := range values {
+		if !isScope(value) {
+			return nil, models.ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("Unknown scope %q; use read, write or facilitate", value),
+			}
+		}
+		if !seen[value] {
+			seen[value] = true
+			scopes = append(scopes, value)
+		}
+	}
+
+	if len(scopes) == 0 {
+		return nil, models.ValidationError{
+			Field:   field,
+			Message: "At least one scope is required",
+		}
+	}
+	return scopes, nil
+}
+
+// TokenLifetimeDays defaults an unset lifetime and bounds it to
+// MaxTokenLifetimeDays.
+func TokenLifetimeDays(field string, value int) (int, error) {
+	if value == 0 {
+		return DefaultTokenLifetimeDays, nil
+	}
+	if value < 0 || value > MaxTokenLifetimeDays {
+		return 0, models.ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("Token lifetime must be between 1 and %d days", MaxTokenLifetimeDays),
+		}
+	}
+	return value, nil
+}
+
+func isScope(value string) bool {
+	for _, scope := range models.Scopes {
+		if value == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+func TestScopes(t *testing.T) {
+	got, err := Scopes("scopes", []string{"write", "read", "write"})
+	if err != nil {
+		t.Fatalf("Scopes failed: %v", err)
+	}
+	if want := []string{"write", "read"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Scopes() = %q, want %q", got, want)
+	}
+	if got, err := Scopes("scopes", models.Scopes); err != nil || !reflect.DeepEqual(got, models.Scopes) {
+		t.Errorf("Scopes(every scope) = %q, %v", got, err)
+	}
+
+	// A token needs a scope, and scopes are names, not case-insensitive words.
+	for _, values := range [][]string{nil, {}, {"read", "admin"}, {"Read"}} {
+		if _, err := Scopes("scopes", values); err == nil {
+			t.Errorf("Scopes(%q) was accepted", values)
+		}
+	}
+}
+
+func TestTokenLifetimeDays(t *testing.T) {
+	lifetimes := map[int]int{
+		0:                    DefaultTokenLifetimeDays,
+		1:                    1,
+		MaxTokenLifetimeDays: MaxTokenLifetimeDays,
+	}
+	for days, want := range lifetimes {
+		if got, err := TokenLifetimeDays("lifetimeDays", days); err != nil || got != want {
+			t.Errorf("TokenLifetimeDays(%d) = %d, %v; want %d", days, got, err, want)
+		}
+	}
+
+	for _, days := range []int{-1, MaxTokenLifetimeDays + 1} {
+		if _, err := TokenLifetimeDays("lifetimeDays", days); err == nil {
+			t.Errorf("TokenLifetimeDays(%d) was accepted", days)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/scrum-poker/backend/models"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	MaxUserNameLength = 50
+	MaxRoomNameLength = 100
+)
+
+const zeroWidthJoiner = '\u200d'
+
+// Sanitize normalises a display name: the text is converted to NFC, control
+// and invisible formatting characters are removed, and runs of whitespace are
+// collapsed into single spaces. The zero width joiner survives so that
+// composed emoji keep rendering.
+func Sanitize(value string) string {
+	var b strings.Builder
+	b.Grow(len(value))
+
+	pendingSpace := false
+	for _, r := range norm.NFC.String(value) {
+		switch {
+		case unicode.IsSpace(r):
+			pendingSpace = b.Len() > 0
+		case r == utf8.RuneError, unicode.IsControl(r), unicode.Is(unicode.Cf, r) && r != zeroWidthJoiner:
+		default:
+			if pendingSpace {
+				b.WriteByte(' ')
+				pendingSpace = false
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func UserName(field, value string) (string, error) {
+	return name(field, "User name", value, MaxUserNameLength)
+}
+
+func RoomName(field, value string) (string, error) {
+	return name(field, "Room name", value, MaxRoomNameLength)
+}
+
+func name(field, label, value string, maxLength int) (string, error) {
+	sanitized := Sanitize(value)
+	if sanitized == "" {
+		return "", models.ValidationError{
+			Field:   field,
+			Message: label + " is required",
+		}
+	}
+	if utf8.RuneCountInString(sanitized) > maxLength {
+		return "", models.ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("%s must be at most %d characters", label, maxLength),
+		}
+	}
+	return sanitized, nil
+}
+
+// UniqueUserName returns name, suffixed with " (2)", " (3)" and so on when it
+// is already taken. Names are compared case-insensitively, and the base name
+// is shortened when needed so the result stays within MaxUserNameLength.
+func UniqueUserName(name string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, t := range taken {
+		used[strings.ToLower(t)] = true
+	}
+	if !used[strings.ToLower(name)] {
+		return name
+	}
+
+	for n := 2; ; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		candidate := truncate(name, MaxUserNameLength-utf8.RuneCountInString(suffix)) + suffix
+		if !used[strings.ToLower(candidate)] {
+			return candidate
+		}
+	}
+}
+
+func truncate(value string, maxLength int) string {
+	if utf8.RuneCountInString(value) <= maxLength {
+		return value
+	}
+	runes := []rune(value)
+	return strings.TrimRight(string(runes[:maxLength]), " ")
+}
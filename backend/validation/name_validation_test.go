@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+func TestSanitize(t *testing.T) {
+	for input, want := range map[string]string{
+		"Alice":                      "Alice",
+		"  Alice \t":                 "Alice",
+		"Alice \n\t Smith":           "Alice Smith",
+		"Ali\x00ce\x1b":              "Alice",
+		"Ali\u200bce\u202e":          "Alice",
+		"Rene\u0301":                 "Ren\u00e9",
+		"Al\xffice":                  "Alice",
+		" \t\n":                      "",
+		"\U0001F469\u200d\U0001F4BB": "\U0001F469\u200d\U0001F4BB",
+	} {
+		if got := Sanitize(input); got != want {
+			t.Errorf("Sanitize(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestNamesAreSanitisedAndBounded(t *testing.T) {
+	validators := map[string]struct {
+		validate  func(field, value string) (string, error)
+		maxLength int
+	}{
+		"UserName": {UserName, MaxUserNameLength},
+		"RoomName": {RoomName, MaxRoomNameLength},
+	}
+
+	for name, v := range validators {
+		if got, err := v.validate("name", "  Sprint\u200b  42 "); err != nil || got != "Sprint 42" {
+			t.Errorf("%s() = %q, %v; want the sanitised name", name, got, err)
+		}
+
+		// The limit counts characters, not bytes.
+		atLimit := strings.Repeat("é", v.maxLength)
+		if got, err := v.validate("name", atLimit); err != nil || got != atLimit {
+			t.Errorf("%s() rejected %d characters: %v", name, v.maxLength, err)
+		}
+
+		for _, invalid := range []string{"", "   ", "\u200b\u200b", atLimit + "e"} {
+			_, err := v.validate("name", invalid)
+			var validationErr models.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Field != "name" {
+				t.Errorf("%s(%q) error = %v, want a ValidationError on name", name, invalid, err)
+			}
+		}
+	}
+}
+
+func TestUniqueUserName(t *testing.T) {
+	taken := []string{"Alice", "alice (2)", "BOB"}
+
+	if got := UniqueUserName("Carol", taken); got != "Carol" {
+		t.Errorf("a free name was changed to %q", got)
+	}
+	if got := UniqueUserName("bob", taken); got != "bob (2)" {
+		t.Errorf("UniqueUserName(bob) = %q, want names compared case-insensitively", got)
+	}
+	if got := UniqueUserName("Alice", taken); got != "Alice (3)" {
+		t.Errorf("UniqueUserName(Alice) = %q, want the first free suffix", got)
+	}
+
+	long := strings.Repeat("a", MaxUserNameLength-2) + " b"
+	got := UniqueUserName(long, []string{long})
+	if len([]rune(got)) > MaxUserNameLength || !strings.HasSuffix(got, "a (2)") {
+		t.Errorf("UniqueUserName(long) = %q, want it shortened to fit the suffix", got)
+	}
+}
@@ -0,0 +1,26 @@
+package validation
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+const MaxNoticeLength = 500
+
+func NoticeMessage(field, value string) (string, error) {
+	return name(field, "Notice", value, MaxNoticeLength)
+}
+
+// CloseReason is optional, so an empty reason is valid.
+func CloseReason(field, value string) (string, error) {
+	reason := Sanitize(value)
+	if utf8.RuneCountInString(reason) > MaxNoticeLength {
+		return "", models.ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("Reason must be at most %d characters", MaxNoticeLength),
+		}
+	}
+	return reason, nil
+}
@@ -0,0 +1,33 @@
+package validation
+
+import (
+	"unicode/utf8"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+const MaxAvatarSeedLength = 64
+
+func AvatarSeed(field, value string) (string, error) {
+	sanitized := Sanitize(value)
+	if utf8.RuneCountInString(sanitized) > MaxAvatarSeedLength {
+		return "", models.ValidationError{
+			Field:   field,
+			Message: "Avatar seed is too long",
+		}
+	}
+	return sanitized, nil
+}
+
+// PreferredRole accepts one of the profile roles or an empty string.
+func PreferredRole(field, value string) (string, error) {
+	switch value {
+	case "", models.ProfileRoleVoter, models.ProfileRoleObserver, models.ProfileRoleFacilitator:
+		return value, nil
+	default:
+		return "", models.ValidationError{
+			Field:   field,
+			Message: "Preferred role must be voter, observer or facilitator",
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+func TestAvatarSeed(t *testing.T) {
+	// An empty seed is allowed: saving a profile then keeps the current one.
+	if got, err := AvatarSeed("avatarSeed", ""); err != nil || got != "" {
+		t.Errorf("AvatarSeed(\"\") = %q, %v; want it accepted", got, err)
+	}
+	if got, err := AvatarSeed("avatarSeed", " seed\u200b-1 "); err != nil || got != "seed-1" {
+		t.Errorf("AvatarSeed() = %q, %v; want the sanitised seed", got, err)
+	}
+
+	longest := strings.Repeat("s", MaxAvatarSeedLength)
+	if _, err := AvatarSeed("avatarSeed", longest); err != nil {
+		t.Errorf("AvatarSeed() rejected %d characters: %v", MaxAvatarSeedLength, err)
+	}
+	if _, err := AvatarSeed("avatarSeed", longest+"s"); err == nil {
+		t.Errorf("AvatarSeed() accepted %d characters", MaxAvatarSeedLength+1)
+	}
+}
+
+func TestPreferredRole(t *testing.T) {
+	for _, role := range []string{"", models.ProfileRoleVoter, models.ProfileRoleObserver, models.ProfileRoleFacilitator} {
+		if got, err := PreferredRole("preferredRole", role); err != nil || got != role {
+			t.Errorf("PreferredRole(%q) = %q, %v; want it accepted", role, got, err)
+		}
+	}
+
+	// Roles are matched exactly, and room roles are not profile roles.
+	for _, role := range []string{"Observer", " voter", "admin", "scrumMaster"} {
+		if _, err := PreferredRole("preferredRole", role); err == nil {
+			t.Errorf("PreferredRole(%q) was accepted", role)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package validation
+
+import (
+	"fmt"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+const (
+	MaxStoryTitleLength = 200
+	MaxStoriesPerRoom   = 500
+)
+
+func StoryTitle(field, value string) (string, error) {
+	return name(field, "Story title", value, MaxStoryTitleLength)
+}
+
+// StoryTitles checks a batch of stories to add to a room. At least one title
+// is required.
+func StoryTitles(field string, values []string) ([]string, error) {
+	if len(values) == 0 {
+		return nil, models.ValidationError{
+			Field:   field,
+			Message: "At least one story is required",
+		}
+	}
+	if len(values) > MaxStoriesPerRoom {
+		return nil, models.ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("A room can have at most %d stories", MaxStoriesPerRoom),
+		}
+	}
+
+	titles := make([]string, 0, len(values))
+	for i, value := range values {
+		title, err := StoryTitle(fmt.Sprintf("%s[%d]", field, i), value)
+		if err != nil {
+			return nil, err
+		}
+		titles = append(titles, title)
+	}
+	return titles, nil
+}
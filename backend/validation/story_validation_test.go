@@ -0,0 +1,37 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/scrum-poker/backend/models"
+)
+
+func TestStoryTitles(t *testing.T) {
+	got, err := StoryTitles("titles", []string{" Login  page ", "Checkout"})
+	if err != nil {
+		t.Fatalf("StoryTitles failed: %v", err)
+	}
+	if want := []string{"Login page", "Checkout"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("StoryTitles() = %q, want %q", got, want)
+	}
+
+	for _, titles := range [][]string{nil, make([]string, MaxStoriesPerRoom+1)} {
+		if _, err := StoryTitles("titles", titles); err == nil {
+			t.Errorf("StoryTitles accepted %d titles", len(titles))
+		}
+	}
+}
+
+func TestStoryTitlesPointAtTheInvalidTitle(t *testing.T) {
+	for _, invalid := range []string{"  ", strings.Repeat("a", MaxStoryTitleLength+1)} {
+		_, err := StoryTitles("titles", []string{"Login page", invalid})
+
+		var validationErr models.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Field != "titles[1]" {
+			t.Errorf("StoryTitles(%q) error = %v, want a ValidationError on titles[1]", invalid, err)
+		}
+	}
+}
@@ -1,43 +1,58 @@
 package websocket
 
 import (
-	"bytes"
-	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/logging"
 	"github.com/scrum-poker/backend/logic/message_logic"
+	"github.com/scrum-poker/backend/metrics"
 	"github.com/scrum-poker/backend/models"
-)
-
-const (
-	writeWait      = 10 * time.Second
-	pongWait       = 60 * time.Second
-	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 2048
-)
-
-var (
-	newline = []byte{'\n'}
-	space   = []byte{' '}
+	"github.com/scrum-poker/backend/ratelimit"
 )
 
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  2048,
 	WriteBufferSize: 2048,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+	Subprotocols:    []string{msgpackSubprotocol, jsonSubprotocol},
+	CheckOrigin:     checkOrigin,
 }
 
 type Client struct {
-	hub    *Hub
-	conn   *websocket.Conn
-	send   chan []byte
-	roomId string
-	userId string
+	hub     *Hub
+	conn    *websocket.Conn
+	codec   codec
+	send    chan []byte
+	roomId  string
+	userId  string
+	resume  bool
+	epoch   string
+	lastSeq uint64
+
+	// logger carries the request, room and user the connection belongs to.
+	logger *slog.Logger
+
+	protocolVersion int
+
+	// closeCode and closeReason are sent in the close frame once the hub
+	// closes send; without a code the frame is empty.
+	closeCode   int
+	closeReason string
+	// closed is set, with the hub's lock held, when send is closed. Frames
+	// are only queued on send while holding the lock and finding closed
+	// unset.
+	closed bool
+
+	ip              string
+	limiter         *ratelimit.Bucket
+	violations      int
+	violationsSince time.Time
 }
 
 func (c *Client) readPump() {
@@ -46,10 +61,10 @@ func (c *Client) readPump() {
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(maxMessageSize)
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetReadLimit(config.Cfg.WebSocket.MaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(config.Cfg.WebSocket.PongWait))
 	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.conn.SetReadDeadline(time.Now().Add(config.Cfg.WebSocket.PongWait))
 		return nil
 	})
 
@@ -57,79 +72,236 @@ func (c *Client) readPump() {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
+				c.logger.Warn("Connection closed unexpectedly", "error", err)
 			}
 			break
 		}
-		message = bytes.TrimSpace(bytes.Replace(message, newline, space, -1))
 
-		var msg models.Message
-		if err := json.Unmarshal(message, &msg); err != nil {
-			log.Printf("error unmarshaling message: %v", err)
+		msg, err := c.codec.decode(message)
+		if errors.Is(err, models.ErrServerOnlyAction) {
+			c.logger.Warn("Rejected spoofed server event", "error", err)
 			continue
 		}
+		if err != nil {
+			c.logger.Warn("Rejected message", "error", err)
+			continue
+		}
+
+		if ok, retryAfter := ratelimit.AllowAction(c.limiter, c.userId, c.ip); !ok {
+			if c.throttle(msg.Action, retryAfter) {
+				c.logger.Warn("Disconnecting for exceeding rate limits", "ip", c.ip)
+				c.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "rate limit exceeded"),
+					time.Now().Add(config.Cfg.WebSocket.WriteWait))
+				return
+			}
+			continue
+		}
+
+		if msg.Action == models.ActionTypeHello {
+			if err := c.negotiate(msg); err != nil {
+				c.logger.Warn("Protocol negotiation failed", "error", err)
+				c.conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseProtocolError, err.Error()),
+					time.Now().Add(config.Cfg.WebSocket.WriteWait))
+				return
+			}
+			continue
+		}
+
+		if c.protocolVersion == 0 {
+			c.protocolVersion = models.ProtocolVersion
+		}
+
+		message_logic.ProcessMessage(c.logger, c.hub.Broadcast, c.roomId, c.userId, msg)
+	}
+}
+
+func (c *Client) negotiate(msg *models.Message) error {
+	if c.protocolVersion != 0 {
+		return fmt.Errorf("protocol version already negotiated as %d", c.protocolVersion)
+	}
+
+	payload, ok := msg.Payload.(*models.HelloPayload)
+	if !ok {
+		return fmt.Errorf("invalid hello payload")
+	}
+
+	version, err := models.NegotiateVersion(payload.Version)
+	if err != nil {
+		return err
+	}
+	c.protocolVersion = version
+
+	c.sendMessage(&models.Message{
+		Action:  models.ActionTypeHello,
+		Payload: &models.HelloPayload{Version: version},
+	})
+	return nil
+}
+
+// throttle tells the client its message was dropped and reports whether the
+// connection has been throttled often enough to be closed.
+func (c *Client) throttle(action models.ActionType, retryAfter time.Duration) bool {
+	c.sendMessage(&models.Message{
+		Action: models.ActionTypeThrottled,
+		Payload: &models.ThrottledPayload{
+			Action:       action,
+			RetryAfterMs: retryAfter.Milliseconds(),
+		},
+	})
+
+	now := time.Now()
+	window := config.Cfg.RateLimit.ViolationWindow
+	if now.Sub(c.violationsSince) > window {
+		c.violations = 0
+		c.violationsSince = now
+	}
+	c.violations++
 
-		message_logic.ProcessMessage(c.hub.Broadcast, c.roomId, &msg)
+	return c.violations > config.Cfg.RateLimit.MaxViolations
+}
+
+// resumeFrom makes c catch up from lastSeq of epoch when it is registered.
+// Clients that do not know the epoch are sent a snapshot.
+func (c *Client) resumeFrom(epoch, lastSeq string) {
+	if lastSeq == "" {
+		return
+	}
+
+	seq, err := strconv.ParseUint(lastSeq, 10, 64)
+	if err != nil {
+		c.logger.Warn("Ignoring invalid lastSeq", "last_seq", lastSeq, "error", err)
+		return
+	}
+	c.resume = true
+	c.epoch = epoch
+	c.lastSeq = seq
+}
+
+func (c *Client) sendMessage(msg *models.Message) {
+	msgBytes, err := c.codec.encode(msg)
+	if err != nil {
+		c.logger.Error("Error encoding message", "action", msg.Action, "error", err)
+		return
+	}
+
+	c.hub.mu.RLock()
+	queued := c.queue(msgBytes)
+	c.hub.mu.RUnlock()
+
+	if queued {
+		metrics.CountMessageOut(msg.Action)
+	} else {
+		go c.hub.UnregisterClient(c)
+	}
+}
+
+// queue adds frame to the client's send buffer without blocking and reports
+// whether there was room. Frames for a closed client are dropped. Must be
+// called with the hub's lock held.
+func (c *Client) queue(frame []byte) bool {
+	if c.closed {
+		return true
+	}
+	select {
+	case c.send <- frame:
+		return true
+	default:
+		return false
 	}
 }
 
+// closeSend closes the send buffer, after which the write pump sends the
+// close frame and stops. Must be called with the hub's lock held for
+// writing.
+func (c *Client) closeSend() {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	close(c.send)
+}
+
 func (c *Client) writePump() {
-	ticker := time.NewTicker(pingPeriod)
+	ticker := time.NewTicker(config.Cfg.WebSocket.PingPeriod())
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		c.hub.pumps.Done()
 	}()
 
 	for {
 		select {
 		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.SetWriteDeadline(time.Now().Add(config.Cfg.WebSocket.WriteWait))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+				c.conn.WriteMessage(websocket.CloseMessage, c.closeMessage())
 				return
 			}
-			w.Write(message)
 
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write(newline)
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
-				log.Printf("error closing writer: %v", err)
+			if err := c.conn.WriteMessage(c.codec.frameType(), message); err != nil {
+				c.logger.Debug("Error writing message", "error", err)
 				return
 			}
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.SetWriteDeadline(time.Now().Add(config.Cfg.WebSocket.WriteWait))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				log.Printf("error pinging client: %v", err)
+				c.logger.Debug("Error pinging client", "error", err)
 				return
 			}
 		}
 	}
 }
 
+func (c *Client) closeMessage() []byte {
+	if c.closeCode == 0 {
+		return []byte{}
+	}
+	return websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+}
+
 func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request, roomId, userId string) {
+	var protocolVersion int
+	if requested := r.URL.Query().Get("v"); requested != "" {
+		version, err := strconv.Atoi(requested)
+		if err == nil {
+			version, err = models.NegotiateVersion(version)
+		}
+		if err != nil {
+			http.Error(w, "Unsupported protocol version", http.StatusBadRequest)
+			return
+		}
+		protocolVersion = version
+	}
+
+	// The pump is counted before the upgrade, while the server still
+	// tracks the request, so that a shutdown waits for it.
+	hub.pumps.Add(1)
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Println(err)
+		hub.pumps.Done()
+		logging.FromRequest(r).Warn("WebSocket upgrade failed", "error", err)
 		return
 	}
 
 	client := &Client{
 		hub:    hub,
 		conn:   conn,
+		codec:  codecForSubprotocol(conn.Subprotocol()),
 		send:   make(chan []byte, 256),
 		roomId: roomId,
 		userId: userId,
+		logger: logging.FromRequest(r).With("user_id", userId),
+
+		protocolVersion: protocolVersion,
+
+		ip:      ratelimit.ClientIP(r),
+		limiter: ratelimit.NewConnectionBucket(),
 	}
 
+	client.resumeFrom(r.URL.Query().Get("epoch"), r.URL.Query().Get("lastSeq"))
+
 	hub.RegisterClient(client)
 
 	go client.writePump()
@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+	"github.com/scrum-poker/backend/models"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	jsonSubprotocol    = "scrum-poker.json"
+	msgpackSubprotocol = "scrum-poker.msgpack"
+)
+
+// codec is the wire encoding negotiated for a connection through the
+// WebSocket subprotocol. JSON is used when the client does not ask for one.
+type codec interface {
+	frameType() int
+	encode(msg *models.Message) ([]byte, error)
+	decode(data []byte) (*models.Message, error)
+}
+
+var (
+	jsonEncoding    codec = jsonCodec{}
+	msgpackEncoding codec = msgpackCodec{}
+)
+
+// outbound is a message on its way to clients, encoded at most once per
+// codec. It is not safe for concurrent use.
+type outbound struct {
+	msg    *models.Message
+	frames map[codec][]byte
+}
+
+func newOutbound(msg *models.Message) *outbound {
+	return &outbound{msg: msg, frames: make(map[codec][]byte, 1)}
+}
+
+func (o *outbound) frame(c codec) ([]byte, error) {
+	if frame, ok := o.frames[c]; ok {
+		return frame, nil
+	}
+
+	frame, err := c.encode(o.msg)
+	if err != nil {
+		return nil, err
+	}
+	o.frames[c] = frame
+	return frame, nil
+}
+
+func codecForSubprotocol(subprotocol string) codec {
+	if subprotocol == msgpackSubprotocol {
+		return msgpackEncoding
+	}
+	return jsonEncoding
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) frameType() int {
+	return websocket.TextMessage
+}
+
+func (jsonCodec) encode(msg *models.Message) ([]byte, error) {
+	return json.Marshal(msg)
+}
+
+func (jsonCodec) decode(data []byte) (*models.Message, error) {
+	return models.DecodeClientMessage(bytes.TrimSpace(data))
+}
+
+type msgpackCodec struct{}
+
+type msgpackEnvelope struct {
+	Action  models.ActionType  `json:"action"`
+	Payload msgpack.RawMessage `json:"payload"`
+}
+
+func (msgpackCodec) frameType() int {
+	return websocket.BinaryMessage
+}
+
+func (msgpackCodec) encode(msg *models.Message) ([]byte, error) {
+	out := *msg
+	if extension, ok := msg.Payload.(*models.ExtensionPayload); ok {
+		var data interface{}
+		if err := json.Unmarshal(extension.Data, &data); err != nil {
+			return nil, fmt.Errorf("invalid extension payload: %w", err)
+		}
+		out.Payload = map[string]interface{}{"from": extension.From, "data": data}
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(&out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) decode(data []byte) (*models.Message, error) {
+	var env msgpackEnvelope
+	if err := newMsgpackDecoder(data).Decode(&env); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	hasPayload := len(env.Payload) > 0 && !bytes.Equal(env.Payload, []byte{msgpackNil})
+	return models.NewClientMessage(env.Action, hasPayload, func(v interface{}) error {
+		if raw, ok := v.(*json.RawMessage); ok {
+			var data interface{}
+			if err := newMsgpackDecoder(env.Payload).Decode(&data); err != nil {
+				return err
+			}
+			encoded, err := json.Marshal(data)
+			if err != nil {
+				return err
+			}
+			*raw = encoded
+			return nil
+		}
+		return newMsgpackDecoder(env.Payload).Decode(v)
+	})
+}
+
+const msgpackNil = 0xc0
+
+func newMsgpackDecoder(data []byte) *msgpack.Decoder {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	dec.DisallowUnknownFields(true)
+	return dec
+}
@@ -0,0 +1,194 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/scrum-poker/backend/models"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// encodeMsgpack encodes v the way a MessagePack client would.
+func encodeMsgpack(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := msgpack.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestCodecsDecodeClientMessages(t *testing.T) {
+	frames := map[codec][]byte{
+		jsonEncoding: []byte(` {"action":"submit","payload":{"userId":"u1","vote":"8"}}` + "\n"),
+		msgpackEncoding: encodeMsgpack(t, map[string]interface{}{
+			"action":  "submit",
+			"payload": map[string]interface{}{"userId": "u1", "vote": "8"},
+		}),
+	}
+
+	for c, frame := range frames {
+		msg, err := c.decode(frame)
+		if err != nil {
+			t.Fatalf("%T.decode() error = %v", c, err)
+		}
+		payload, ok := msg.Payload.(*models.SubmitPayload)
+		if msg.Action != models.ActionTypeSubmit || !ok || payload.UserId != "u1" || payload.Vote != "8" {
+			t.Errorf("%T.decode() = %s %#v, want a submit of 8 by u1", c, msg.Action, msg.Payload)
+		}
+	}
+}
+
+func TestMsgpackDecodingIsStrict(t *testing.T) {
+	rejected := map[string]interface{}{
+		"unknown payload field":  map[string]interface{}{"action": "ping", "payload": map[string]interface{}{"userId": "u1", "admin": true}},
+		"unknown envelope field": map[string]interface{}{"action": "ping", "payload": map[string]interface{}{"userId": "u1"}, "seq": 1},
+		"nil payload":            map[string]interface{}{"action": "ping", "payload": nil},
+		"server-only action":     map[string]interface{}{"action": "join", "payload": map[string]interface{}{"id": "u1"}},
+	}
+	for name, message := range rejected {
+		if _, err := msgpackEncoding.decode(encodeMsgpack(t, message)); err == nil {
+			t.Errorf("%s: decode() accepted the message", name)
+		}
+	}
+}
+
+func TestMsgpackExtensionPayloadsArriveAsJSON(t *testing.T) {
+	frame := encodeMsgpack(t, map[string]interface{}{
+		"action":  "x-timer",
+		"payload": map[string]interface{}{"seconds": 30},
+	})
+	msg, err := msgpackEncoding.decode(frame)
+	if err != nil {
+		t.Fatalf("decode() error = %v", err)
+	}
+	if data, ok := msg.Payload.(json.RawMessage); !ok || string(data) != `{"seconds":30}` {
+		t.Errorf("extension payload = %#v, want JSON", msg.Payload)
+	}
+
+	// Forwarded, the extension is re-encoded as MessagePack, not as a JSON
+	// string inside MessagePack. Its numbers become floats, as they were in
+	// JSON.
+	out, err := msgpackEncoding.encode(&models.Message{
+		Action:  "x-timer",
+		Payload: &models.ExtensionPayload{From: "u1", Data: json.RawMessage(`{"seconds":30}`)},
+	})
+	if err != nil {
+		t.Fatalf("encode() error = %v", err)
+	}
+	var decoded struct {
+		Payload struct {
+			From string `msgpack:"from"`
+			Data struct {
+				Seconds float64 `msgpack:"seconds"`
+			} `msgpack:"data"`
+		} `msgpack:"payload"`
+	}
+	if err := msgpack.Unmarshal(out, &decoded); err != nil || decoded.Payload.From != "u1" || decoded.Payload.Data.Seconds != 30 {
+		t.Errorf("forwarded extension = %+v (%v)", decoded, err)
+	}
+}
+
+func TestCodecsEncodeServerMessages(t *testing.T) {
+	msg := &models.Message{
+		Epoch:   "e1",
+		Seq:     7,
+		Action:  models.ActionTypeRename,
+		Payload: &models.RenamePayload{UserId: "u1", Name: "Alice"},
+	}
+
+	type wireMessage struct {
+		Epoch   string            `json:"epoch" msgpack:"epoch"`
+		Seq     uint64            `json:"seq" msgpack:"seq"`
+		Action  models.ActionType `json:"action" msgpack:"action"`
+		Payload struct {
+			UserId string `json:"userId" msgpack:"userId"`
+			Name   string `json:"name" msgpack:"name"`
+		} `json:"payload" msgpack:"payload"`
+	}
+	unmarshal := map[codec]func([]byte, interface{}) error{
+		jsonEncoding:    json.Unmarshal,
+		msgpackEncoding: msgpack.Unmarshal,
+	}
+	frameTypes := map[codec]int{jsonEncoding: websocket.TextMessage, msgpackEncoding: websocket.BinaryMessage}
+
+	for c, decode := range unmarshal {
+		frame, err := c.encode(msg)
+		if err != nil {
+			t.Fatalf("%T.encode() error = %v", c, err)
+		}
+		var got wireMessage
+		if err := decode(frame, &got); err != nil {
+			t.Fatalf("%T frame does not decode: %v", c, err)
+		}
+		if got.Epoch != "e1" || got.Seq != 7 || got.Action != models.ActionTypeRename || got.Payload.UserId != "u1" || got.Payload.Name != "Alice" {
+			t.Errorf("%T round trip = %+v", c, got)
+		}
+		if c.frameType() != frameTypes[c] {
+			t.Errorf("%T frame type = %d, want %d", c, c.frameType(), frameTypes[c])
+		}
+	}
+}
+
+func TestOutboundEncodesOncePerCodec(t *testing.T) {
+	out := newOutbound(&models.Message{Action: models.ActionTypeReset, Payload: &models.UserPayload{UserId: "u1"}})
+
+	first, err := out.frame(jsonEncoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := out.frame(jsonEncoding)
+	if &first[0] != &again[0] {
+		t.Error("the JSON frame was encoded twice")
+	}
+
+	binary, err := out.frame(msgpackEncoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(binary, first) || len(out.frames) != 2 {
+		t.Errorf("frames = %d, want one per codec", len(out.frames))
+	}
+}
+
+func TestSubprotocolNegotiation(t *testing.T) {
+	useHubConfig(t)
+	negotiated := make(chan codec, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		negotiated <- codecForSubprotocol(conn.Subprotocol())
+		conn.Close()
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	tests := []struct {
+		offered []string
+		want    codec
+	}{
+		{offered: nil, want: jsonEncoding},
+		{offered: []string{jsonSubprotocol}, want: jsonEncoding},
+		{offered: []string{msgpackSubprotocol}, want: msgpackEncoding},
+		{offered: []string{jsonSubprotocol, msgpackSubprotocol}, want: msgpackEncoding},
+		{offered: []string{"graphql-ws"}, want: jsonEncoding},
+	}
+	for _, tt := range tests {
+		dialer := websocket.Dialer{Subprotocols: tt.offered}
+		conn, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("Dial(%v) error = %v", tt.offered, err)
+		}
+		conn.Close()
+		if got := <-negotiated; got != tt.want {
+			t.Errorf("offering %v negotiated %T, want %T", tt.offered, got, tt.want)
+		}
+	}
+}
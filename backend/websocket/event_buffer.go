@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"sync"
+
+	"github.com/scrum-poker/backend/config"
+)
+
+type roomEvent struct {
+	seq uint64
+	out *outbound
+}
+
+// eventBuffer numbers the events of a single room and keeps the most recent
+// ones so that reconnecting clients can catch up. Its mutex also serialises
+// delivery, which guarantees clients receive events in sequence order.
+//
+// Numbering restarts whenever a room's buffer is recreated, after a restart
+// or once an idle room's buffer is dropped, so every buffer has an epoch of
+// its own. A sequence number only means something together with its epoch.
+type eventBuffer struct {
+	mu     sync.Mutex
+	epoch  string
+	seq    uint64
+	events []roomEvent
+	start  int
+	count  int
+}
+
+func newEventBuffer() *eventBuffer {
+	return &eventBuffer{
+		epoch:  strconv.FormatUint(rand.Uint64(), 36),
+		events: make([]roomEvent, config.Cfg.WebSocket.EventBufferSize),
+	}
+}
+
+func (b *eventBuffer) nextSeq() uint64 {
+	return b.seq + 1
+}
+
+func (b *eventBuffer) push(seq uint64, out *outbound) {
+	b.seq = seq
+
+	idx := (b.start + b.count) % len(b.events)
+	b.events[idx] = roomEvent{seq: seq, out: out}
+	if b.count < len(b.events) {
+		b.count++
+	} else {
+		b.start = (b.start + 1) % len(b.events)
+	}
+}
+
+// since returns the buffered events after lastSeq of epoch. The boolean is
+// false when the buffer can no longer cover the gap, or lastSeq was numbered
+// by another buffer, and the caller needs a snapshot.
+func (b *eventBuffer) since(epoch string, lastSeq uint64) ([]roomEvent, bool) {
+	if epoch != b.epoch || lastSeq > b.seq {
+		return nil, false
+	}
+	if lastSeq == b.seq {
+		return nil, true
+	}
+
+	oldest := b.seq - uint64(b.count) + 1
+	if b.count == 0 || lastSeq+1 < oldest {
+		return nil, false
+	}
+
+	missed := make([]roomEvent, 0, b.seq-lastSeq)
+	for i := 0; i < b.count; i++ {
+		event := b.events[(b.start+i)%len(b.events)]
+		if event.seq > lastSeq {
+			missed = append(missed, event)
+		}
+	}
+	return missed, true
+}
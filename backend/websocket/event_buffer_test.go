@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"slices"
+	"testing"
+)
+
+// pushEvents numbers n more events in b.
+func pushEvents(b *eventBuffer, n int) {
+	for i := 0; i < n; i++ {
+		b.push(b.nextSeq(), nil)
+	}
+}
+
+func seqs(events []roomEvent) []uint64 {
+	var out []uint64
+	for _, event := range events {
+		out = append(out, event.seq)
+	}
+	return out
+}
+
+func TestEventBufferCatchesUpWithinCapacity(t *testing.T) {
+	b := &eventBuffer{epoch: "a", events: make([]roomEvent, 4)}
+
+	if missed, ok := b.since("a", 0); !ok || len(missed) != 0 {
+		t.Fatalf("since(0) on an empty room = %v, %v; want nothing to replay", seqs(missed), ok)
+	}
+
+	pushEvents(b, 3)
+	if missed, ok := b.since("a", 1); !ok || !slices.Equal(seqs(missed), []uint64{2, 3}) {
+		t.Errorf("since(1) = %v, %v; want [2 3]", seqs(missed), ok)
+	}
+	if missed, ok := b.since("a", 3); !ok || len(missed) != 0 {
+		t.Errorf("since(3) = %v, %v; want nothing to replay", seqs(missed), ok)
+	}
+
+	// The ring wraps: events 1 to 6 are gone, 7 to 10 are kept.
+	pushEvents(b, 7)
+	if missed, ok := b.since("a", 6); !ok || !slices.Equal(seqs(missed), []uint64{7, 8, 9, 10}) {
+		t.Errorf("since(6) after wrapping = %v, %v; want [7 8 9 10]", seqs(missed), ok)
+	}
+	if _, ok := b.since("a", 5); ok {
+		t.Error("since(5) after wrapping covered a gap the buffer no longer holds")
+	}
+}
+
+func TestEventBufferRejectsForeignNumbering(t *testing.T) {
+	// A restarted server numbers the room's events from 1 again, so a client
+	// that saw up to 3 before the restart must not be replayed 4 and 5.
+	b := &eventBuffer{epoch: "after-restart", events: make([]roomEvent, 8)}
+	pushEvents(b, 5)
+
+	if _, ok := b.since("before-restart", 3); ok {
+		t.Error("since() replayed events numbered in another epoch")
+	}
+	if _, ok := b.since("", 3); ok {
+		t.Error("since() replayed events to a client that does not know the epoch")
+	}
+	if _, ok := b.since("after-restart", 6); ok {
+		t.Error("since() accepted a sequence number the room has not reached")
+	}
+}
+
+func TestNewEventBufferStartsAnEpoch(t *testing.T) {
+	useHubConfig(t)
+
+	first, second := newEventBuffer(), newEventBuffer()
+	if first.epoch == "" || first.epoch == second.epoch {
+		t.Errorf("epochs = %q and %q, want two distinct epochs", first.epoch, second.epoch)
+	}
+}
@@ -1,38 +1,94 @@
 package websocket
 
 import (
-	"encoding/json"
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
 	"sync"
 	"time"
 
+	"github.com/gorilla/websocket"
+	"github.com/scrum-poker/backend/config"
 	"github.com/scrum-poker/backend/db"
+	"github.com/scrum-poker/backend/metrics"
 	"github.com/scrum-poker/backend/models"
 	"github.com/scrum-poker/backend/session"
 )
 
 var GlobalHub *Hub
 
+// Close codes, in the range reserved for applications, that tell clients
+// not to reconnect.
+const (
+	CloseRoomClosed = 4000
+	CloseEvicted    = 4001
+)
+
 type Hub struct {
-	rooms map[string]map[*Client]bool
-	mu    sync.RWMutex
+	rooms   map[string]map[*Client]bool
+	buffers map[string]*eventBuffer
+	mu      sync.RWMutex
+
+	closing bool
+	pumps   sync.WaitGroup
 }
 
 func Init() {
 	GlobalHub = &Hub{
-		rooms: make(map[string]map[*Client]bool),
+		rooms:   make(map[string]map[*Client]bool),
+		buffers: make(map[string]*eventBuffer),
 	}
 
 	session.InitSessionManager(
 		GlobalHub.Broadcast,
 		GlobalHub.IsUserConnected,
+		GlobalHub.CloseRoom,
 	)
 }
 
+// RegisterClient adds c to its room. A resuming client is first sent what it
+// missed: the buffered events after its last sequence number or, when the
+// buffer no longer covers the gap or numbered them in another epoch, a
+// snapshot of the room followed by the events after the snapshot. The room is loaded without the event buffer
+// locked, so that a slow database does not hold up the room's broadcasts.
 func (h *Hub) RegisterClient(c *Client) {
+	var snapshot *models.Message
+	for {
+		buffer := h.lockEventBuffer(c.roomId)
+		missed, ok := missedEvents(c, buffer, snapshot)
+		if !ok {
+			epoch, seq := buffer.epoch, buffer.seq
+			buffer.mu.Unlock()
+			if snapshot = h.loadSnapshot(c, epoch, seq); snapshot == nil {
+				c.resume = false
+			}
+			continue
+		}
+
+		if h.addClient(c) {
+			if c.resume {
+				h.replay(c, snapshot, missed)
+			}
+			go h.notifyUserOnline(c.roomId, c.userId)
+		}
+		buffer.mu.Unlock()
+		return
+	}
+}
+
+// addClient adds c to its room, replacing earlier connections of the same
+// user. It reports false, after closing c, when the hub is shutting down.
+func (h *Hub) addClient(c *Client) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if h.closing {
+		h.closeForRestart(c)
+		return false
+	}
+
 	clients, exists := h.rooms[c.roomId]
 	if !exists {
 		clients = make(map[*Client]bool)
@@ -42,7 +98,7 @@ func (h *Hub) RegisterClient(c *Client) {
 	for existing := range clients {
 		if existing.userId == c.userId {
 			delete(clients, existing)
-			close(existing.send)
+			existing.closeSend()
 			if existing.conn != nil {
 				existing.conn.Close()
 			}
@@ -50,8 +106,8 @@ func (h *Hub) RegisterClient(c *Client) {
 	}
 
 	clients[c] = true
-
-	go h.notifyUserOnline(c.roomId, c.userId)
+	h.recordConnections()
+	return true
 }
 
 func (h *Hub) UnregisterClient(c *Client) {
@@ -69,7 +125,7 @@ func (h *Hub) UnregisterClient(c *Client) {
 	}
 
 	delete(clients, c)
-	close(c.send)
+	c.closeSend()
 
 	userStillConnected := false
 	for existing := range clients {
@@ -81,7 +137,11 @@ func (h *Hub) UnregisterClient(c *Client) {
 
 	if len(clients) == 0 {
 		delete(h.rooms, c.roomId)
+		time.AfterFunc(config.Cfg.WebSocket.EventBufferRetention, func() {
+			h.pruneEventBuffer(c.roomId)
+		})
 	}
+	h.recordConnections()
 
 	h.mu.Unlock()
 
@@ -93,7 +153,7 @@ func (h *Hub) UnregisterClient(c *Client) {
 func (h *Hub) notifyUserOnline(roomId, userId string) {
 	room, err := db.GetRoom(roomId)
 	if err != nil {
-		log.Printf("Error getting room %s: %v", roomId, err)
+		slog.Error("Error getting room", "room_id", roomId, "user_id", userId, "error", err)
 		return
 	}
 
@@ -103,7 +163,7 @@ func (h *Hub) notifyUserOnline(roomId, userId string) {
 
 	h.Broadcast(roomId, &models.Message{
 		Action:  models.ActionTypeOnline,
-		Payload: map[string]interface{}{"userId": userId},
+		Payload: &models.UserPayload{UserId: userId},
 	})
 }
 
@@ -114,9 +174,11 @@ func (h *Hub) handleUserOffline(roomId, userId string) {
 		return
 	}
 
+	logger := slog.With("room_id", roomId, "user_id", userId)
+
 	room, err := db.GetRoom(roomId)
 	if err != nil {
-		log.Printf("Error getting room %s: %v", roomId, err)
+		logger.Error("Error getting room", "error", err)
 		return
 	}
 
@@ -124,21 +186,21 @@ func (h *Hub) handleUserOffline(roomId, userId string) {
 		return
 	}
 
-	existingSession, err := db.GetSessionByUserID(userId)
+	existingSession, err := db.GetSessionByUserID(userId, roomId)
 	if err == nil && existingSession != nil {
-		existingSession.Refresh(session.TTL)
+		existingSession.Refresh(config.Cfg.Session.MembershipTTL)
 		if err := db.UpdateSession(existingSession); err != nil {
-			log.Printf("Error updating session: %v", err)
+			logger.Error("Error updating session", "error", err)
 		}
 	} else {
-		if _, err := session.GlobalManager.CreateSession(userId, roomId); err != nil {
-			log.Printf("Error creating session: %v", err)
+		if _, err := session.GlobalManager.CreateSession("", userId, roomId); err != nil {
+			logger.Error("Error creating session", "error", err)
 		}
 	}
 
 	h.Broadcast(roomId, &models.Message{
 		Action:  models.ActionTypeOffline,
-		Payload: map[string]interface{}{"userId": userId},
+		Payload: &models.UserPayload{UserId: userId},
 	})
 }
 
@@ -160,32 +222,319 @@ func (h *Hub) IsUserConnected(roomId, userId string) bool {
 }
 
 func (h *Hub) Broadcast(roomId string, msg *models.Message) {
-	msgBytes, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("error marshaling message: %v", err)
+	if !msg.IsSequenced() {
+		h.deliver(roomId, newOutbound(msg))
+		return
+	}
+
+	buffer := h.getEventBuffer(roomId)
+	if buffer == nil {
 		return
 	}
 
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	sequenced := *msg
+	sequenced.Epoch = buffer.epoch
+	sequenced.Seq = buffer.nextSeq()
+
+	out := newOutbound(&sequenced)
+	buffer.push(sequenced.Seq, out)
+	h.deliver(roomId, out)
+}
+
+// deliver queues out for every client in roomId. The lock is held
+// throughout, so that no client is closed while a frame is queued for it;
+// the sends never block.
+func (h *Hub) deliver(roomId string, out *outbound) {
 	h.mu.RLock()
-	clients, exists := h.rooms[roomId]
+	defer h.mu.RUnlock()
+
+	for c := range h.rooms[roomId] {
+		frame, err := out.frame(c.codec)
+		if err != nil {
+			c.logger.Error("Error encoding message", "action", out.msg.Action, "error", err)
+			continue
+		}
+
+		if c.queue(frame) {
+			metrics.CountMessageOut(out.msg.Action)
+		} else {
+			metrics.BroadcastDrops.Inc()
+			go h.UnregisterClient(c)
+		}
+	}
+}
+
+// missedEvents returns the buffered events c needs to catch up from its last
+// sequence number, or from snapshot when one was loaded. It reports false
+// when c needs a newer snapshot instead, because the buffer no longer covers
+// the gap, was recreated since, or the events would not fit in c's send
+// buffer. Must be called with buffer locked.
+func missedEvents(c *Client, buffer *eventBuffer, snapshot *models.Message) ([]roomEvent, bool) {
+	if !c.resume {
+		return nil, true
+	}
+
+	epoch, from := c.epoch, c.lastSeq
+	if snapshot != nil {
+		epoch, from = snapshot.Epoch, snapshot.Seq
+	}
+	missed, ok := buffer.since(epoch, from)
+	return missed, ok && len(missed) < cap(c.send)
+}
+
+// replay queues snapshot, if any, and the missed events for c, which has
+// just been added to its room. Must be called with the room's event buffer
+// locked, so that no newer event overtakes them.
+func (h *Hub) replay(c *Client, snapshot *models.Message, missed []roomEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if snapshot != nil {
+		frame, err := c.codec.encode(snapshot)
+		if err != nil {
+			c.logger.Error("Error encoding message", "action", snapshot.Action, "error", err)
+		} else if c.queue(frame) {
+			metrics.CountMessageOut(snapshot.Action)
+		}
+	}
+
+	for _, event := range missed {
+		frame, err := event.out.frame(c.codec)
+		if err != nil {
+			c.logger.Error("Error encoding message", "action", event.out.msg.Action, "error", err)
+			continue
+		}
+		if c.queue(frame) {
+			metrics.CountMessageOut(event.out.msg.Action)
+		}
+	}
+}
+
+// loadSnapshot returns a snapshot of c's room numbered seq of epoch, or nil
+// when the room cannot be loaded. Events after seq may already be reflected
+// in it; they are replayed on top of the snapshot, and clients apply them
+// idempotently.
+func (h *Hub) loadSnapshot(c *Client, epoch string, seq uint64) *models.Message {
+	room, err := db.GetRoom(c.roomId)
+	if err != nil {
+		c.logger.Error("Error getting room for snapshot", "error", err)
+		return nil
+	}
+
+	return &models.Message{
+		Epoch:   epoch,
+		Seq:     seq,
+		Action:  models.ActionTypeSnapshot,
+		Payload: room.ToSnapshot(),
+	}
+}
+
+func (h *Hub) getEventBuffer(roomId string) *eventBuffer {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.buffers[roomId]
+}
+
+func (h *Hub) getOrCreateEventBuffer(roomId string) *eventBuffer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buffer, exists := h.buffers[roomId]
 	if !exists {
-		h.mu.RUnlock()
+		buffer = newEventBuffer()
+		h.buffers[roomId] = buffer
+	}
+	return buffer
+}
+
+func (h *Hub) lockEventBuffer(roomId string) *eventBuffer {
+	for {
+		buffer := h.getOrCreateEventBuffer(roomId)
+		buffer.mu.Lock()
+		if h.getEventBuffer(roomId) == buffer {
+			return buffer
+		}
+		buffer.mu.Unlock()
+	}
+}
+
+func (h *Hub) pruneEventBuffer(roomId string) {
+	buffer := h.getEventBuffer(roomId)
+	if buffer == nil {
 		return
 	}
 
-	clientList := make([]*Client, 0, len(clients))
-	for c := range clients {
-		clientList = append(clientList, c)
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, active := h.rooms[roomId]; !active && h.buffers[roomId] == buffer {
+		delete(h.buffers, roomId)
 	}
-	h.mu.RUnlock()
+}
 
-	for _, c := range clientList {
-		select {
-		case c.send <- msgBytes:
-		default:
-			go h.UnregisterClient(c)
+// Shutdown tells every client that the server is restarting, with a hint
+// when to reconnect, and closes it; WebSocket connections are closed with
+// code 1012 (service restart). Clients that connect afterwards are turned
+// away the same way. Participants are not marked offline, since they are
+// expected back once the server is up again.
+func (h *Hub) Shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closing = true
+	count := 0
+	for roomId, clients := range h.rooms {
+		for c := range clients {
+			h.closeForRestart(c)
+			count++
+		}
+		delete(h.rooms, roomId)
+	}
+	h.recordConnections()
+	slog.Info("Closed real-time connections for restart", "count", count)
+}
+
+// Wait blocks until every WebSocket connection has been written its close
+// frame and closed, or ctx ends.
+func (h *Hub) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.pumps.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Check reports whether the hub can take connections: it is not shutting
+// down and its lock can be taken before ctx ends, which would not be the
+// case if it were deadlocked.
+func (h *Hub) Check(ctx context.Context) error {
+	closing := make(chan bool, 1)
+	go func() {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		closing <- h.closing
+	}()
+
+	select {
+	case isClosing := <-closing:
+		if isClosing {
+			return errors.New("shutting down")
 		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("hub lock not acquired: %v", ctx.Err())
+	}
+}
+
+// closeForRestart queues the restart notice for c and closes its send
+// channel, after which its pump writes the close frame. Must be called with
+// h.mu held.
+func (h *Hub) closeForRestart(c *Client) {
+	base := config.Cfg.Server.ReconnectDelay
+	h.closeClient(c, &models.Message{
+		Action: models.ActionTypeServerRestarting,
+		Payload: &models.ServerRestartingPayload{
+			ReconnectAfterMs: (base + rand.N(base)).Milliseconds(),
+		},
+	}, websocket.CloseServiceRestart, "server restarting")
+}
+
+// closeClient queues msg, if any, for c and closes its send channel, after
+// which its pump writes a close frame with code and reason. Must be called
+// with h.mu held.
+func (h *Hub) closeClient(c *Client, msg *models.Message, code int, reason string) {
+	if msg != nil {
+		if frame, err := c.codec.encode(msg); err != nil {
+			c.logger.Error("Error encoding message", "action", msg.Action, "error", err)
+		} else if c.queue(frame) {
+			metrics.CountMessageOut(msg.Action)
+		}
+	}
+	c.closeCode = code
+	c.closeReason = reason
+	c.closeSend()
+}
+
+// CloseRoom tells every client in roomId that the room was closed and
+// disconnects them without marking anyone offline. It returns the number of
+// connections closed.
+func (h *Hub) CloseRoom(roomId, reason string) int {
+	msg := &models.Message{
+		Action:  models.ActionTypeRoomClosed,
+		Payload: &models.RoomClosedPayload{Reason: reason},
+	}
+	return h.disconnect(roomId, func(*Client) bool { return true }, msg, CloseRoomClosed, "room closed")
+}
+
+// DisconnectUser closes the connections of userId in roomId, e.g. after the
+// user was removed from the room. It returns the number of connections
+// closed.
+func (h *Hub) DisconnectUser(roomId, userId string) int {
+	return h.disconnect(roomId, func(c *Client) bool { return c.userId == userId }, nil, CloseEvicted, "removed from room")
+}
+
+func (h *Hub) disconnect(roomId string, match func(*Client) bool, msg *models.Message, code int, reason string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients, exists := h.rooms[roomId]
+	if !exists {
+		return 0
+	}
+
+	count := 0
+	for c := range clients {
+		if match(c) {
+			delete(clients, c)
+			h.closeClient(c, msg, code, reason)
+			count++
+		}
+	}
+
+	if len(clients) == 0 {
+		delete(h.rooms, roomId)
+		time.AfterFunc(config.Cfg.WebSocket.EventBufferRetention, func() {
+			h.pruneEventBuffer(roomId)
+		})
+	}
+	h.recordConnections()
+	return count
+}
+
+// ConnectedRoomIds returns the rooms with at least one connection.
+func (h *Hub) ConnectedRoomIds() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	roomIds := make([]string, 0, len(h.rooms))
+	for roomId := range h.rooms {
+		roomIds = append(roomIds, roomId)
+	}
+	return roomIds
+}
+
+// recordConnections updates the connection gauges after the clients of a
+// room changed. Must be called with h.mu held.
+func (h *Hub) recordConnections() {
+	clients := 0
+	for _, roomClients := range h.rooms {
+		clients += len(roomClients)
 	}
+	metrics.ActiveRooms.Set(float64(len(h.rooms)))
+	metrics.ConnectedClients.Set(float64(clients))
 }
 
 func (h *Hub) GetConnectedUserIds(roomId string) []string {
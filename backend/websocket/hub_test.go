@@ -0,0 +1,234 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/metrics"
+	"github.com/scrum-poker/backend/models"
+)
+
+func useHubConfig(t *testing.T) {
+	t.Helper()
+	previous := config.Cfg
+	config.Cfg.Server.ReconnectDelay = time.Second
+	config.Cfg.WebSocket.EventBufferSize = 16
+	config.Cfg.WebSocket.EventBufferRetention = time.Minute
+	t.Cleanup(func() { config.Cfg = previous })
+}
+
+func newTestHub() *Hub {
+	return &Hub{
+		rooms:   make(map[string]map[*Client]bool),
+		buffers: make(map[string]*eventBuffer),
+	}
+}
+
+// testClient records the last frame the hub queued for its client.
+type testClient struct {
+	*Client
+	last []byte
+}
+
+// addTestClient connects a client without a WebSocket and drains what it is
+// sent, like a write pump, until the hub closes it.
+func addTestClient(t *testing.T, h *Hub, roomId, userId string, done *sync.WaitGroup) *testClient {
+	t.Helper()
+	c := &Client{
+		hub:    h,
+		codec:  jsonEncoding,
+		send:   make(chan []byte, 1024),
+		roomId: roomId,
+		userId: userId,
+		logger: slog.Default(),
+	}
+	if !h.addClient(c) {
+		t.Fatalf("client %s was not added", userId)
+	}
+
+	tc := &testClient{Client: c}
+	done.Add(1)
+	go func() {
+		defer done.Done()
+		for frame := range c.send {
+			tc.last = frame
+		}
+	}()
+	return tc
+}
+
+// TestCloseDuringBroadcast closes clients while messages are being queued
+// for them, which must neither panic nor race.
+func TestCloseDuringBroadcast(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(h *Hub)
+		// lastAction is what clients must be sent before they are closed.
+		lastAction models.ActionType
+	}{
+		{name: "shutdown", close: func(h *Hub) { h.Shutdown() }, lastAction: models.ActionTypeServerRestarting},
+		{name: "close room", close: func(h *Hub) {
+			for room := 0; room < 3; room++ {
+				h.CloseRoom(fmt.Sprintf("room-%d", room), "closed")
+			}
+		}, lastAction: models.ActionTypeRoomClosed},
+		{name: "disconnect user", close: func(h *Hub) {
+			for room := 0; room < 3; room++ {
+				for user := 0; user < 5; user++ {
+					h.DisconnectUser(fmt.Sprintf("room-%d", room), fmt.Sprintf("user-%d", user))
+				}
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useHubConfig(t)
+			h := newTestHub()
+
+			var pumps sync.WaitGroup
+			var clients []*testClient
+			for room := 0; room < 3; room++ {
+				roomId := fmt.Sprintf("room-%d", room)
+				h.getOrCreateEventBuffer(roomId)
+				for user := 0; user < 5; user++ {
+					clients = append(clients, addTestClient(t, h, roomId, fmt.Sprintf("user-%d", user), &pumps))
+				}
+			}
+
+			start := make(chan struct{})
+			var senders sync.WaitGroup
+			for room := 0; room < 3; room++ {
+				roomId := fmt.Sprintf("room-%d", room)
+				senders.Add(1)
+				go func() {
+					defer senders.Done()
+					<-start
+					for i := 0; i < 100; i++ {
+						h.Broadcast(roomId, &models.Message{Action: models.ActionTypeReset})
+						h.Broadcast(roomId, &models.Message{
+							Action:  models.ActionTypeNotice,
+							Payload: &models.NoticePayload{Message: "hello"},
+						})
+					}
+				}()
+			}
+			for _, c := range clients {
+				senders.Add(1)
+				go func() {
+					defer senders.Done()
+					<-start
+					for i := 0; i < 20; i++ {
+						c.sendMessage(&models.Message{Action: models.ActionTypePong})
+					}
+				}()
+			}
+
+			close(start)
+			tt.close(h)
+			senders.Wait()
+
+			for _, c := range clients {
+				if !c.closed {
+					t.Errorf("client %s in %s was not closed", c.userId, c.roomId)
+				}
+			}
+			pumps.Wait()
+
+			if tt.lastAction == "" {
+				return
+			}
+			for _, c := range clients {
+				var msg struct {
+					Action models.ActionType `json:"action"`
+				}
+				if err := json.Unmarshal(c.last, &msg); err != nil || msg.Action != tt.lastAction {
+					t.Errorf("client %s in %s was last sent %s, want %s", c.userId, c.roomId, c.last, tt.lastAction)
+				}
+			}
+		})
+	}
+}
+
+func TestBroadcastNumbersEventsInTheBufferEpoch(t *testing.T) {
+	useHubConfig(t)
+	h := newTestHub()
+	buffer := h.getOrCreateEventBuffer("room-1")
+
+	var pumps sync.WaitGroup
+	c := addTestClient(t, h, "room-1", "user-1", &pumps)
+	h.Broadcast("room-1", &models.Message{Action: models.ActionTypeReset})
+	h.Shutdown()
+	pumps.Wait()
+
+	event := buffer.events[0].out.msg
+	if event.Epoch != buffer.epoch || event.Seq != 1 {
+		t.Errorf("buffered event = %s/%d, want %s/1", event.Epoch, event.Seq, buffer.epoch)
+	}
+	if !c.closed {
+		t.Error("client was not closed by Shutdown")
+	}
+}
+
+func TestMissedEventsAfterBufferIsRecreated(t *testing.T) {
+	useHubConfig(t)
+	h := newTestHub()
+	old := h.getOrCreateEventBuffer("room-1")
+	for i := 0; i < 5; i++ {
+		h.Broadcast("room-1", &models.Message{Action: models.ActionTypeReset})
+	}
+
+	// The room is pruned and recreated, and numbers two events afresh.
+	h.mu.Lock()
+	delete(h.buffers, "room-1")
+	h.mu.Unlock()
+	current := h.getOrCreateEventBuffer("room-1")
+	for i := 0; i < 2; i++ {
+		h.Broadcast("room-1", &models.Message{Action: models.ActionTypeReset})
+	}
+
+	c := &Client{send: make(chan []byte, 16)}
+	c.resumeFrom(old.epoch, "1")
+
+	current.mu.Lock()
+	defer current.mu.Unlock()
+	if _, ok := missedEvents(c, current, nil); ok {
+		t.Fatal("missedEvents() replayed events of a recreated buffer instead of asking for a snapshot")
+	}
+
+	snapshot := &models.Message{Action: models.ActionTypeSnapshot, Epoch: current.epoch, Seq: 1}
+	missed, ok := missedEvents(c, current, snapshot)
+	if !ok || len(missed) != 1 || missed[0].seq != 2 {
+		t.Errorf("missedEvents() after the snapshot = %v, %v; want event 2", seqs(missed), ok)
+	}
+}
+
+func TestConnectionGaugesHaveNoRoomLabel(t *testing.T) {
+	useHubConfig(t)
+	h := newTestHub()
+
+	var pumps sync.WaitGroup
+	addTestClient(t, h, "room-1", "user-1", &pumps)
+	addTestClient(t, h, "room-1", "user-2", &pumps)
+	addTestClient(t, h, "room-2", "user-3", &pumps)
+	if rooms, clients := testutil.ToFloat64(metrics.ActiveRooms), testutil.ToFloat64(metrics.ConnectedClients); rooms != 2 || clients != 3 {
+		t.Errorf("gauges = %v rooms, %v clients; want 2 rooms, 3 clients", rooms, clients)
+	}
+
+	h.CloseRoom("room-1", "test")
+	if rooms, clients := testutil.ToFloat64(metrics.ActiveRooms), testutil.ToFloat64(metrics.ConnectedClients); rooms != 1 || clients != 1 {
+		t.Errorf("gauges after closing a room = %v rooms, %v clients; want 1 room, 1 client", rooms, clients)
+	}
+
+	h.Shutdown()
+	pumps.Wait()
+	if clients := testutil.ToFloat64(metrics.ConnectedClients); clients != 0 {
+		t.Errorf("connected clients after shutdown = %v, want 0", clients)
+	}
+}
@@ -0,0 +1,36 @@
+package websocket
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/logging"
+)
+
+// checkOrigin applies the ALLOWED_ORIGINS list used for CORS to WebSocket
+// upgrades, so that another site cannot open a connection with a visitor's
+// cookies. Requests without an Origin header do not come from a browser and
+// same-origin requests are always accepted.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if config.Cfg.IsDev {
+		return true
+	}
+
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	if config.Cfg.IsOriginAllowed(origin) {
+		return true
+	}
+
+	logging.FromRequest(r).Warn("Rejected WebSocket upgrade from disallowed origin", "origin", origin, "remote", r.RemoteAddr)
+	return false
+}
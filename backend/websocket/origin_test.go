@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/scrum-poker/backend/config"
+)
+
+func useAllowedOrigins(t *testing.T, dev bool, origins ...string) {
+	t.Helper()
+	previous := config.Cfg
+	t.Cleanup(func() { config.Cfg = previous })
+	config.Cfg.IsDev = dev
+	config.Cfg.AllowedOrigins = origins
+}
+
+func TestCheckOrigin(t *testing.T) {
+	useAllowedOrigins(t, false, "https://poker.example.com", "https://*.teams.example.com", "http://localhost:3000")
+
+	allowed := []string{
+		"",                               // not a browser
+		"https://api.example.com",        // same origin as the API
+		"https://poker.example.com",      // listed
+		"https://POKER.example.com",      // hosts are case-insensitive
+		"https://blue.teams.example.com", // wildcard subdomain
+		"https://a.b.teams.example.com",  // nested subdomain
+		"http://localhost:3000",          // listed with its port
+	}
+	rejected := []string{
+		"https://evil.example.com",
+		"http://poker.example.com",            // scheme differs
+		"https://poker.example.com:8443",      // port differs
+		"https://teams.example.com",           // the wildcard needs a subdomain
+		"https://blue.teams.example.com.evil", // suffix is not the domain
+		"https://evilteams.example.com",       // not a subdomain
+		"http://localhost:3001",
+		"null",
+	}
+
+	check := func(origin string) bool {
+		r := httptest.NewRequest(http.MethodGet, "https://api.example.com/ws/room-1", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		return checkOrigin(r)
+	}
+	for _, origin := range allowed {
+		if !check(origin) {
+			t.Errorf("checkOrigin(%q) rejected an allowed origin", origin)
+		}
+	}
+	for _, origin := range rejected {
+		if check(origin) {
+			t.Errorf("checkOrigin(%q) accepted a disallowed origin", origin)
+		}
+	}
+}
+
+func TestCheckOriginWildcardAndDev(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "https://api.example.com/ws/room-1", nil)
+	r.Header.Set("Origin", "https://anywhere.example.net")
+
+	useAllowedOrigins(t, false)
+	if checkOrigin(r) {
+		t.Error("an empty ALLOWED_ORIGINS accepted a foreign origin")
+	}
+
+	useAllowedOrigins(t, false, "*")
+	if !checkOrigin(r) {
+		t.Error(`"*" rejected a foreign origin`)
+	}
+
+	useAllowedOrigins(t, true)
+	if !checkOrigin(r) {
+		t.Error("development rejected a foreign origin")
+	}
+}
@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/scrum-poker/backend/config"
+	"github.com/scrum-poker/backend/logging"
+	"github.com/scrum-poker/backend/models"
+)
+
+// ServeSSE streams the room's broadcasts to a client that cannot use
+// WebSockets. The stream is registered with the hub like any other client;
+// it only differs in how messages are written out. Event ids are written as
+// epoch:seq, so the browser's Last-Event-ID resumes in the right numbering.
+func ServeSSE(hub *Hub, w http.ResponseWriter, r *http.Request, roomId, userId string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	client := &Client{
+		hub:    hub,
+		codec:  jsonEncoding,
+		send:   make(chan []byte, 256),
+		roomId: roomId,
+		userId: userId,
+		logger: logging.FromRequest(r).With("user_id", userId),
+
+		protocolVersion: models.ProtocolVersion,
+	}
+
+	if lastEventId := r.Header.Get("Last-Event-ID"); lastEventId != "" {
+		epoch, lastSeq, _ := strings.Cut(lastEventId, ":")
+		client.resumeFrom(epoch, lastSeq)
+	} else {
+		client.resumeFrom(r.URL.Query().Get("epoch"), r.URL.Query().Get("lastSeq"))
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	hub.RegisterClient(client)
+	defer hub.UnregisterClient(client)
+
+	ticker := time.NewTicker(config.Cfg.WebSocket.PingPeriod())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case message, ok := <-client.send:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, message); err != nil {
+				client.logger.Debug("Error writing event", "error", err)
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, message []byte) error {
+	var header struct {
+		Epoch string `json:"epoch"`
+		Seq   uint64 `json:"seq"`
+	}
+	if err := json.Unmarshal(message, &header); err == nil && header.Seq > 0 {
+		if _, err := fmt.Fprintf(w, "id: %s:%d\n", header.Epoch, header.Seq); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "data: %s\n\n", message)
+	return err
+}
@@ -1,4 +1,4 @@
-import React, { useState } from 'react';
+import React, { useEffect, useState } from 'react';
 import { useNavigate } from 'react-router-dom';
 import './Home.css';
 
@@ -12,6 +12,27 @@ const Home = () => {
   const [userName, setUserName] = useState('');
   const [roomId, setRoomId] = useState('');
   const [error, setError] = useState('');
+  const [rememberMe, setRememberMe] = useState(false);
+
+  useEffect(() => {
+    api.get('/profiles/me')
+      .then((response) => {
+        setUserName(response.data.displayName);
+        setRememberMe(true);
+      })
+      .catch(() => {});
+  }, []);
+
+  const saveProfile = async () => {
+    if (!rememberMe) {
+      return;
+    }
+    try {
+      await api.put('/profiles/me', { displayName: userName });
+    } catch (profileErr) {
+      console.error('Error saving profile:', profileErr);
+    }
+  };
 
   const handleCreateRoom = async (e) => {
     e.preventDefault();
@@ -22,6 +43,8 @@ const Home = () => {
       return;
     }
 
+    await saveProfile();
+
     try {
       const response = await api.post('/rooms', {
         name: roomName,
@@ -31,16 +54,10 @@ const Home = () => {
       const userId = response.data.participants[response.data.scrumMaster].id;
       const newRoomId = response.data.id;
 
-      try {
-        await api.post(`/sessions/${userId}/${newRoomId}`);
-      } catch (sessionErr) {
-        console.error('Error creating session:', sessionErr);
-      }
-
       navigate(`/room/${newRoomId}`, { state: { userId: userId, userName: userName } });
     } catch (err) {
       console.error('Error creating room:', err);
-      setError('Failed to create room. Please try again.');
+      setError(err.response?.data?.error || 'Failed to create room. Please try again.');
     }
   };
 
@@ -53,6 +70,8 @@ const Home = () => {
       return;
     }
 
+    await saveProfile();
+
     try {
       const response = await api.post(`/rooms/${roomId}/join`, {
         userName: userName
@@ -60,16 +79,10 @@ const Home = () => {
 
       const userId = response.data.user.id;
 
-      try {
-        await api.post(`/sessions/${userId}/${roomId}`);
-      } catch (sessionErr) {
-        console.error('Error creating session:', sessionErr);
-      }
-
       navigate(`/room/${roomId}`, { state: { userId: userId, userName: userName } });
     } catch (err) {
       console.error('Error joining room:', err);
-      setError('Failed to join room. Please check the Room Id and try again.');
+      setError(err.response?.data?.error || 'Failed to join room. Please check the Room Id and try again.');
     }
   };
 
@@ -106,6 +119,7 @@ const Home = () => {
                 className="form-control"
                 value={roomName}
                 onChange={(e) => setRoomName(e.target.value)}
+                maxLength={100}
                 placeholder="e.g., Sprint Planning"
               />
             </div>
@@ -117,9 +131,20 @@ const Home = () => {
                 className="form-control"
                 value={userName}
                 onChange={(e) => setUserName(e.target.value)}
+                maxLength={50}
                 placeholder="e.g., John Doe"
               />
             </div>
+            <div className="form-group">
+              <label className="remember-me">
+                <input
+                  type="checkbox"
+                  checked={rememberMe}
+                  onChange={(e) => setRememberMe(e.target.checked)}
+                />
+                Remember me on this device
+              </label>
+            </div>
             <button type="submit" className="btn btn-success">
               Create Room
             </button>
@@ -147,9 +172,20 @@ const Home = () => {
                 className="form-control"
                 value={userName}
                 onChange={(e) => setUserName(e.target.value)}
+                maxLength={50}
                 placeholder="e.g., Jane Smith"
               />
             </div>
+            <div className="form-group">
+              <label className="remember-me">
+                <input
+                  type="checkbox"
+                  checked={rememberMe}
+                  onChange={(e) => setRememberMe(e.target.checked)}
+                />
+                Remember me on this device
+              </label>
+            </div>
             <button type="submit" className="btn btn-success">
               Join Room
             </button>
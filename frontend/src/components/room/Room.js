@@ -35,6 +35,9 @@ const RoomComponent = () => {
   const isScrumMaster = useMemo(() => {
     return roomData && roomData.isScrumMaster(userId);
   }, [roomData, userId]);
+  const isObserver = useMemo(() => {
+    return roomData && roomData.isObserver(userId);
+  }, [roomData, userId]);
 
   const [menuOpenFor, setMenuOpenFor] = useState(null);
   const [showRenameDialog, setShowRenameDialog] = useState(false);
@@ -164,7 +167,7 @@ const RoomComponent = () => {
       }
     } catch (err) {
       console.error('Error joining room:', err);
-      setError('Failed to join room. Please try again.');
+      setError(err.response?.data?.error || 'Failed to join room. Please try again.');
     }
   };
 
@@ -177,7 +180,11 @@ const RoomComponent = () => {
       wsServiceRef.current.sendMessage('leave', {
         userId: userId
       });
-      await api.delete('/sessions');
+      await api.delete('/sessions', {
+        params: {
+          roomId: roomId,
+        }
+      });
     } catch (err) {
       setError('Failed to leave room. Please try again.');
     }
@@ -482,7 +489,7 @@ const RoomComponent = () => {
                         key={vote}
                         value={vote}
                         selected={selectedVote === vote}
-                        disabled={false}
+                        disabled={isObserver}
                         onClick={() => handleVote(vote)}
                     />
                 ))}
@@ -42,6 +42,7 @@ const NamePromptDialog = ({
                             className="form-control"
                             value={userName}
                             onChange={(e) => setUserName(e.target.value)}
+                            maxLength={50}
                             placeholder="e.g., John Doe"
                             autoFocus
                         />
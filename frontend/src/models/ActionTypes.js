@@ -8,6 +8,9 @@ class ActionTypes {
     static REVEAL = 'reveal'
     static RESET = 'reset'
     static TRANSFER = 'transfer'
+    static SNAPSHOT = 'snapshot'
+    static NOTICE = 'notice'
+    static ROOM_CLOSED = 'room_closed'
 }
 
 export default ActionTypes;
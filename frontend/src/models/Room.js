@@ -14,6 +14,11 @@ class Room {
     return this.scrumMaster === userId;
   }
 
+  isObserver(userId) {
+    const participant = this.participants[userId];
+    return !!participant && participant.isObserver();
+  }
+
   hasVoted(userId) {
     return !!this.votes[userId];
   }
@@ -30,7 +35,7 @@ class Room {
     const participants = {};
     if (data.participants) {
       Object.entries(data.participants).forEach(([id, p]) => {
-        participants[id] = new User(p.id, p.name, true);
+        participants[id] = new User(p.id, p.name, true, p.role, p.avatarSeed);
       });
     }
     return new Room(data.id, data.name, data.scrumMaster, participants, data.votes, data.votesRevealed);
@@ -1,8 +1,14 @@
 class User {
-    constructor(id, name, isOnline) {
+    constructor(id, name, isOnline, role, avatarSeed) {
         this.id = id
         this.name = name
         this.isOnline = isOnline
+        this.role = role || 'voter'
+        this.avatarSeed = avatarSeed || ''
+    }
+
+    isObserver() {
+        return this.role === 'observer'
     }
 }
 
@@ -16,6 +16,9 @@ class WebSocketService {
     this.isConnected = false;
     this.visibilityHandler = null;
     this.isTabVisible = !document.hidden;
+    this.epoch = null;
+    this.lastSeq = null;
+    this.restartDelay = null;
   }
 
   connect() {
@@ -46,7 +49,11 @@ class WebSocketService {
     this.setupVisibilityHandler();
 
     console.log(`Connecting WebSocket with roomId=${this.roomId} and userId=${this.userId}`);
-    this.ws = new WebSocket(`${process.env.REACT_APP_API_URL}/ws/${this.roomId}?userId=${this.userId}`);
+    let url = `${process.env.REACT_APP_API_URL}/ws/${this.roomId}`;
+    if (this.lastSeq !== null) {
+      url += `?epoch=${encodeURIComponent(this.epoch || '')}&lastSeq=${this.lastSeq}`;
+    }
+    this.ws = new WebSocket(url);
 
     this.ws.onopen = () => {
       console.log('WebSocket connected');
@@ -59,24 +66,7 @@ class WebSocketService {
     };
 
     this.ws.onmessage = async (event) => {
-      try {
-        const data = JSON.parse(event.data);
-
-        if (data.action === 'pong') {
-          if (data.payload && data.payload.userId === this.userId) {
-            console.log('Received pong for our ping');
-            this.lastPongTime = Date.now();
-          }
-          return;
-        }
-
-        this.lastPongTime = Date.now();
-
-        const message = new Message(data.action, data.payload);
-        await this.onMessage(message);
-      } catch (error) {
-        console.error('Error processing message:', error);
-      }
+      await this.handleFrame(event.data);
     };
 
     this.ws.onclose = (event) => {
@@ -87,6 +77,11 @@ class WebSocketService {
 
       if (event.code === 1000) {
         console.log('Clean WebSocket close, not reconnecting');
+      } else if (event.code === 1012) {
+        this.reconnectAfterRestart();
+      } else if (event.code === 4000 || event.code === 4001) {
+        console.log('Removed by the server, not reconnecting');
+        this.onStatusChange(event.code === 4000 ? 'Room closed' : 'Removed from room');
       } else if (this.reconnectTimeout) {
         console.log('Reconnect already scheduled, not scheduling another');
       } else {
@@ -101,6 +96,45 @@ class WebSocketService {
     };
   }
 
+  async handleFrame(frame) {
+    try {
+      const data = JSON.parse(frame);
+
+      if (data.action === 'server_restarting') {
+        this.restartDelay = data.payload ? data.payload.reconnectAfterMs : null;
+        this.onStatusChange('Server restarting...');
+        return;
+      }
+
+      if (data.action === 'pong') {
+        if (data.payload && data.payload.userId === this.userId) {
+          console.log('Received pong for our ping');
+          this.lastPongTime = Date.now();
+        }
+        return;
+      }
+
+      this.lastPongTime = Date.now();
+
+      if (typeof data.seq === 'number') {
+        // Sequence numbers restart when the server loses the room's events,
+        // which it announces with a new epoch and a snapshot.
+        const sameEpoch = data.epoch === this.epoch;
+        if (data.action !== 'snapshot' && sameEpoch && this.lastSeq !== null && data.seq <= this.lastSeq) {
+          console.log(`Skipping already processed event ${data.seq}`);
+          return;
+        }
+        this.epoch = data.epoch;
+        this.lastSeq = data.seq;
+      }
+
+      const message = new Message(data.action, data.payload);
+      await this.onMessage(message);
+    } catch (error) {
+      console.error('Error processing message:', error);
+    }
+  }
+
   startHeartbeat() {
     this.stopHeartbeat();
 
@@ -201,6 +235,26 @@ class WebSocketService {
     this.connect();
   }
 
+  reconnectAfterRestart() {
+    if (this.reconnectTimeout) {
+      clearTimeout(this.reconnectTimeout);
+    }
+
+    const delay = this.restartDelay ?? 1000 + Math.random() * 2000;
+    this.restartDelay = null;
+    this.reconnectAttempts = 0;
+
+    console.log(`Server restarting, reconnecting in ${Math.round(delay/1000)}s`);
+    this.onStatusChange('Server restarting...');
+
+    this.reconnectTimeout = setTimeout(() => {
+      this.reconnectTimeout = null;
+      if (!this.isConnected) {
+        this.connect();
+      }
+    }, delay);
+  }
+
   reconnect() {
     if (this.reconnectTimeout) {
       console.log('Clearing existing reconnect timeout');
@@ -333,6 +387,8 @@ class WebSocketService {
     if (this.userId !== newUserId) {
       console.log(`Updating userId from ${this.userId} to ${newUserId}`);
       this.userId = newUserId;
+      this.epoch = null;
+      this.lastSeq = null;
 
       if (this.reconnectTimeout) {
         console.log('Canceling existing reconnect timeout to start fresh with new userId');
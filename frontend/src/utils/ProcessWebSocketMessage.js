@@ -8,7 +8,7 @@ export function processWebSocketMessage(message, roomData, setRoomData, setSelec
 
     const actionHandlers = {
         [ActionTypes.JOIN]: () => {
-            roomData.participants[payload.id] = new User(payload.id, payload.name, payload.isOnline);
+            roomData.participants[payload.id] = new User(payload.id, payload.name, payload.isOnline, payload.role, payload.avatarSeed);
             toast.info(`${payload.name} joined the room`);
         },
 
@@ -47,6 +47,29 @@ export function processWebSocketMessage(message, roomData, setRoomData, setSelec
 
         [ActionTypes.TRANSFER]: () => {
             roomData.scrumMaster = payload.newScrumMasterId;
+        },
+
+        [ActionTypes.NOTICE]: () => {
+            toast.warning(payload.message, { autoClose: false });
+        },
+
+        [ActionTypes.ROOM_CLOSED]: () => {
+            toast.error(payload.reason, { autoClose: false });
+        },
+
+        [ActionTypes.SNAPSHOT]: () => {
+            const snapshot = Room.fromApiResponse(payload);
+            Object.entries(snapshot.participants).forEach(([id, participant]) => {
+                const previous = roomData.participants[id];
+                if (previous) {
+                    participant.isOnline = previous.isOnline;
+                }
+            });
+            roomData.name = snapshot.name;
+            roomData.scrumMaster = snapshot.scrumMaster;
+            roomData.participants = snapshot.participants;
+            roomData.votes = snapshot.votes;
+            roomData.votesRevealed = snapshot.votesRevealed;
         }
     };
 